@@ -0,0 +1,190 @@
+// fair-verify 离线校验拼手气红包：核对种子与发出时公布的承诺，并按领取顺序重算每一份金额。
+// 抽奖红包另外核对报名名单摘要，并用种子和名单重算每个名次的中奖者（仅支持 -f）。
+//
+//	curl -H "Authorization: Bearer <token>" http://localhost:8080/api/red-packets/<public_id>/fairness > fairness.json
+//	go run ./cmd/fair-verify -f fairness.json
+//
+// 也可以不依赖接口，直接传参数：
+//
+//	go run ./cmd/fair-verify -seed <seed> -hash <seed_hash> -total 1000 -count 5 -amounts 188,302,95
+//
+// 校验不通过时以状态码 1 退出。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"red-packet/pkg/fairsplit"
+)
+
+// fairness 与 GET /api/red-packets/:id/fairness 的 data 字段一致，只取校验需要的部分
+type fairness struct {
+	Algorithm   string   `json:"algorithm"`
+	SeedHash    string   `json:"seed_hash"`
+	Seed        string   `json:"seed"`
+	TotalAmount uint64   `json:"total_amount"`
+	TotalCount  uint32   `json:"total_count"`
+	Claims      []claim  `json:"claims"`
+	Entries     []uint64 `json:"entries"`
+	EntriesHash string   `json:"entries_hash"`
+}
+
+type claim struct {
+	Seq        uint32 `json:"seq"`
+	ReceiverID uint64 `json:"receiver_id"`
+	Amount     uint64 `json:"amount"`
+}
+
+func main() {
+	file := flag.String("f", "", "fairness JSON saved from the API (full response or its data field)")
+	seed := flag.String("seed", "", "revealed seed (hex)")
+	hash := flag.String("hash", "", "seed_hash published when the packet was sent")
+	total := flag.Uint64("total", 0, "total amount in minor units")
+	count := flag.Uint("count", 0, "total count")
+	amounts := flag.String("amounts", "", "comma separated claimed amounts in claim order")
+	flag.Parse()
+
+	var f fairness
+	if *file != "" {
+		if err := load(*file, &f); err != nil {
+			log.Fatalf("load %s: %v", *file, err)
+		}
+	} else {
+		f = fairness{Algorithm: fairsplit.Algorithm, SeedHash: *hash, Seed: *seed, TotalAmount: *total, TotalCount: uint32(*count)}
+		for i, s := range strings.Split(*amounts, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			a, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				log.Fatalf("invalid amount %q", s)
+			}
+			f.Claims = append(f.Claims, claim{Seq: uint32(i), Amount: a})
+		}
+	}
+
+	if f.Algorithm != fairsplit.Algorithm {
+		log.Fatalf("unsupported algorithm %q, this verifier implements %q", f.Algorithm, fairsplit.Algorithm)
+	}
+	if f.Seed == "" {
+		log.Fatal("seed is not revealed yet: wait until the packet is emptied, expired or refunded")
+	}
+
+	ok := true
+	commitment, err := fairsplit.Commitment(f.Seed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if f.SeedHash != "" && commitment != f.SeedHash {
+		fmt.Printf("seed hash mismatch: sha256(seed)=%s published=%s\n", commitment, f.SeedHash)
+		ok = false
+	} else {
+		fmt.Printf("seed hash ok: %s\n", commitment)
+	}
+
+	shares, err := fairsplit.Shares(f.Seed, f.TotalAmount, f.TotalCount)
+	if err != nil {
+		log.Fatal(err)
+	}
+	claimed := make(map[uint32]claim, len(f.Claims))
+	for _, c := range f.Claims {
+		claimed[c.Seq] = c
+	}
+	fmt.Printf("%-5s %-12s %-12s\n", "seq", "expected", "claimed")
+	for i, s := range shares {
+		c, has := claimed[uint32(i)]
+		switch {
+		case !has:
+			fmt.Printf("%-5d %-12d %-12s\n", i, s, "-")
+		case c.Amount != s:
+			fmt.Printf("%-5d %-12d %-12d MISMATCH\n", i, s, c.Amount)
+			ok = false
+		default:
+			fmt.Printf("%-5d %-12d %-12d\n", i, s, c.Amount)
+		}
+	}
+	if len(f.Entries) > 0 && !verifyWinners(f, claimed) {
+		ok = false
+	}
+	for seq := range claimed {
+		if int(seq) >= len(shares) {
+			fmt.Printf("claim seq %d is out of range\n", seq)
+			ok = false
+		}
+	}
+
+	if !ok {
+		fmt.Println("verification FAILED")
+		os.Exit(1)
+	}
+	fmt.Println("verification passed")
+}
+
+// verifyWinners 抽奖红包：核对报名名单摘要，用种子和名单重算各名次中奖者并与领取记录比对。
+// 中奖后因冻结或超限放弃的名次没有领取记录，显示为 -
+func verifyWinners(f fairness, claimed map[uint32]claim) bool {
+	ok := true
+	drawSeed := f.Seed
+	if f.EntriesHash == "" {
+		fmt.Println("entries hash not recorded: drawn before it was introduced, winners use the seed directly")
+	} else {
+		if h := fairsplit.EntriesHash(f.Entries); h != f.EntriesHash {
+			fmt.Printf("entries hash mismatch: computed=%s published=%s\n", h, f.EntriesHash)
+			ok = false
+		} else {
+			fmt.Printf("entries hash ok: %s (%d entries)\n", h, len(f.Entries))
+		}
+		var err error
+		if drawSeed, err = fairsplit.DrawSeed(f.Seed, f.EntriesHash); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	winners, err := fairsplit.Winners(drawSeed, len(f.Entries), int(f.TotalCount))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-5s %-12s %-12s\n", "rank", "winner", "receiver")
+	for r, i := range winners {
+		want := f.Entries[i]
+		c, has := claimed[uint32(r)]
+		switch {
+		case !has:
+			fmt.Printf("%-5d %-12d %-12s\n", r, want, "-")
+		case c.ReceiverID != want:
+			fmt.Printf("%-5d %-12d %-12d MISMATCH\n", r, want, c.ReceiverID)
+			ok = false
+		default:
+			fmt.Printf("%-5d %-12d %-12d\n", r, want, c.ReceiverID)
+		}
+	}
+	for seq := range claimed {
+		if int(seq) >= len(winners) {
+			fmt.Printf("claim seq %d has no winner\n", seq)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// load 兼容完整响应 {"code":0,"data":{...}} 和单独保存的 data
+func load(path string, f *fairness) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var envelope struct {
+		Data *fairness `json:"data"`
+	}
+	if err := json.Unmarshal(b, &envelope); err == nil && envelope.Data != nil {
+		*f = *envelope.Data
+		return nil
+	}
+	return json.Unmarshal(b, f)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"red-packet/pkg/fairsplit"
+)
+
+func TestVerifyWinners(t *testing.T) {
+	const seed = "5b0e4a0f3c2d1e0f5b0e4a0f3c2d1e0f5b0e4a0f3c2d1e0f5b0e4a0f3c2d77d3"
+	f := fairness{Seed: seed, TotalCount: 3, Entries: []uint64{11, 12, 13, 14, 15, 16}}
+	f.EntriesHash = fairsplit.EntriesHash(f.Entries)
+	ds, err := fairsplit.DrawSeed(seed, f.EntriesHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := fairsplit.Winners(ds, len(f.Entries), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed := map[uint32]claim{}
+	for r, i := range idx {
+		claimed[uint32(r)] = claim{Seq: uint32(r), ReceiverID: f.Entries[i]}
+	}
+	if !verifyWinners(f, claimed) {
+		t.Fatal("honest draw failed verification")
+	}
+
+	// 放弃的名次没有领取记录，不影响通过
+	delete(claimed, 1)
+	if !verifyWinners(f, claimed) {
+		t.Fatal("forfeited rank failed verification")
+	}
+
+	// 中奖者被替换
+	tampered := map[uint32]claim{0: {Seq: 0, ReceiverID: f.Entries[idx[1]]}}
+	if verifyWinners(f, tampered) {
+		t.Fatal("swapped winner passed verification")
+	}
+
+	// 名单与开奖时记录的摘要不符
+	f2 := f
+	f2.Entries = []uint64{11, 12, 13, 14, 15, 17}
+	if verifyWinners(f2, map[uint32]claim{}) {
+		t.Fatal("altered entries passed verification")
+	}
+}
@@ -0,0 +1,61 @@
+// webhook-receiver 本地联调用的 webhook 接收端：校验签名并打印收到的事件。
+//
+//	go run ./cmd/webhook-receiver -addr :9090 -secret <创建订阅时返回的 secret>
+//
+// -fail N 让前 N 次请求返回 500，用来观察重试和死信。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"red-packet/pkg/webhook"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	secret := flag.String("secret", "", "subscription secret")
+	fail := flag.Int64("fail", 0, "respond 500 to the first N requests")
+	flag.Parse()
+	if *secret == "" {
+		log.Fatal("-secret is required")
+	}
+
+	var received atomic.Int64
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "read body failed", http.StatusBadRequest)
+			return
+		}
+		if err := webhook.Verify(*secret, r.Header.Get(webhook.HeaderSignature), body, 5*time.Minute); err != nil {
+			log.Printf("reject delivery %s: %v", r.Header.Get(webhook.HeaderDelivery), err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		n := received.Add(1)
+		if n <= *fail {
+			log.Printf("delivery %s: simulated failure %d/%d", r.Header.Get(webhook.HeaderDelivery), n, *fail)
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		var p webhook.Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		log.Printf("delivery %s: event #%d %s at %s data=%s",
+			r.Header.Get(webhook.HeaderDelivery), p.ID, p.Type, p.OccurredAt.Format(time.RFC3339), p.Data)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
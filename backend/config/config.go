@@ -3,14 +3,26 @@ package config
 import "github.com/spf13/viper"
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Currency  CurrencyConfig  `mapstructure:"currency"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+	Risk      RiskConfig      `mapstructure:"risk"`
+	Limits    []LimitConfig   `mapstructure:"limits"`
+	Events    EventsConfig    `mapstructure:"events"`
+	Share     ShareConfig     `mapstructure:"share"`
+	Rain      RainConfig      `mapstructure:"rain"`
+	Claim     ClaimConfig     `mapstructure:"claim"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
 }
 
+// ServerConfig trusted_proxies 为反向代理的 IP 或网段，只有来自这些地址的请求才采信 X-Forwarded-For，为空时不信任任何代理
 type ServerConfig struct {
-	Port string `mapstructure:"port"`
+	Port           string   `mapstructure:"port"`
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -28,6 +40,92 @@ type JWTConfig struct {
 	ExpireHours int    `mapstructure:"expire_hours"`
 }
 
+type SchedulerConfig struct {
+	IntervalSeconds int `mapstructure:"interval_seconds"`
+}
+
+type CurrencyConfig struct {
+	Default string           `mapstructure:"default"`
+	List    []CurrencyOption `mapstructure:"list"`
+}
+
+// CurrencyOption precision 为最小单位的小数位数，如 CNY 为 2（分）
+type CurrencyOption struct {
+	Code      string `mapstructure:"code"`
+	Precision int    `mapstructure:"precision"`
+}
+
+// AdminConfig usernames 中的用户启动时被提升为管理员
+type AdminConfig struct {
+	Usernames []string `mapstructure:"usernames"`
+}
+
+type RiskConfig struct {
+	Enabled bool       `mapstructure:"enabled"`
+	Rules   []RiskRule `mapstructure:"rules"`
+}
+
+// RiskRule 风控规则，未配置（零值）的条件不参与判断，已配置的条件需全部满足
+type RiskRule struct {
+	Name   string `mapstructure:"name"`
+	Scene  string `mapstructure:"scene"`  // send / claim / *，空值等同 *
+	Action string `mapstructure:"action"` // review / challenge / deny
+
+	AccountAgeLtHours    int    `mapstructure:"account_age_lt_hours"`
+	UserActions1hGte     int64  `mapstructure:"user_actions_1h_gte"`
+	DeviceAccounts24hGte int64  `mapstructure:"device_accounts_24h_gte"`
+	IPAccounts24hGte     int64  `mapstructure:"ip_accounts_24h_gte"`
+	AmountGte            uint64 `mapstructure:"amount_gte"`
+	DeviceMissing        bool   `mapstructure:"device_missing"`
+}
+
+// LimitConfig 单币种的收发限额，金额单位为该币种最小单位，0 表示不限制
+type LimitConfig struct {
+	Currency           string `mapstructure:"currency"`
+	MaxPacketAmount    uint64 `mapstructure:"max_packet_amount"`
+	DailySendAmount    uint64 `mapstructure:"daily_send_amount"`
+	DailySendCount     int64  `mapstructure:"daily_send_count"`
+	DailyReceiveAmount uint64 `mapstructure:"daily_receive_amount"`
+	DailyReceiveCount  int64  `mapstructure:"daily_receive_count"`
+}
+
+type EventsConfig struct {
+	Relay EventRelayConfig `mapstructure:"relay"`
+}
+
+// EventRelayConfig 领域事件转发，broker 目前只支持 memory（进程内）
+type EventRelayConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Broker  string `mapstructure:"broker"`
+	Topic   string `mapstructure:"topic"`
+}
+
+// ShareConfig 分享链接：base_url 为前端地址，secret 为空时使用 jwt.secret
+type ShareConfig struct {
+	BaseURL  string `mapstructure:"base_url"`
+	Secret   string `mapstructure:"secret"`
+	TTLHours int    `mapstructure:"ttl_hours"`
+}
+
+// RainConfig 红包雨：secret 为空时使用 jwt.secret，workers 为处理排队请求的 worker 数
+type RainConfig struct {
+	Secret           string `mapstructure:"secret"`
+	TicketTTLSeconds int    `mapstructure:"ticket_ttl_seconds"`
+	Workers          int    `mapstructure:"workers"`
+	PollIntervalMs   int    `mapstructure:"poll_interval_ms"`
+	BatchSize        int    `mapstructure:"batch_size"`
+}
+
+// ClaimConfig 领红包的并发控制策略：pessimistic（默认）/ optimistic / conditional
+type ClaimConfig struct {
+	Strategy string `mapstructure:"strategy"`
+}
+
+// WebhookConfig allow_private_targets 为 true 时允许推送到回环和内网地址，仅用于本地联调
+type WebhookConfig struct {
+	AllowPrivateTargets bool `mapstructure:"allow_private_targets"`
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -3,6 +3,7 @@ package database
 import (
 	"red-packet/config"
 	"red-packet/model"
+	"red-packet/pkg/currency"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -16,16 +17,117 @@ func Init(cfg *config.Config) error {
 		return err
 	}
 
+	if err := migrateOpenAt(db); err != nil {
+		return err
+	}
+
 	err = db.AutoMigrate(
 		&model.User{},
 		&model.RedPacket{},
 		&model.RedPacketRecord{},
+		&model.RedPacketEntry{},
+		&model.RedPacketAllocation{},
+		&model.Campaign{},
+		&model.Rain{},
+		&model.RainClaim{},
+		&model.ClaimTicket{},
+		&model.ClaimQueue{},
 		&model.Transaction{},
+		&model.RedPacketSecretAttempt{},
+		&model.Wallet{},
+		&model.AdminAuditLog{},
+		&model.RiskDecision{},
+		&model.Transfer{},
+		&model.Collection{},
+		&model.CollectionShare{},
+		&model.WebhookSubscription{},
+		&model.OutboxEvent{},
+		&model.WebhookDelivery{},
+		&model.DomainEvent{},
+		&model.DomainEventOffset{},
 	)
 	if err != nil {
 		return err
 	}
 
+	if err := migrateLegacyBalance(db, currency.Default()); err != nil {
+		return err
+	}
+	if err := backfillPublicIDs(db); err != nil {
+		return err
+	}
+	if err := dropClaimTicketSecret(db); err != nil {
+		return err
+	}
+
 	DB = db
 	return nil
 }
+
+// migrateOpenAt 为旧表补 red_packets.open_at：严格模式下不能直接加无默认值的 NOT NULL 列，
+// 先以可空列加入，按 created_at 分批回填后再改为 NOT NULL；需在 AutoMigrate 之前执行
+func migrateOpenAt(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model.RedPacket{}) || m.HasColumn(&model.RedPacket{}, "open_at") {
+		return nil
+	}
+	if err := db.Exec("ALTER TABLE red_packets ADD COLUMN open_at DATETIME(3) NULL").Error; err != nil {
+		return err
+	}
+	for {
+		result := db.Exec("UPDATE red_packets SET open_at = created_at WHERE open_at IS NULL LIMIT 500")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			break
+		}
+	}
+	return m.AlterColumn(&model.RedPacket{}, "OpenAt")
+}
+
+// migrateLegacyBalance 将旧版 users.balance 迁移到默认币种钱包，已有钱包的用户跳过。
+// 需在 currency.Init 之后执行，否则总是迁移到内置默认的人民币
+func migrateLegacyBalance(db *gorm.DB, code string) error {
+	if !db.Migrator().HasColumn(&model.User{}, "balance") {
+		return nil
+	}
+	return db.Exec(
+		"INSERT IGNORE INTO wallets (user_id, currency, balance, created_at, updated_at) "+
+			"SELECT id, ?, balance, NOW(), NOW() FROM users WHERE balance > 0",
+		code,
+	).Error
+}
+
+// dropClaimTicketSecret 删除旧版 claim_tickets.secret 列，排队请求不再保存口令明文。
+// 遗留的排队请求没有口令校验标记，处理时按口令错误失败
+func dropClaimTicketSecret(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&model.ClaimTicket{}, "secret") {
+		return nil
+	}
+	return db.Migrator().DropColumn(&model.ClaimTicket{}, "secret")
+}
+
+// backfillPublicIDs 为新增 public_id 列之前创建的红包生成公开ID，已有的跳过
+func backfillPublicIDs(db *gorm.DB) error {
+	for {
+		var ids []uint64
+		err := db.Model(&model.RedPacket{}).
+			Where("public_id IS NULL OR public_id = ''").
+			Limit(500).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		for _, id := range ids {
+			publicID, err := model.NewPublicID()
+			if err != nil {
+				return err
+			}
+			// 直接更新列，不触发红包的领域事件钩子
+			if err := db.Model(&model.RedPacket{}).Where("id = ?", id).UpdateColumn("public_id", publicID).Error; err != nil {
+				return err
+			}
+		}
+	}
+}
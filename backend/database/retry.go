@@ -0,0 +1,65 @@
+package database
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTxAttempts  = 5
+	baseRetryDelay = 10 * time.Millisecond
+	maxRetryDelay  = 200 * time.Millisecond
+)
+
+// MySQL 可重试的错误码：1213 死锁被选为牺牲者，1205 锁等待超时
+const (
+	errCodeDeadlock    = 1213
+	errCodeLockTimeout = 1205
+)
+
+// ErrConflict 由事务函数返回，表示乐观并发冲突（版本号或条件更新未命中），整个事务需要重试
+var ErrConflict = errors.New("transaction conflict")
+
+// Transaction 执行事务，遇到死锁、锁等待超时或 ErrConflict 时回滚并整体重试，
+// 最多 5 次，退避时间指数增长并带随机抖动，避免冲突的请求同时重试再次冲突。
+// fn 可能被执行多次，不能在事务外留下副作用
+func Transaction(fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryDelay(attempt))
+		}
+		if err = DB.Transaction(fn); !IsRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// IsRetryable 是否为重试可能成功的并发错误
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrConflict) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errCodeDeadlock || mysqlErr.Number == errCodeLockTimeout
+	}
+	return false
+}
+
+// retryDelay 第 attempt 次重试前的等待：上限为 base*2^(attempt-1)（不超过 maxRetryDelay），在上限的一半到上限之间随机
+func retryDelay(attempt int) time.Duration {
+	d := baseRetryDelay << (attempt - 1)
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,100 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/service"
+)
+
+type AdminUser struct {
+	ID        uint64    `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Status    int8      `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AdminUserDetail struct {
+	AdminUser
+	Balances  []Balance `json:"balances"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AdjustBalanceResponse struct {
+	TransactionID uint64 `json:"transaction_id"`
+	Currency      string `json:"currency"`
+	BalanceAfter  uint64 `json:"balance_after"`
+}
+
+type AdminRedPacket struct {
+	RedPacket  RedPacket         `json:"red_packet"`
+	SenderName string            `json:"sender_name"`
+	Records    []RedPacketRecord `json:"records"`
+}
+
+type RefundResponse struct {
+	RefundedAmount uint64 `json:"refunded_amount"`
+}
+
+type AuditLog struct {
+	ID         uint64    `json:"id"`
+	AdminID    uint64    `json:"admin_id"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   uint64    `json:"target_id"`
+	Detail     string    `json:"detail"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewAdminUser(u *model.User) AdminUser {
+	return AdminUser{
+		ID:        u.ID,
+		Username:  u.Username,
+		Role:      u.Role,
+		Status:    u.Status,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func NewAdminUsers(list []model.User) []AdminUser {
+	items := make([]AdminUser, 0, len(list))
+	for i := range list {
+		items = append(items, NewAdminUser(&list[i]))
+	}
+	return items
+}
+
+func NewAdminUserDetail(u *model.User, balances []service.BalanceItem) AdminUserDetail {
+	return AdminUserDetail{
+		AdminUser: NewAdminUser(u),
+		Balances:  NewBalances(balances),
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+func NewAdminRedPacket(d *service.AdminRedPacketDetail) AdminRedPacket {
+	return AdminRedPacket{
+		RedPacket:  NewRedPacket(d.RedPacket),
+		SenderName: d.SenderName,
+		Records:    NewRedPacketRecords(d.Records),
+	}
+}
+
+func NewAuditLogs(list []model.AdminAuditLog) []AuditLog {
+	items := make([]AuditLog, 0, len(list))
+	for _, l := range list {
+		items = append(items, AuditLog{
+			ID:         l.ID,
+			AdminID:    l.AdminID,
+			Action:     l.Action,
+			TargetType: l.TargetType,
+			TargetID:   l.TargetID,
+			Detail:     l.Detail,
+			IP:         l.IP,
+			CreatedAt:  l.CreatedAt,
+		})
+	}
+	return items
+}
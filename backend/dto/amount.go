@@ -0,0 +1,8 @@
+package dto
+
+import "red-packet/pkg/currency"
+
+// FormatAmount 最小货币单位金额转为展示用字符串，如 CNY 12345 -> "123.45"
+func FormatAmount(cur string, amount uint64) string {
+	return currency.Format(cur, amount)
+}
@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/service"
+)
+
+// Campaign 营销活动及其预算账户，balance 为尚未发放到红包的预算
+type Campaign struct {
+	ID             uint64    `json:"id"`
+	Name           string    `json:"name"`
+	Currency       string    `json:"currency"`
+	Budget         uint64    `json:"budget"`
+	BudgetDisplay  string    `json:"budget_display"`
+	Balance        uint64    `json:"balance"`
+	BalanceDisplay string    `json:"balance_display"`
+	PerUserLimit   uint32    `json:"per_user_limit"`
+	StartAt        time.Time `json:"start_at"`
+	EndAt          time.Time `json:"end_at"`
+	CreatedBy      uint64    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CampaignReport 活动花费与参与情况，金额均为最小货币单位
+type CampaignReport struct {
+	PacketCount    int64  `json:"packet_count"`
+	IssuedAmount   uint64 `json:"issued_amount"`
+	ClaimedAmount  uint64 `json:"claimed_amount"`
+	Outstanding    uint64 `json:"outstanding_amount"`
+	ReturnedAmount uint64 `json:"returned_amount"`
+	ClaimCount     int64  `json:"claim_count"`
+	Participants   int64  `json:"participants"`
+}
+
+type CampaignDetail struct {
+	Campaign
+	Report CampaignReport `json:"report"`
+}
+
+// CampaignBatch 本批次发出的红包，public_id 用于分发领取链接
+type CampaignBatch struct {
+	List []RedPacket `json:"list"`
+}
+
+func NewCampaign(c *model.Campaign) Campaign {
+	return Campaign{
+		ID:             c.ID,
+		Name:           c.Name,
+		Currency:       c.Currency,
+		Budget:         c.Budget,
+		BudgetDisplay:  FormatAmount(c.Currency, c.Budget),
+		Balance:        c.Balance,
+		BalanceDisplay: FormatAmount(c.Currency, c.Balance),
+		PerUserLimit:   c.PerUserLimit,
+		StartAt:        c.StartAt,
+		EndAt:          c.EndAt,
+		CreatedBy:      c.CreatedBy,
+		CreatedAt:      c.CreatedAt,
+	}
+}
+
+func NewCampaigns(list []model.Campaign) []Campaign {
+	items := make([]Campaign, 0, len(list))
+	for i := range list {
+		items = append(items, NewCampaign(&list[i]))
+	}
+	return items
+}
+
+func NewCampaignDetail(c *model.Campaign, r *service.CampaignReport) CampaignDetail {
+	return CampaignDetail{
+		Campaign: NewCampaign(c),
+		Report: CampaignReport{
+			PacketCount:    r.PacketCount,
+			IssuedAmount:   r.Issued,
+			ClaimedAmount:  r.Claimed,
+			Outstanding:    r.Outstanding,
+			ReturnedAmount: r.Returned,
+			ClaimCount:     r.ClaimCount,
+			Participants:   r.Participants,
+		},
+	}
+}
+
+func NewCampaignBatch(packets []model.RedPacket) CampaignBatch {
+	list := make([]RedPacket, 0, len(packets))
+	for i := range packets {
+		list = append(list, NewRedPacket(&packets[i]))
+	}
+	return CampaignBatch{List: list}
+}
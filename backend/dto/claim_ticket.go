@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/service"
+)
+
+// ClaimTicket 排队领取请求，status：1=排队中，2=领取成功，3=领取失败；
+// 失败时 code 与 reason 同同步领取接口的错误码和错误信息
+type ClaimTicket struct {
+	ID            uint64     `json:"id"`
+	Status        int8       `json:"status"`
+	Amount        uint64     `json:"amount"`
+	AmountDisplay string     `json:"amount_display"`
+	Code          int        `json:"code,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NewClaimTicket code 由 handler 按失败原因换算后传入
+func NewClaimTicket(t *service.ClaimTicketResult, code int) ClaimTicket {
+	return ClaimTicket{
+		ID:            t.ID,
+		Status:        t.Status,
+		Amount:        t.Amount,
+		AmountDisplay: FormatAmount(t.Currency, t.Amount),
+		Code:          code,
+		Reason:        t.Reason,
+		ProcessedAt:   t.ProcessedAt,
+		CreatedAt:     t.CreatedAt,
+	}
+}
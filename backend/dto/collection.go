@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/service"
+)
+
+// Collection 收款基本信息，status：1=收款中，2=已收齐，3=已取消，4=到期结算
+type Collection struct {
+	ID                 uint64     `json:"id"`
+	InitiatorID        uint64     `json:"initiator_id"`
+	Title              string     `json:"title"`
+	Currency           string     `json:"currency"`
+	TotalAmount        uint64     `json:"total_amount"`
+	TotalAmountDisplay string     `json:"total_amount_display"`
+	PaidAmount         uint64     `json:"paid_amount"`
+	PaidAmountDisplay  string     `json:"paid_amount_display"`
+	ParticipantCount   uint32     `json:"participant_count"`
+	PaidCount          uint32     `json:"paid_count"`
+	Status             int8       `json:"status"`
+	ExpiredAt          time.Time  `json:"expired_at"`
+	SettledAt          *time.Time `json:"settled_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// CollectionShare 参与人应付份额，status：1=待付款，2=已付款，3=已退回，4=已结束未付
+type CollectionShare struct {
+	UserID        uint64     `json:"user_id"`
+	Username      string     `json:"username"`
+	Amount        uint64     `json:"amount"`
+	AmountDisplay string     `json:"amount_display"`
+	Status        int8       `json:"status"`
+	PaidAt        *time.Time `json:"paid_at"`
+}
+
+type CollectionDetail struct {
+	Collection
+	InitiatorName string            `json:"initiator_name"`
+	Shares        []CollectionShare `json:"shares"`
+}
+
+// RemindResponse 本次提醒的未付款人数
+type RemindResponse struct {
+	Reminded int `json:"reminded"`
+}
+
+func NewCollection(c *model.Collection) Collection {
+	return Collection{
+		ID:                 c.ID,
+		InitiatorID:        c.InitiatorID,
+		Title:              c.Title,
+		Currency:           c.Currency,
+		TotalAmount:        c.TotalAmount,
+		TotalAmountDisplay: FormatAmount(c.Currency, c.TotalAmount),
+		PaidAmount:         c.PaidAmount,
+		PaidAmountDisplay:  FormatAmount(c.Currency, c.PaidAmount),
+		ParticipantCount:   c.ParticipantCount,
+		PaidCount:          c.PaidCount,
+		Status:             c.Status,
+		ExpiredAt:          c.ExpiredAt,
+		SettledAt:          c.SettledAt,
+		CreatedAt:          c.CreatedAt,
+	}
+}
+
+func NewCollections(list []model.Collection) []Collection {
+	items := make([]Collection, 0, len(list))
+	for i := range list {
+		items = append(items, NewCollection(&list[i]))
+	}
+	return items
+}
+
+func NewCollectionDetail(d *service.CollectionDetail) CollectionDetail {
+	shares := make([]CollectionShare, 0, len(d.Shares))
+	for _, s := range d.Shares {
+		shares = append(shares, CollectionShare{
+			UserID:        s.UserID,
+			Username:      s.Username,
+			Amount:        s.Amount,
+			AmountDisplay: FormatAmount(d.Currency, s.Amount),
+			Status:        s.Status,
+			PaidAt:        s.PaidAt,
+		})
+	}
+	return CollectionDetail{
+		Collection:    NewCollection(d.Collection),
+		InitiatorName: d.InitiatorName,
+		Shares:        shares,
+	}
+}
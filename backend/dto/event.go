@@ -0,0 +1,17 @@
+package dto
+
+import (
+	"red-packet/pkg/event"
+	"red-packet/service"
+)
+
+// DomainEventList 事件拉取结果，下次请求把 next_seq 作为 after 传入
+type DomainEventList struct {
+	List    []event.DomainEvent `json:"list"`
+	NextSeq uint64              `json:"next_seq"`
+	HasMore bool                `json:"has_more"`
+}
+
+func NewDomainEventList(p *service.DomainEventPage) DomainEventList {
+	return DomainEventList{List: p.List, NextSeq: p.NextSeq, HasMore: p.HasMore}
+}
@@ -0,0 +1,54 @@
+package dto
+
+import "red-packet/service"
+
+// Fairness 拼手气红包公平性校验数据，seed 在红包结束前为空
+type Fairness struct {
+	PublicID    string      `json:"public_id"`
+	Algorithm   string      `json:"algorithm"`
+	SeedHash    string      `json:"seed_hash"`
+	Seed        string      `json:"seed,omitempty"`
+	Status      int8        `json:"status"`
+	TotalAmount uint64      `json:"total_amount"`
+	TotalCount  uint32      `json:"total_count"`
+	Claims      []FairClaim `json:"claims"`
+	Entries     []uint64    `json:"entries,omitempty"`      // 抽奖红包开奖后按报名顺序的报名用户ID
+	EntriesHash string      `json:"entries_hash,omitempty"` // 抽奖红包开奖时记录的报名名单摘要
+	Verified    *bool       `json:"verified,omitempty"`
+}
+
+type FairClaim struct {
+	Seq                uint32  `json:"seq"`
+	ReceiverID         uint64  `json:"receiver_id"`
+	ReceiverName       string  `json:"receiver_name"`
+	Amount             uint64  `json:"amount"`
+	Expected           *uint64 `json:"expected,omitempty"`
+	ExpectedReceiverID *uint64 `json:"expected_receiver_id,omitempty"` // 抽奖红包重算出的该名次中奖者
+}
+
+func NewFairness(f *service.Fairness) Fairness {
+	claims := make([]FairClaim, 0, len(f.Claims))
+	for _, c := range f.Claims {
+		claims = append(claims, FairClaim{
+			Seq:                c.Seq,
+			ReceiverID:         c.ReceiverID,
+			ReceiverName:       c.ReceiverName,
+			Amount:             c.Amount,
+			Expected:           c.Expected,
+			ExpectedReceiverID: c.ExpectedReceiverID,
+		})
+	}
+	return Fairness{
+		PublicID:    f.RedPacket.PublicID,
+		Algorithm:   f.Algorithm,
+		SeedHash:    f.RedPacket.SeedHash,
+		Seed:        f.Seed,
+		Status:      f.RedPacket.Status,
+		TotalAmount: f.RedPacket.TotalAmount,
+		TotalCount:  f.RedPacket.TotalCount,
+		Claims:      claims,
+		Entries:     f.Entries,
+		EntriesHash: f.EntriesHash,
+		Verified:    f.Verified,
+	}
+}
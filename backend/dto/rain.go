@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/service"
+)
+
+// Rain 红包雨，status：1=进行中（含未开始），2=已结束；seed 在结束后公开，可按 seq 复算每份金额
+type Rain struct {
+	ID                     uint64    `json:"id"`
+	CampaignID             uint64    `json:"campaign_id"`
+	Currency               string    `json:"currency"`
+	TotalAmount            uint64    `json:"total_amount"`
+	TotalAmountDisplay     string    `json:"total_amount_display"`
+	DropCount              uint32    `json:"drop_count"`
+	RemainingAmount        uint64    `json:"remaining_amount"`
+	RemainingAmountDisplay string    `json:"remaining_amount_display"`
+	RemainingDrops         uint32    `json:"remaining_drops"`
+	PerUserCap             uint32    `json:"per_user_cap"`
+	SeedHash               string    `json:"seed_hash"`
+	Seed                   string    `json:"seed,omitempty"`
+	Status                 int8      `json:"status"`
+	StartAt                time.Time `json:"start_at"`
+	EndAt                  time.Time `json:"end_at"`
+	CreatedAt              time.Time `json:"created_at"`
+}
+
+type RainTicket struct {
+	Ticket    string    `json:"ticket"`
+	NotBefore time.Time `json:"not_before"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RainClaim 领取请求结果，status：1=排队中，2=抢到，3=未抢到（原因见 reason）
+type RainClaim struct {
+	ID            uint64     `json:"id"`
+	RainID        uint64     `json:"rain_id"`
+	Status        int8       `json:"status"`
+	Amount        uint64     `json:"amount"`
+	AmountDisplay string     `json:"amount_display"`
+	Seq           uint32     `json:"seq"`
+	Reason        string     `json:"reason,omitempty"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func NewRain(r *model.Rain) Rain {
+	item := Rain{
+		ID:                     r.ID,
+		CampaignID:             r.CampaignID,
+		Currency:               r.Currency,
+		TotalAmount:            r.TotalAmount,
+		TotalAmountDisplay:     FormatAmount(r.Currency, r.TotalAmount),
+		DropCount:              r.DropCount,
+		RemainingAmount:        r.RemainingAmount,
+		RemainingAmountDisplay: FormatAmount(r.Currency, r.RemainingAmount),
+		RemainingDrops:         r.RemainingDrops,
+		PerUserCap:             r.PerUserCap,
+		SeedHash:               r.SeedHash,
+		Status:                 r.Status,
+		StartAt:                r.StartAt,
+		EndAt:                  r.EndAt,
+		CreatedAt:              r.CreatedAt,
+	}
+	if r.Status == model.RainStatusFinished {
+		item.Seed = r.Seed
+	}
+	return item
+}
+
+func NewRainTicket(t *service.RainTicket) RainTicket {
+	return RainTicket{
+		Ticket:    t.Ticket,
+		NotBefore: t.NotBefore,
+		ExpiresAt: t.ExpiresAt,
+	}
+}
+
+func NewRainClaim(c *service.RainClaimResult) RainClaim {
+	return RainClaim{
+		ID:            c.ID,
+		RainID:        c.RainID,
+		Status:        c.Status,
+		Amount:        c.Amount,
+		AmountDisplay: FormatAmount(c.Currency, c.Amount),
+		Seq:           c.Seq,
+		Reason:        c.Reason,
+		ProcessedAt:   c.ProcessedAt,
+		CreatedAt:     c.CreatedAt,
+	}
+}
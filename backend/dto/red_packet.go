@@ -0,0 +1,196 @@
+package dto
+
+import (
+	"net/url"
+	"time"
+
+	"red-packet/model"
+	"red-packet/service"
+)
+
+// RedPacket 红包基本信息，发红包、我发出的红包、后台详情共用
+type RedPacket struct {
+	ID                     uint64     `json:"id"`
+	PublicID               string     `json:"public_id"`
+	SenderID               uint64     `json:"sender_id"`
+	CampaignID             *uint64    `json:"campaign_id,omitempty"` // 活动红包所属活动，此时 sender_id 为 0
+	Type                   int8       `json:"type"`
+	Currency               string     `json:"currency"`
+	TotalAmount            uint64     `json:"total_amount"`
+	TotalAmountDisplay     string     `json:"total_amount_display"`
+	TotalCount             uint32     `json:"total_count"`
+	RemainingAmount        uint64     `json:"remaining_amount"`
+	RemainingAmountDisplay string     `json:"remaining_amount_display"`
+	RemainingCount         uint32     `json:"remaining_count"`
+	Status                 int8       `json:"status"`
+	HasSecret              bool       `json:"has_secret"`
+	DrawAt                 *time.Time `json:"draw_at,omitempty"`
+	RequireShare           bool       `json:"require_share"`
+	SeedHash               string     `json:"seed_hash,omitempty"` // 拼手气红包种子承诺
+	Seed                   string     `json:"seed,omitempty"`      // 红包结束后公开
+	OpenAt                 time.Time  `json:"open_at"`
+	ExpiredAt              time.Time  `json:"expired_at"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
+
+type MyClaim struct {
+	Claimed   bool       `json:"claimed"`
+	Amount    uint64     `json:"amount,omitempty"`
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+}
+
+// MyEntry 抽奖红包中当前用户的报名结果，status：1=待开奖，2=中奖，3=未中奖，4=中奖但超限放弃
+type MyEntry struct {
+	Status    int8      `json:"status"`
+	Amount    uint64    `json:"amount,omitempty"`
+	EnteredAt time.Time `json:"entered_at"`
+}
+
+// MyAllocation 指定金额红包中分配给当前用户的金额，status：1=待领取，2=已领取，3=已退回
+type MyAllocation struct {
+	Amount uint64 `json:"amount"`
+	Status int8   `json:"status"`
+}
+
+type RedPacketDetail struct {
+	RedPacket
+	SenderName   string        `json:"sender_name"`
+	ClaimedCount int64         `json:"claimed_count"`
+	MyClaim      MyClaim       `json:"my_claim"`
+	EntryCount   int64         `json:"entry_count,omitempty"`
+	MyEntry      *MyEntry      `json:"my_entry,omitempty"`
+	MyAllocation *MyAllocation `json:"my_allocation,omitempty"`
+}
+
+// EnterResponse 报名成功后的报名人数
+type EnterResponse struct {
+	EntryCount int64 `json:"entry_count"`
+}
+
+type ClaimResponse struct {
+	Amount uint64 `json:"amount"`
+}
+
+// ShareLink 分享链接，qr_url 为对应二维码图片的接口地址
+type ShareLink struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	QRURL     string    `json:"qr_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RedPacketRecord struct {
+	ReceiverID   uint64    `json:"receiver_id"`
+	ReceiverName string    `json:"receiver_name"`
+	Amount       uint64    `json:"amount"`
+	ClaimedAt    time.Time `json:"claimed_at"`
+}
+
+type ReceivedRedPacket struct {
+	RedPacketID   uint64    `json:"red_packet_id"`
+	PublicID      string    `json:"public_id"`
+	SenderName    string    `json:"sender_name"`
+	Currency      string    `json:"currency"`
+	Amount        uint64    `json:"amount"`
+	AmountDisplay string    `json:"amount_display"`
+	ClaimedAt     time.Time `json:"claimed_at"`
+}
+
+func NewRedPacket(rp *model.RedPacket) RedPacket {
+	return RedPacket{
+		ID:                     rp.ID,
+		PublicID:               rp.PublicID,
+		SenderID:               rp.SenderID,
+		CampaignID:             rp.CampaignID,
+		Type:                   rp.Type,
+		Currency:               rp.Currency,
+		TotalAmount:            rp.TotalAmount,
+		TotalAmountDisplay:     FormatAmount(rp.Currency, rp.TotalAmount),
+		TotalCount:             rp.TotalCount,
+		RemainingAmount:        rp.RemainingAmount,
+		RemainingAmountDisplay: FormatAmount(rp.Currency, rp.RemainingAmount),
+		RemainingCount:         rp.RemainingCount,
+		Status:                 rp.Status,
+		HasSecret:              rp.HasSecret,
+		DrawAt:                 rp.DrawAt,
+		RequireShare:           rp.RequireShare,
+		SeedHash:               rp.SeedHash,
+		Seed:                   revealedSeed(rp),
+		OpenAt:                 rp.OpenAt,
+		ExpiredAt:              rp.ExpiredAt,
+		CreatedAt:              rp.CreatedAt,
+	}
+}
+
+func revealedSeed(rp *model.RedPacket) string {
+	if !rp.SeedRevealed() {
+		return ""
+	}
+	return rp.Seed
+}
+
+func NewRedPackets(list []model.RedPacket) []RedPacket {
+	items := make([]RedPacket, 0, len(list))
+	for i := range list {
+		items = append(items, NewRedPacket(&list[i]))
+	}
+	return items
+}
+
+func NewRedPacketDetail(d *service.RedPacketDetail) RedPacketDetail {
+	detail := RedPacketDetail{
+		RedPacket:    NewRedPacket(d.RedPacket),
+		SenderName:   d.SenderName,
+		ClaimedCount: d.ClaimedCount,
+	}
+	if d.MyClaim != nil && d.MyClaim.Claimed {
+		claimedAt := d.MyClaim.ClaimedAt
+		detail.MyClaim = MyClaim{Claimed: true, Amount: d.MyClaim.Amount, ClaimedAt: &claimedAt}
+	}
+	detail.EntryCount = d.EntryCount
+	if d.MyEntry != nil {
+		detail.MyEntry = &MyEntry{Status: d.MyEntry.Status, Amount: d.MyEntry.Amount, EnteredAt: d.MyEntry.CreatedAt}
+	}
+	if d.MyAllocation != nil {
+		detail.MyAllocation = &MyAllocation{Amount: d.MyAllocation.Amount, Status: d.MyAllocation.Status}
+	}
+	return detail
+}
+
+func NewRedPacketRecords(items []service.RecordItem) []RedPacketRecord {
+	list := make([]RedPacketRecord, 0, len(items))
+	for _, r := range items {
+		list = append(list, RedPacketRecord{
+			ReceiverID:   r.ReceiverID,
+			ReceiverName: r.ReceiverName,
+			Amount:       r.Amount,
+			ClaimedAt:    r.ClaimedAt,
+		})
+	}
+	return list
+}
+
+func NewReceivedRedPackets(items []service.ReceivedItem) []ReceivedRedPacket {
+	list := make([]ReceivedRedPacket, 0, len(items))
+	for _, r := range items {
+		list = append(list, ReceivedRedPacket{
+			RedPacketID:   r.RedPacketID,
+			PublicID:      r.PublicID,
+			SenderName:    r.SenderName,
+			Currency:      r.Currency,
+			Amount:        r.Amount,
+			AmountDisplay: FormatAmount(r.Currency, r.Amount),
+			ClaimedAt:     r.ClaimedAt,
+		})
+	}
+	return list
+}
+
+func NewShareLink(l *service.ShareLink) ShareLink {
+	return ShareLink{
+		Token:     l.Token,
+		URL:       l.URL,
+		QRURL:     "/api/red-packets/" + url.PathEscape(l.PublicID) + "/share/qr?t=" + url.QueryEscape(l.Token),
+		ExpiresAt: l.ExpiresAt,
+	}
+}
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/service"
+)
+
+type ReportBiggestClaim struct {
+	RedPacketID   uint64    `json:"red_packet_id"`
+	SenderID      uint64    `json:"sender_id"`
+	SenderName    string    `json:"sender_name"`
+	Amount        uint64    `json:"amount"`
+	AmountDisplay string    `json:"amount_display"`
+	ClaimedAt     time.Time `json:"claimed_at"`
+}
+
+type ReportCounterpart struct {
+	UserID        uint64 `json:"user_id"`
+	Username      string `json:"username"`
+	Amount        uint64 `json:"amount"`
+	AmountDisplay string `json:"amount_display"`
+	Count         int64  `json:"count"`
+}
+
+type ReportMonth struct {
+	Month          string `json:"month"` // 2026-01
+	SentAmount     uint64 `json:"sent_amount"`
+	SentCount      int64  `json:"sent_count"`
+	ReceivedAmount uint64 `json:"received_amount"`
+	ReceivedCount  int64  `json:"received_count"`
+}
+
+// Report 个人收发红包报告
+type Report struct {
+	Currency              string              `json:"currency"`
+	Start                 time.Time           `json:"start"`
+	End                   time.Time           `json:"end"` // 不含
+	SentAmount            uint64              `json:"sent_amount"`
+	SentAmountDisplay     string              `json:"sent_amount_display"`
+	SentCount             int64               `json:"sent_count"`
+	RefundAmount          uint64              `json:"refund_amount"`
+	ReceivedAmount        uint64              `json:"received_amount"`
+	ReceivedAmountDisplay string              `json:"received_amount_display"`
+	ReceivedCount         int64               `json:"received_count"`
+	BiggestClaim          *ReportBiggestClaim `json:"biggest_claim"`
+	BestLuckCount         int64               `json:"best_luck_count"`
+	TopSenders            []ReportCounterpart `json:"top_senders"`
+	TopReceivers          []ReportCounterpart `json:"top_receivers"`
+	Months                []ReportMonth       `json:"months"`
+	GeneratedAt           time.Time           `json:"generated_at"`
+}
+
+func NewReport(r *service.Report) Report {
+	counterparts := func(list []service.ReportCounterpart) []ReportCounterpart {
+		result := make([]ReportCounterpart, 0, len(list))
+		for _, c := range list {
+			result = append(result, ReportCounterpart{
+				UserID:        c.UserID,
+				Username:      c.Username,
+				Amount:        c.Amount,
+				AmountDisplay: FormatAmount(r.Currency, c.Amount),
+				Count:         c.Count,
+			})
+		}
+		return result
+	}
+
+	months := make([]ReportMonth, 0, len(r.Months))
+	for _, m := range r.Months {
+		months = append(months, ReportMonth{
+			Month:          m.Month,
+			SentAmount:     m.SentAmount,
+			SentCount:      m.SentCount,
+			ReceivedAmount: m.ReceivedAmount,
+			ReceivedCount:  m.ReceivedCount,
+		})
+	}
+
+	report := Report{
+		Currency:              r.Currency,
+		Start:                 r.Period.Start,
+		End:                   r.Period.End,
+		SentAmount:            r.SentAmount,
+		SentAmountDisplay:     FormatAmount(r.Currency, r.SentAmount),
+		SentCount:             r.SentCount,
+		RefundAmount:          r.RefundAmount,
+		ReceivedAmount:        r.ReceivedAmount,
+		ReceivedAmountDisplay: FormatAmount(r.Currency, r.ReceivedAmount),
+		ReceivedCount:         r.ReceivedCount,
+		BestLuckCount:         r.BestLuckCount,
+		TopSenders:            counterparts(r.TopSenders),
+		TopReceivers:          counterparts(r.TopReceivers),
+		Months:                months,
+		GeneratedAt:           r.GeneratedAt,
+	}
+	if b := r.BiggestClaim; b != nil {
+		report.BiggestClaim = &ReportBiggestClaim{
+			RedPacketID:   b.RedPacketID,
+			SenderID:      b.SenderID,
+			SenderName:    r.BiggestSender,
+			Amount:        b.Amount,
+			AmountDisplay: FormatAmount(r.Currency, b.Amount),
+			ClaimedAt:     b.CreatedAt,
+		}
+	}
+	return report
+}
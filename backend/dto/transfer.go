@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/model"
+)
+
+type Transfer struct {
+	ID            uint64     `json:"id"`
+	FromUserID    uint64     `json:"from_user_id"`
+	ToUserID      uint64     `json:"to_user_id"`
+	Currency      string     `json:"currency"`
+	Amount        uint64     `json:"amount"`
+	AmountDisplay string     `json:"amount_display"`
+	Note          string     `json:"note"`
+	RequireAccept bool       `json:"require_accept"`
+	Status        int8       `json:"status"`
+	ExpiredAt     *time.Time `json:"expired_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func NewTransfer(t *model.Transfer) Transfer {
+	return Transfer{
+		ID:            t.ID,
+		FromUserID:    t.FromUserID,
+		ToUserID:      t.ToUserID,
+		Currency:      t.Currency,
+		Amount:        t.Amount,
+		AmountDisplay: FormatAmount(t.Currency, t.Amount),
+		Note:          t.Note,
+		RequireAccept: t.RequireAccept,
+		Status:        t.Status,
+		ExpiredAt:     t.ExpiredAt,
+		FinishedAt:    t.FinishedAt,
+		CreatedAt:     t.CreatedAt,
+	}
+}
+
+func NewTransfers(list []model.Transfer) []Transfer {
+	items := make([]Transfer, 0, len(list))
+	for i := range list {
+		items = append(items, NewTransfer(&list[i]))
+	}
+	return items
+}
@@ -0,0 +1,104 @@
+package dto
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/service"
+)
+
+type Pong struct {
+	Message string `json:"message"`
+}
+
+type RegisterResponse struct {
+	ID       uint64 `json:"id"`
+	Username string `json:"username"`
+}
+
+type LoginResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"` // token 有效期（秒）
+}
+
+type Profile struct {
+	ID             uint64 `json:"id"`
+	Username       string `json:"username"`
+	Balance        uint64 `json:"balance"`
+	BalanceDisplay string `json:"balance_display"`
+	Currency       string `json:"currency"`
+	Role           string `json:"role"`
+	Status         int8   `json:"status"`
+}
+
+type Balance struct {
+	Currency  string `json:"currency"`
+	Balance   uint64 `json:"balance"`
+	Precision int    `json:"precision"`
+	Display   string `json:"display"`
+}
+
+type BalanceList struct {
+	List []Balance `json:"list"`
+}
+
+type QuotaItem struct {
+	Limit     uint64 `json:"limit"`
+	Used      uint64 `json:"used"`
+	Remaining uint64 `json:"remaining"`
+	Unlimited bool   `json:"unlimited"`
+}
+
+type Quota struct {
+	Currency        string    `json:"currency"`
+	MaxPacketAmount uint64    `json:"max_packet_amount"`
+	SendAmount      QuotaItem `json:"send_amount"`
+	SendCount       QuotaItem `json:"send_count"`
+	ReceiveAmount   QuotaItem `json:"receive_amount"`
+	ReceiveCount    QuotaItem `json:"receive_count"`
+	ResetAt         time.Time `json:"reset_at"`
+}
+
+func NewProfile(p *service.Profile) Profile {
+	return Profile{
+		ID:             p.ID,
+		Username:       p.Username,
+		Balance:        p.Balance,
+		BalanceDisplay: FormatAmount(p.Currency, p.Balance),
+		Currency:       p.Currency,
+		Role:           p.Role,
+		Status:         p.Status,
+	}
+}
+
+func NewBalances(items []service.BalanceItem) []Balance {
+	list := make([]Balance, 0, len(items))
+	for _, b := range items {
+		list = append(list, Balance{
+			Currency:  b.Currency,
+			Balance:   b.Balance,
+			Precision: b.Precision,
+			Display:   b.Display,
+		})
+	}
+	return list
+}
+
+func NewQuota(q *service.Quota) Quota {
+	item := func(i service.QuotaItem) QuotaItem {
+		return QuotaItem{Limit: i.Limit, Used: i.Used, Remaining: i.Remaining, Unlimited: i.Unlimited}
+	}
+	return Quota{
+		Currency:        q.Currency,
+		MaxPacketAmount: q.MaxPacketAmount,
+		SendAmount:      item(q.SendAmount),
+		SendCount:       item(q.SendCount),
+		ReceiveAmount:   item(q.ReceiveAmount),
+		ReceiveCount:    item(q.ReceiveCount),
+		ResetAt:         q.ResetAt,
+	}
+}
+
+func NewRegisterResponse(u *model.User) RegisterResponse {
+	return RegisterResponse{ID: u.ID, Username: u.Username}
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"red-packet/model"
+)
+
+type WebhookSubscription struct {
+	ID        uint64    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedBy uint64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookCreated 创建结果，secret 仅在此时返回一次
+type WebhookCreated struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+type WebhookSubscriptionList struct {
+	List []WebhookSubscription `json:"list"`
+}
+
+type WebhookDelivery struct {
+	ID             uint64    `json:"id"`
+	SubscriptionID uint64    `json:"subscription_id"`
+	EventID        uint64    `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         int8      `json:"status"`
+	Attempts       uint32    `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func NewWebhookSubscription(s *model.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        s.ID,
+		URL:       s.URL,
+		Events:    strings.Split(s.Events, ","),
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func NewWebhookSubscriptions(list []model.WebhookSubscription) []WebhookSubscription {
+	items := make([]WebhookSubscription, 0, len(list))
+	for i := range list {
+		items = append(items, NewWebhookSubscription(&list[i]))
+	}
+	return items
+}
+
+func NewWebhookDeliveries(list []model.WebhookDelivery) []WebhookDelivery {
+	items := make([]WebhookDelivery, 0, len(list))
+	for _, d := range list {
+		items = append(items, WebhookDelivery{
+			ID:             d.ID,
+			SubscriptionID: d.SubscriptionID,
+			EventID:        d.OutboxEventID,
+			EventType:      d.EventType,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+		})
+	}
+	return items
+}
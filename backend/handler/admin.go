@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"red-packet/dto"
+	"red-packet/pkg/pagination"
+	"red-packet/pkg/response"
+	"red-packet/repository"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FreezeUserRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type AdjustBalanceRequest struct {
+	Currency  string `json:"currency" binding:"omitempty,len=3"`
+	Direction int8   `json:"direction" binding:"required,oneof=1 2"`
+	Amount    uint64 `json:"amount" binding:"required,min=1"`
+	Remark    string `json:"remark" binding:"required,max=255"`
+}
+
+type RefundRedPacketRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+func adminContext(c *gin.Context) service.AdminContext {
+	adminID, _ := c.Get("user_id")
+	return service.AdminContext{AdminID: adminID.(uint64), IP: c.ClientIP()}
+}
+
+func AdminSearchUsers(c *gin.Context) {
+	p, err := pagination.Parse(c, 20, 100)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	users, result, err := service.SearchUsers(c.Query("keyword"), p)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+
+	response.Success(c, response.NewPage(dto.NewAdminUsers(users), result))
+}
+
+func AdminGetUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+
+	user, balances, err := service.GetUserForAdmin(userID)
+	if err != nil {
+		response.Fail(c, http.StatusNotFound, 404, err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewAdminUserDetail(user, balances))
+}
+
+func AdminFreezeUser(c *gin.Context) {
+	setUserFrozen(c, true)
+}
+
+func AdminUnfreezeUser(c *gin.Context) {
+	setUserFrozen(c, false)
+}
+
+func setUserFrozen(c *gin.Context, frozen bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+	var req FreezeUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Fail(c, http.StatusBadRequest, 400, err.Error())
+			return
+		}
+	}
+
+	if err := service.SetUserFrozen(adminContext(c), userID, frozen, req.Reason); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func AdminAdjustBalance(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+	var req AdjustBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	t, err := service.AdjustBalance(adminContext(c), service.AdjustBalanceParams{
+		UserID:    userID,
+		Currency:  req.Currency,
+		Direction: req.Direction,
+		Amount:    req.Amount,
+		Remark:    req.Remark,
+	})
+	if err != nil {
+		code := 400
+		if err.Error() == "insufficient balance" {
+			code = 1001
+		}
+		response.Fail(c, http.StatusBadRequest, code, err.Error())
+		return
+	}
+
+	response.Success(c, dto.AdjustBalanceResponse{
+		TransactionID: t.ID,
+		Currency:      t.Currency,
+		BalanceAfter:  t.BalanceAfter,
+	})
+}
+
+func AdminGetRedPacket(c *gin.Context) {
+	redPacketID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+
+	detail, err := service.GetRedPacketForAdmin(redPacketID)
+	if err != nil {
+		response.Fail(c, http.StatusNotFound, 404, err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewAdminRedPacket(detail))
+}
+
+func AdminRefundRedPacket(c *gin.Context) {
+	redPacketID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+	var req RefundRedPacketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	amount, err := service.ForceRefundRedPacket(adminContext(c), redPacketID, req.Reason)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	response.Success(c, dto.RefundResponse{RefundedAmount: amount})
+}
+
+func AdminListAuditLogs(c *gin.Context) {
+	p, err := pagination.Parse(c, 20, 100)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	adminID, _ := strconv.ParseUint(c.Query("admin_id"), 10, 64)
+	targetID, _ := strconv.ParseUint(c.Query("target_id"), 10, 64)
+
+	list, result, err := service.ListAuditLogs(repository.AuditLogFilter{
+		AdminID:    adminID,
+		TargetType: c.Query("target_type"),
+		TargetID:   targetID,
+	}, p)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+	response.Success(c, response.NewPage(dto.NewAuditLogs(list), result))
+}
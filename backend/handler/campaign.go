@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"red-packet/dto"
+	"red-packet/pkg/pagination"
+	"red-packet/pkg/response"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateCampaignRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Currency string `json:"currency" binding:"omitempty,len=3"`
+	// Budget 初始预算，可为 0，之后通过注资接口追加
+	Budget uint64 `json:"budget"`
+	// PerUserLimit 每人在本活动内最多领取的红包个数，0 表示不限
+	PerUserLimit uint32    `json:"per_user_limit"`
+	StartAt      time.Time `json:"start_at" binding:"required"`
+	EndAt        time.Time `json:"end_at" binding:"required"`
+}
+
+type FundCampaignRequest struct {
+	Amount uint64 `json:"amount" binding:"required,min=1"`
+	Remark string `json:"remark" binding:"required,max=255"`
+}
+
+type IssueCampaignBatchRequest struct {
+	Type         int8   `json:"type" binding:"required,oneof=1 2"`
+	PacketAmount uint64 `json:"packet_amount" binding:"required,min=1"`
+	PacketCount  uint32 `json:"packet_count" binding:"required,min=1,max=100"`
+	Packets      int    `json:"packets" binding:"required,min=1,max=500"`
+}
+
+func campaignErrorCode(err error) int {
+	switch err.Error() {
+	case "campaign budget exhausted":
+		return 1301
+	case "campaign has ended":
+		return 1302
+	case "campaign not found":
+		return 404
+	}
+	return 400
+}
+
+func AdminCreateCampaign(c *gin.Context) {
+	var req CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	campaign, err := service.CreateCampaign(adminContext(c), service.CampaignParams{
+		Name:         req.Name,
+		Currency:     req.Currency,
+		Budget:       req.Budget,
+		PerUserLimit: req.PerUserLimit,
+		StartAt:      req.StartAt,
+		EndAt:        req.EndAt,
+	})
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewCampaign(campaign))
+}
+
+func AdminListCampaigns(c *gin.Context) {
+	p, err := pagination.Parse(c, 20, 100)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	list, result, err := service.ListCampaigns(p)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+	response.Success(c, response.NewPage(dto.NewCampaigns(list), result))
+}
+
+func AdminGetCampaign(c *gin.Context) {
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+
+	campaign, report, err := service.GetCampaignReport(campaignID)
+	if err != nil {
+		response.Fail(c, http.StatusNotFound, 404, err.Error())
+		return
+	}
+	response.Success(c, dto.NewCampaignDetail(campaign, report))
+}
+
+func AdminFundCampaign(c *gin.Context) {
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+	var req FundCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	campaign, err := service.FundCampaign(adminContext(c), campaignID, req.Amount, req.Remark)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, campaignErrorCode(err), err.Error())
+		return
+	}
+	response.Success(c, dto.NewCampaign(campaign))
+}
+
+func AdminIssueCampaignBatch(c *gin.Context) {
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+	var req IssueCampaignBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	packets, err := service.IssueCampaignBatch(adminContext(c), campaignID, service.CampaignBatchParams{
+		Type:         req.Type,
+		PacketAmount: req.PacketAmount,
+		PacketCount:  req.PacketCount,
+		Packets:      req.Packets,
+	})
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, campaignErrorCode(err), err.Error())
+		return
+	}
+	response.Success(c, dto.NewCampaignBatch(packets))
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"red-packet/dto"
+	"red-packet/model"
+	"red-packet/pkg/response"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnqueueClaim 排队领取：请求体与领红包相同，立即返回排队中的请求，结果通过查询接口获取
+func EnqueueClaim(c *gin.Context) {
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
+		return
+	}
+
+	var req ClaimRedPacketRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Fail(c, http.StatusBadRequest, 400, err.Error())
+			return
+		}
+	}
+	if req.ShareToken == "" {
+		req.ShareToken = c.Query("t")
+	}
+
+	receiverID, _ := c.Get("user_id")
+	ticket, err := service.EnqueueClaim(service.ClaimRedPacketParams{
+		RedPacketID: redPacketID,
+		ReceiverID:  receiverID.(uint64),
+		Secret:      req.Secret,
+		ShareToken:  req.ShareToken,
+		Meta:        requestMeta(c),
+	})
+	if err != nil {
+		code := claimErrorCode(err)
+		status := http.StatusBadRequest
+		if code == 1015 {
+			status = http.StatusTooManyRequests
+		}
+		response.Fail(c, status, code, err.Error())
+		return
+	}
+
+	response.Success(c, newClaimTicket(ticket))
+}
+
+// GetClaimTicket 查询排队领取结果，wait 为长轮询等待秒数
+func GetClaimTicket(c *gin.Context) {
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
+		return
+	}
+	ticketID, err := strconv.ParseUint(c.Param("ticket_id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid ticket id")
+		return
+	}
+	wait := 0
+	if s := c.Query("wait"); s != "" {
+		if wait, err = strconv.Atoi(s); err != nil || wait < 0 {
+			response.Fail(c, http.StatusBadRequest, 400, "invalid wait")
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+	ticket, err := service.GetClaimTicket(redPacketID, ticketID, userID.(uint64), time.Duration(wait)*time.Second)
+	if err != nil {
+		response.Fail(c, http.StatusNotFound, 404, err.Error())
+		return
+	}
+
+	response.Success(c, newClaimTicket(ticket))
+}
+
+func newClaimTicket(t *service.ClaimTicketResult) dto.ClaimTicket {
+	code := 0
+	if t.Status == model.ClaimTicketFailed {
+		code = claimErrorCode(errors.New(t.Reason))
+	}
+	return dto.NewClaimTicket(t, code)
+}
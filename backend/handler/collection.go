@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"red-packet/dto"
+	"red-packet/pkg/pagination"
+	"red-packet/pkg/response"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateCollectionRequest struct {
+	Title    string `json:"title" binding:"required,max=100"`
+	Currency string `json:"currency" binding:"omitempty,len=3"`
+	// TotalAmount 与 PerPersonAmount 二选一：总额按人数均分，或每人固定金额
+	TotalAmount     uint64   `json:"total_amount"`
+	PerPersonAmount uint64   `json:"per_person_amount"`
+	Participants    []string `json:"participants" binding:"required,min=1,max=100"`
+	// ExpireHours 收款有效期，默认 168 小时，最长 720 小时
+	ExpireHours int `json:"expire_hours" binding:"omitempty,min=1,max=720"`
+}
+
+func collectionErrorCode(err error) int {
+	switch err.Error() {
+	case "insufficient balance":
+		return 1001
+	case "account is frozen":
+		return 1008
+	case "already paid":
+		return 1201
+	case "collection is closed":
+		return 1202
+	case "remind too frequent":
+		return 1203
+	case "participant not found":
+		return 1204
+	case "initiator daily receive limit exceeded":
+		return 1205
+	case "risk denied":
+		return 1009
+	case "risk challenge required":
+		return 1010
+	case "daily send amount limit exceeded", "daily send count limit exceeded":
+		return 1011
+	case "collection not found":
+		return 404
+	}
+	return 400
+}
+
+func CreateCollection(c *gin.Context) {
+	var req CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	detail, err := service.CreateCollection(service.CollectionParams{
+		InitiatorID:  userID.(uint64),
+		Title:        req.Title,
+		Currency:     req.Currency,
+		TotalAmount:  req.TotalAmount,
+		PerPerson:    req.PerPersonAmount,
+		Participants: req.Participants,
+		TTL:          time.Duration(req.ExpireHours) * time.Hour,
+	})
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, collectionErrorCode(err), err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewCollectionDetail(detail))
+}
+
+func GetCollections(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	p, err := pagination.Parse(c, 10, 50)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	list, result, err := service.GetUserCollections(userID.(uint64), p)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+
+	response.Success(c, response.NewPage(dto.NewCollections(list), result))
+}
+
+func GetCollection(c *gin.Context) {
+	collectionAction(c, service.GetCollection)
+}
+
+func PayCollection(c *gin.Context) {
+	collectionAction(c, func(collectionID, userID uint64) (*service.CollectionDetail, error) {
+		return service.PayCollection(collectionID, userID, requestMeta(c))
+	})
+}
+
+func CancelCollection(c *gin.Context) {
+	collectionAction(c, service.CancelCollection)
+}
+
+func RemindCollection(c *gin.Context) {
+	collectionID, ok := collectionParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	reminded, err := service.RemindCollection(collectionID, userID.(uint64))
+	if err != nil {
+		code := collectionErrorCode(err)
+		response.Fail(c, collectionHTTPStatus(code), code, err.Error())
+		return
+	}
+
+	response.Success(c, dto.RemindResponse{Reminded: reminded})
+}
+
+func collectionAction(c *gin.Context, fn func(collectionID, userID uint64) (*service.CollectionDetail, error)) {
+	collectionID, ok := collectionParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	detail, err := fn(collectionID, userID.(uint64))
+	if err != nil {
+		code := collectionErrorCode(err)
+		response.Fail(c, collectionHTTPStatus(code), code, err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewCollectionDetail(detail))
+}
+
+func collectionParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
+
+func collectionHTTPStatus(code int) int {
+	if code == 404 {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"red-packet/dto"
+	"red-packet/pkg/response"
+	"red-packet/repository"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminPullEvents 按序号拉取领域事件，供数据分析、通知等内部消费方增量同步
+func AdminPullEvents(c *gin.Context) {
+	after, err := strconv.ParseUint(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid after")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 500 {
+		response.Fail(c, http.StatusBadRequest, 400, "limit must be between 1 and 500")
+		return
+	}
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	var types []string
+	if v := c.Query("types"); v != "" {
+		types = strings.Split(v, ",")
+	}
+
+	page, err := service.PullDomainEvents(repository.DomainEventFilter{
+		AfterSeq: after,
+		Types:    types,
+		UserID:   userID,
+	}, limit)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+	response.Success(c, dto.NewDomainEventList(page))
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"red-packet/dto"
+	"red-packet/pkg/response"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateRainRequest struct {
+	TotalAmount uint64 `json:"total_amount" binding:"required,min=1"`
+	DropCount   uint32 `json:"drop_count" binding:"required,min=1,max=100000"`
+	// PerUserCap 每人最多抢到的份数，默认 1
+	PerUserCap uint32    `json:"per_user_cap"`
+	StartAt    time.Time `json:"start_at" binding:"required"`
+	EndAt      time.Time `json:"end_at" binding:"required"`
+}
+
+type SubmitRainClaimRequest struct {
+	Ticket string `json:"ticket" binding:"required,max=200"`
+}
+
+func rainErrorCode(err error) int {
+	switch err.Error() {
+	case "account is frozen":
+		return 1008
+	case "risk denied":
+		return 1009
+	case "risk challenge required":
+		return 1010
+	case "campaign budget exhausted":
+		return 1301
+	case "campaign has ended":
+		return 1302
+	case "rain is over":
+		return 1401
+	case "rain has not started":
+		return 1402
+	case "invalid rain ticket", "rain ticket expired":
+		return 1403
+	case "ticket already used":
+		return 1404
+	case "rain claim limit reached":
+		return 1405
+	case "rain queue is full":
+		return 1406
+	case "rain not found", "rain claim not found", "campaign not found":
+		return 404
+	}
+	return 400
+}
+
+func rainFail(c *gin.Context, err error) {
+	code := rainErrorCode(err)
+	status := http.StatusBadRequest
+	switch code {
+	case 404:
+		status = http.StatusNotFound
+	case 1406:
+		status = http.StatusTooManyRequests
+	}
+	response.Fail(c, status, code, err.Error())
+}
+
+func GetRain(c *gin.Context) {
+	rainID, ok := rainParam(c)
+	if !ok {
+		return
+	}
+
+	rain, err := service.GetRain(rainID)
+	if err != nil {
+		rainFail(c, err)
+		return
+	}
+	response.Success(c, dto.NewRain(rain))
+}
+
+// IssueRainTicket 领取券可在开始前预领，签券时做风控检查
+func IssueRainTicket(c *gin.Context) {
+	rainID, ok := rainParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	ticket, err := service.IssueRainTicket(rainID, userID.(uint64), requestMeta(c))
+	if err != nil {
+		rainFail(c, err)
+		return
+	}
+	response.Success(c, dto.NewRainTicket(ticket))
+}
+
+// SubmitRainClaim 凭券入队，立即返回排队中的请求，结果通过查询接口获取
+func SubmitRainClaim(c *gin.Context) {
+	rainID, ok := rainParam(c)
+	if !ok {
+		return
+	}
+	var req SubmitRainClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	claim, err := service.SubmitRainClaim(rainID, userID.(uint64), req.Ticket)
+	if err != nil {
+		rainFail(c, err)
+		return
+	}
+	response.Success(c, dto.NewRainClaim(claim))
+}
+
+// GetRainClaim 查询领取结果，wait 为长轮询等待秒数
+func GetRainClaim(c *gin.Context) {
+	rainID, ok := rainParam(c)
+	if !ok {
+		return
+	}
+	claimID, err := strconv.ParseUint(c.Param("claim_id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid claim id")
+		return
+	}
+	wait := 0
+	if s := c.Query("wait"); s != "" {
+		if wait, err = strconv.Atoi(s); err != nil || wait < 0 {
+			response.Fail(c, http.StatusBadRequest, 400, "invalid wait")
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+	claim, err := service.GetRainClaim(rainID, claimID, userID.(uint64), time.Duration(wait)*time.Second)
+	if err != nil {
+		rainFail(c, err)
+		return
+	}
+	response.Success(c, dto.NewRainClaim(claim))
+}
+
+func AdminCreateRain(c *gin.Context) {
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+	var req CreateRainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	rain, err := service.CreateRain(adminContext(c), campaignID, service.RainParams{
+		TotalAmount: req.TotalAmount,
+		DropCount:   req.DropCount,
+		PerUserCap:  req.PerUserCap,
+		StartAt:     req.StartAt,
+		EndAt:       req.EndAt,
+	})
+	if err != nil {
+		rainFail(c, err)
+		return
+	}
+	response.Success(c, dto.NewRain(rain))
+}
+
+func rainParam(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"net/http"
-	"strconv"
+	"time"
 
+	"red-packet/dto"
+	"red-packet/pkg/pagination"
 	"red-packet/pkg/response"
 	"red-packet/service"
 
@@ -11,9 +13,50 @@ import (
 )
 
 type SendRedPacketRequest struct {
-	Type        int8   `json:"type" binding:"required,oneof=1 2"`
+	Type        int8   `json:"type" binding:"required,oneof=1 2 3 4"`
 	TotalAmount uint64 `json:"total_amount" binding:"required,min=1"`
 	TotalCount  uint32 `json:"total_count" binding:"required,min=1,max=100"`
+	// Currency 币种代码，不传则使用默认币种
+	Currency string `json:"currency" binding:"omitempty,len=3"`
+	// OpenAt 定时开启时间（RFC3339），不传则立即开启
+	OpenAt *time.Time `json:"open_at"`
+	// Secret 口令，设置后领取时需提交相同口令
+	Secret string `json:"secret" binding:"omitempty,max=32"`
+	// RequireShare 为 true 时只能通过发送者生成的分享链接领取
+	RequireShare bool `json:"require_share"`
+	// DrawAt 抽奖红包（type=3）必填，报名截止并开奖的时间（RFC3339），total_count 为中奖名额
+	DrawAt *time.Time `json:"draw_at"`
+	// Allocations 指定金额红包（type=4）必填，个数等于 total_count，金额之和等于 total_amount
+	Allocations []AllocationRequest `json:"allocations" binding:"omitempty,max=100,dive"`
+}
+
+type AllocationRequest struct {
+	ReceiverID uint64 `json:"receiver_id" binding:"required"`
+	Amount     uint64 `json:"amount" binding:"required,min=1"`
+}
+
+type ClaimRedPacketRequest struct {
+	Secret string `json:"secret" binding:"omitempty,max=32"`
+	// ShareToken 分享链接中的令牌，也可通过查询参数 t 传入
+	ShareToken string `json:"share_token"`
+}
+
+// redPacketParam 用户侧路由中的 :id 为公开ID，解析为内部ID；失败时已写入响应
+func redPacketParam(c *gin.Context) (uint64, bool) {
+	id, err := service.ResolveRedPacketID(c.Param("id"))
+	if err != nil {
+		response.Fail(c, http.StatusNotFound, 404, err.Error())
+		return 0, false
+	}
+	return id, true
+}
+
+// requestMeta 提取风控所需的请求来源信息
+func requestMeta(c *gin.Context) service.RequestMeta {
+	return service.RequestMeta{
+		IP:       c.ClientIP(),
+		DeviceID: c.GetHeader("X-Device-ID"),
+	}
 }
 
 func SendRedPacket(c *gin.Context) {
@@ -23,57 +66,245 @@ func SendRedPacket(c *gin.Context) {
 		return
 	}
 
+	allocations := make([]service.Allocation, 0, len(req.Allocations))
+	for _, a := range req.Allocations {
+		allocations = append(allocations, service.Allocation{ReceiverID: a.ReceiverID, Amount: a.Amount})
+	}
+
 	senderID, _ := c.Get("user_id")
 	rp, err := service.SendRedPacket(service.SendRedPacketParams{
-		SenderID:    senderID.(uint64),
-		Type:        req.Type,
-		TotalAmount: req.TotalAmount,
-		TotalCount:  req.TotalCount,
+		SenderID:     senderID.(uint64),
+		Type:         req.Type,
+		TotalAmount:  req.TotalAmount,
+		TotalCount:   req.TotalCount,
+		Currency:     req.Currency,
+		OpenAt:       req.OpenAt,
+		Secret:       req.Secret,
+		RequireShare: req.RequireShare,
+		DrawAt:       req.DrawAt,
+		Allocations:  allocations,
+		Meta:         requestMeta(c),
 	})
 	if err != nil {
-		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		code := 400
+		switch err.Error() {
+		case "account is frozen":
+			code = 1008
+		case "risk denied":
+			code = 1009
+		case "risk challenge required":
+			code = 1010
+		case "insufficient balance":
+			code = 1001
+		case "red packet amount exceeds limit", "daily send amount limit exceeded", "daily send count limit exceeded":
+			code = 1011
+		}
+		response.Fail(c, http.StatusBadRequest, code, err.Error())
 		return
 	}
 
-	response.Success(c, gin.H{
-		"id":           rp.ID,
-		"type":         rp.Type,
-		"total_amount": rp.TotalAmount,
-		"total_count":  rp.TotalCount,
-		"expired_at":   rp.ExpiredAt,
-	})
+	response.Success(c, dto.NewRedPacket(rp))
 }
 
 func ClaimRedPacket(c *gin.Context) {
-	redPacketID, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
 		return
 	}
 
+	// 请求体可选，仅口令红包、分享链接红包需要
+	var req ClaimRedPacketRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Fail(c, http.StatusBadRequest, 400, err.Error())
+			return
+		}
+	}
+
+	if req.ShareToken == "" {
+		req.ShareToken = c.Query("t")
+	}
+
 	receiverID, _ := c.Get("user_id")
-	amount, err := service.ClaimRedPacket(redPacketID, receiverID.(uint64))
+	amount, err := service.ClaimRedPacket(service.ClaimRedPacketParams{
+		RedPacketID: redPacketID,
+		ReceiverID:  receiverID.(uint64),
+		Secret:      req.Secret,
+		ShareToken:  req.ShareToken,
+		Meta:        requestMeta(c),
+	})
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, claimErrorCode(err), err.Error())
+		return
+	}
+
+	response.Success(c, dto.ClaimResponse{Amount: amount})
+}
+
+// claimErrorCode 领取失败的业务错误码，同步领取与排队领取的结果共用
+func claimErrorCode(err error) int {
+	switch err.Error() {
+	case "red packet is empty":
+		return 1002
+	case "red packet is expired":
+		return 1003
+	case "already claimed":
+		return 1004
+	case "red packet is not open yet":
+		return 1005
+	case "wrong secret":
+		return 1006
+	case "too many wrong secret attempts":
+		return 1007
+	case "account is frozen":
+		return 1008
+	case "risk denied":
+		return 1009
+	case "risk challenge required":
+		return 1010
+	case "daily receive amount limit exceeded", "daily receive count limit exceeded":
+		return 1011
+	case "invalid share token", "share link expired":
+		return 1012
+	case "not a designated receiver":
+		return 1014
+	case "claim queue is full":
+		return 1015
+	case "red packet is busy":
+		return 1016
+	case "campaign is not active":
+		return 1303
+	case "campaign claim limit reached":
+		return 1304
+	}
+	return 400
+}
+
+// EnterLottery 报名抽奖红包，请求体与领红包相同
+func EnterLottery(c *gin.Context) {
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
+		return
+	}
+
+	var req ClaimRedPacketRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Fail(c, http.StatusBadRequest, 400, err.Error())
+			return
+		}
+	}
+	if req.ShareToken == "" {
+		req.ShareToken = c.Query("t")
+	}
+
+	userID, _ := c.Get("user_id")
+	count, err := service.EnterLottery(service.ClaimRedPacketParams{
+		RedPacketID: redPacketID,
+		ReceiverID:  userID.(uint64),
+		Secret:      req.Secret,
+		ShareToken:  req.ShareToken,
+		Meta:        requestMeta(c),
+	})
 	if err != nil {
 		code := 400
 		switch err.Error() {
-		case "red packet is empty":
-			code = 1002
 		case "red packet is expired":
 			code = 1003
-		case "already claimed":
+		case "already entered":
 			code = 1004
+		case "red packet is not open yet":
+			code = 1005
+		case "wrong secret":
+			code = 1006
+		case "too many wrong secret attempts":
+			code = 1007
+		case "account is frozen":
+			code = 1008
+		case "risk denied":
+			code = 1009
+		case "risk challenge required":
+			code = 1010
+		case "invalid share token", "share link expired":
+			code = 1012
+		case "lottery is closed":
+			code = 1013
 		}
 		response.Fail(c, http.StatusBadRequest, code, err.Error())
 		return
 	}
 
-	response.Success(c, gin.H{"amount": amount})
+	response.Success(c, dto.EnterResponse{EntryCount: count})
 }
 
-func GetRedPacketDetail(c *gin.Context) {
-	redPacketID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+// CreateShareLink 发送者为红包生成带签名、会过期的分享链接
+func CreateShareLink(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	link, err := service.CreateShareLink(userID.(uint64), c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "red packet not found":
+			response.Fail(c, http.StatusNotFound, 404, err.Error())
+		case "only the sender can share":
+			response.Fail(c, http.StatusForbidden, 403, err.Error())
+		case "red packet is empty":
+			response.Fail(c, http.StatusBadRequest, 1002, err.Error())
+		case "red packet is expired":
+			response.Fail(c, http.StatusBadRequest, 1003, err.Error())
+		case "lottery is closed":
+			response.Fail(c, http.StatusBadRequest, 1013, err.Error())
+		default:
+			response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		}
+		return
+	}
+
+	response.Success(c, dto.NewShareLink(link))
+}
+
+// GetShareQRCode 分享链接的二维码 PNG，令牌无效或过期时返回普通错误响应
+func GetShareQRCode(c *gin.Context) {
+	png, err := service.ShareQRCode(c.Param("id"), c.Query("t"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid share token", "share link expired":
+			response.Fail(c, http.StatusBadRequest, 1012, err.Error())
+		default:
+			response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// GetRedPacketFairness 拼手气红包的种子承诺和领取顺序，红包结束后附带种子和重算结果
+func GetRedPacketFairness(c *gin.Context) {
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
+		return
+	}
+
+	f, err := service.GetFairness(redPacketID)
 	if err != nil {
-		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		switch err.Error() {
+		case "red packet not found":
+			response.Fail(c, http.StatusNotFound, 404, err.Error())
+		case "red packet is not verifiable":
+			response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		default:
+			response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		}
+		return
+	}
+
+	response.Success(c, dto.NewFairness(f))
+}
+
+func GetRedPacketDetail(c *gin.Context) {
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
 		return
 	}
 
@@ -84,69 +315,60 @@ func GetRedPacketDetail(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, gin.H{
-		"id":               detail.ID,
-		"sender_id":        detail.SenderID,
-		"sender_name":      detail.SenderName,
-		"type":             detail.Type,
-		"total_amount":     detail.TotalAmount,
-		"total_count":      detail.TotalCount,
-		"remaining_amount": detail.RemainingAmount,
-		"remaining_count":  detail.RemainingCount,
-		"claimed_count":    detail.ClaimedCount,
-		"status":           detail.Status,
-		"expired_at":       detail.ExpiredAt,
-		"created_at":       detail.CreatedAt,
-		"my_claim":         detail.MyClaim,
-	})
+	response.Success(c, dto.NewRedPacketDetail(detail))
 }
 
 func GetRedPacketRecords(c *gin.Context) {
-	redPacketID, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+	redPacketID, ok := redPacketParam(c)
+	if !ok {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	if pageSize > 50 {
-		pageSize = 50
+	p, err := pagination.Parse(c, 10, 50)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
 	}
 
-	records, total, err := service.GetRedPacketRecords(redPacketID, page, pageSize)
+	records, result, err := service.GetRedPacketRecords(redPacketID, p)
 	if err != nil {
 		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
 		return
 	}
 
-	response.Success(c, gin.H{"total": total, "list": records})
+	response.Success(c, response.NewPage(dto.NewRedPacketRecords(records), result))
 }
 
 func GetSentRedPackets(c *gin.Context) {
 	senderID, _ := c.Get("user_id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	p, err := pagination.Parse(c, 10, 50)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
 
-	list, total, err := service.GetSentRedPackets(senderID.(uint64), page, pageSize)
+	list, result, err := service.GetSentRedPackets(senderID.(uint64), p)
 	if err != nil {
 		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
 		return
 	}
 
-	response.Success(c, gin.H{"total": total, "list": list})
+	response.Success(c, response.NewPage(dto.NewRedPackets(list), result))
 }
 
 func GetReceivedRedPackets(c *gin.Context) {
 	receiverID, _ := c.Get("user_id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	p, err := pagination.Parse(c, 10, 50)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
 
-	list, total, err := service.GetReceivedRedPackets(receiverID.(uint64), page, pageSize)
+	list, result, err := service.GetReceivedRedPackets(receiverID.(uint64), p)
 	if err != nil {
 		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
 		return
 	}
 
-	response.Success(c, gin.H{"total": total, "list": list})
+	response.Success(c, response.NewPage(dto.NewReceivedRedPackets(list), result))
 }
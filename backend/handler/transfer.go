@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"red-packet/dto"
+	"red-packet/model"
+	"red-packet/pkg/pagination"
+	"red-packet/pkg/response"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTransferRequest struct {
+	ToUsername string `json:"to_username" binding:"required"`
+	Amount     uint64 `json:"amount" binding:"required,min=1"`
+	Currency   string `json:"currency" binding:"omitempty,len=3"`
+	Note       string `json:"note" binding:"max=100"`
+	// RequireAccept 为 true 时需对方在 24 小时内确认收款，否则自动退回
+	RequireAccept bool `json:"require_accept"`
+}
+
+func transferErrorCode(err error) int {
+	switch err.Error() {
+	case "insufficient balance":
+		return 1001
+	case "account is frozen":
+		return 1008
+	case "risk denied":
+		return 1009
+	case "risk challenge required":
+		return 1010
+	case "daily send amount limit exceeded", "daily send count limit exceeded",
+		"daily receive amount limit exceeded", "daily receive count limit exceeded":
+		return 1011
+	case "recipient not found":
+		return 1101
+	case "transfer is expired", "transfer is already finished":
+		return 1102
+	case "recipient daily receive limit exceeded":
+		return 1103
+	}
+	return 400
+}
+
+func CreateTransfer(c *gin.Context) {
+	var req CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	t, err := service.CreateTransfer(service.TransferParams{
+		FromUserID:    userID.(uint64),
+		ToUsername:    req.ToUsername,
+		Currency:      req.Currency,
+		Amount:        req.Amount,
+		Note:          req.Note,
+		RequireAccept: req.RequireAccept,
+		Meta:          requestMeta(c),
+	})
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, transferErrorCode(err), err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewTransfer(t))
+}
+
+func AcceptTransfer(c *gin.Context) {
+	finishTransfer(c, service.AcceptTransfer)
+}
+
+func RejectTransfer(c *gin.Context) {
+	finishTransfer(c, service.RejectTransfer)
+}
+
+func finishTransfer(c *gin.Context, fn func(transferID, userID uint64) (*model.Transfer, error)) {
+	transferID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	t, err := fn(transferID, userID.(uint64))
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, transferErrorCode(err), err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewTransfer(t))
+}
+
+func GetTransfers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	p, err := pagination.Parse(c, 10, 50)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	transfers, result, err := service.GetUserTransfers(userID.(uint64), p)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+
+	response.Success(c, response.NewPage(dto.NewTransfers(transfers), result))
+}
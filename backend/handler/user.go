@@ -1,8 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"red-packet/dto"
 	"red-packet/pkg/response"
 	"red-packet/service"
 
@@ -32,10 +38,7 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-	})
+	response.Success(c, dto.NewRegisterResponse(user))
 }
 
 func Login(c *gin.Context) {
@@ -51,9 +54,9 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, gin.H{
-		"token":      token,
-		"expires_in": 86400,
+	response.Success(c, dto.LoginResponse{
+		Token:     token,
+		ExpiresIn: int64(service.TokenTTL().Seconds()),
 	})
 }
 
@@ -66,9 +69,108 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"balance":  user.Balance,
-	})
+	response.Success(c, dto.NewProfile(user))
+}
+
+func ListBalances(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	list, err := service.ListBalances(userID.(uint64))
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+
+	response.Success(c, dto.BalanceList{List: dto.NewBalances(list)})
+}
+
+func GetQuota(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	quota, err := service.GetQuota(userID.(uint64), c.Query("currency"))
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewQuota(quota))
+}
+
+// GetReport 个人收发报告：默认统计今年，传 year 统计指定年份，或用 start/end（YYYY-MM-DD，含首尾）指定区间
+func GetReport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	period, err := parseReportPeriod(c)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	report, err := service.GetReport(userID.(uint64), c.Query("currency"), period)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	response.Success(c, dto.NewReport(report))
+}
+
+func parseReportPeriod(c *gin.Context) (service.ReportPeriod, error) {
+	if c.Query("start") != "" || c.Query("end") != "" {
+		start, end, err := parseDateRange(c)
+		if err != nil {
+			return service.ReportPeriod{}, err
+		}
+		return service.NewReportPeriod(start, end)
+	}
+
+	year := time.Now().Year()
+	if v := c.Query("year"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2000 || n > year {
+			return service.ReportPeriod{}, errors.New("invalid year")
+		}
+		year = n
+	}
+	return service.YearPeriod(year), nil
+}
+
+// parseDateRange 解析 start/end 日期参数（YYYY-MM-DD，服务器时区），两者都必填
+func parseDateRange(c *gin.Context) (time.Time, time.Time, error) {
+	start, err1 := time.ParseInLocation(time.DateOnly, c.Query("start"), time.Local)
+	end, err2 := time.ParseInLocation(time.DateOnly, c.Query("end"), time.Local)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, time.Time{}, errors.New("start and end must both be dates like 2026-01-31")
+	}
+	return start, end, nil
+}
+
+// ExportStatement 流式导出对账单，参数校验在写响应头之前完成；开始输出后出错只能中断连接
+func ExportStatement(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	start, end, err := parseDateRange(c)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	params, err := service.NewStatementParams(userID.(uint64), c.Query("currency"), c.Query("format"), c.Query("lang"), start, end)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if params.Format == service.StatementFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	filename := fmt.Sprintf("statement_%s_%s.%s", start.Format("20060102"), end.Format("20060102"), params.Format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	if err := service.WriteStatement(c.Writer, params); err != nil {
+		log.Printf("export statement for user %d failed: %v", params.UserID, err)
+		c.Abort()
+	}
 }
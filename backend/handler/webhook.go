@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"red-packet/dto"
+	"red-packet/pkg/pagination"
+	"red-packet/pkg/response"
+	"red-packet/repository"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=500"`
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=64"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+func AdminCreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	sub, err := service.CreateWebhookSubscription(adminContext(c), service.CreateWebhookParams{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+	})
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+
+	response.Success(c, dto.WebhookCreated{
+		WebhookSubscription: dto.NewWebhookSubscription(sub),
+		Secret:              sub.Secret,
+	})
+}
+
+func AdminListWebhooks(c *gin.Context) {
+	list, err := service.ListWebhookSubscriptions()
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+	response.Success(c, dto.WebhookSubscriptionList{List: dto.NewWebhookSubscriptions(list)})
+}
+
+func AdminDeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+
+	if err := service.DeleteWebhookSubscription(adminContext(c), id); err != nil {
+		response.Fail(c, http.StatusNotFound, 404, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func AdminListWebhookDeliveries(c *gin.Context) {
+	p, err := pagination.Parse(c, 20, 100)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	subscriptionID, _ := strconv.ParseUint(c.Query("subscription_id"), 10, 64)
+	status, _ := strconv.ParseInt(c.Query("status"), 10, 8)
+
+	list, result, err := service.ListWebhookDeliveries(repository.WebhookDeliveryFilter{
+		SubscriptionID: subscriptionID,
+		Status:         int8(status),
+	}, p)
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, 500, "internal error")
+		return
+	}
+	response.Success(c, response.NewPage(dto.NewWebhookDeliveries(list), result))
+}
+
+func AdminRedeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, "invalid id")
+		return
+	}
+
+	if err := service.RedeliverWebhook(adminContext(c), id); err != nil {
+		response.Fail(c, http.StatusBadRequest, 400, err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
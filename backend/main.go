@@ -1,11 +1,18 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"red-packet/config"
 	"red-packet/database"
+	"red-packet/pkg/broker"
+	"red-packet/pkg/currency"
+	"red-packet/risk"
 	"red-packet/router"
+	"red-packet/scheduler"
 	"red-packet/service"
 )
 
@@ -15,6 +22,17 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	// 币种先于数据库初始化：迁移旧版余额时使用配置的默认币种
+	if len(cfg.Currency.List) > 0 {
+		list := make([]currency.Currency, 0, len(cfg.Currency.List))
+		for _, c := range cfg.Currency.List {
+			list = append(list, currency.Currency{Code: c.Code, Precision: c.Precision})
+		}
+		if err := currency.Init(cfg.Currency.Default, list); err != nil {
+			log.Fatalf("failed to init currency: %v", err)
+		}
+	}
+
 	if err := database.Init(cfg); err != nil {
 		log.Fatalf("failed to init database: %v", err)
 	}
@@ -22,6 +40,102 @@ func main() {
 
 	service.InitUserService(cfg.JWT.Secret, cfg.JWT.ExpireHours)
 
+	shareSecret := cfg.Share.Secret
+	if shareSecret == "" {
+		shareSecret = cfg.JWT.Secret
+	}
+	shareTTL := time.Duration(cfg.Share.TTLHours) * time.Hour
+	if shareTTL <= 0 {
+		shareTTL = 24 * time.Hour
+	}
+	service.InitShareService(shareSecret, cfg.Share.BaseURL, shareTTL)
+
+	if err := service.InitClaimStrategy(cfg.Claim.Strategy); err != nil {
+		log.Fatalf("failed to init claim strategy: %v", err)
+	}
+
+	rainSecret := cfg.Rain.Secret
+	if rainSecret == "" {
+		rainSecret = cfg.JWT.Secret
+	}
+	rainTTL := time.Duration(cfg.Rain.TicketTTLSeconds) * time.Second
+	if rainTTL <= 0 {
+		rainTTL = 30 * time.Second
+	}
+	rainBatch := cfg.Rain.BatchSize
+	if rainBatch <= 0 {
+		rainBatch = 200
+	}
+	service.InitRainService(rainSecret, rainTTL, rainBatch)
+
+	limits := make(map[string]service.Limit, len(cfg.Limits))
+	for _, l := range cfg.Limits {
+		limits[strings.ToUpper(l.Currency)] = service.Limit{
+			MaxPacketAmount:    l.MaxPacketAmount,
+			DailySendAmount:    l.DailySendAmount,
+			DailySendCount:     l.DailySendCount,
+			DailyReceiveAmount: l.DailyReceiveAmount,
+			DailyReceiveCount:  l.DailyReceiveCount,
+		}
+	}
+	service.InitLimitService(limits)
+	service.InitWebhookService(cfg.Webhook.AllowPrivateTargets)
+	service.EnsureAdmins(cfg.Admin.Usernames)
+
+	if cfg.Risk.Enabled {
+		engine, err := risk.NewRulesEngine(cfg.Risk.Rules)
+		if err != nil {
+			log.Fatalf("failed to init risk engine: %v", err)
+		}
+		service.InitRiskService(engine)
+	}
+
+	if cfg.Events.Relay.Enabled {
+		topic := cfg.Events.Relay.Topic
+		if topic == "" {
+			topic = "domain-events"
+		}
+		b, err := newBroker(cfg.Events.Relay.Broker, topic)
+		if err != nil {
+			log.Fatalf("failed to init event relay: %v", err)
+		}
+		service.InitEventRelay(b, topic)
+	}
+
+	interval := cfg.Scheduler.IntervalSeconds
+	if interval <= 0 {
+		interval = 1
+	}
+	scheduler.Start(time.Duration(interval) * time.Second)
+
+	rainWorkers := cfg.Rain.Workers
+	if rainWorkers <= 0 {
+		rainWorkers = 2
+	}
+	rainPoll := time.Duration(cfg.Rain.PollIntervalMs) * time.Millisecond
+	if rainPoll <= 0 {
+		rainPoll = 200 * time.Millisecond
+	}
+	scheduler.StartRainWorkers(rainWorkers, rainPoll)
+
 	r := router.NewRouter()
+	// 风控按客户端 IP 计数，未配置代理时忽略 X-Forwarded-For，防止伪造
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
 	r.Run(":" + cfg.Server.Port)
 }
+
+// newBroker 按配置创建事件转发的消息中间件；memory 为进程内实现，进程内消费方通过 Subscribe 接入，默认只打印日志
+func newBroker(name, topic string) (broker.Broker, error) {
+	switch name {
+	case "", "memory":
+		m := broker.NewMemory()
+		m.Subscribe(topic, func(topic string, msg broker.Message) {
+			log.Printf("event relay %s seq=%s", topic, msg.Key)
+		})
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported broker: %s", name)
+	}
+}
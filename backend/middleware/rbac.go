@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"red-packet/pkg/response"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 校验当前用户角色是否拥有指定权限，需放在 Auth 之后
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		role, err := service.GetUserRole(userID.(uint64))
+		if err != nil || !service.HasPermission(role, perm) {
+			response.Fail(c, http.StatusForbidden, 403, "forbidden")
+			c.Abort()
+			return
+		}
+
+		c.Set("role", role)
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 审计操作类型
+const (
+	AuditActionFreezeUser       = "freeze_user"
+	AuditActionUnfreezeUser     = "unfreeze_user"
+	AuditActionAdjustBalance    = "adjust_balance"
+	AuditActionRefundPacket     = "refund_red_packet"
+	AuditActionCreateWebhook    = "create_webhook"
+	AuditActionDeleteWebhook    = "delete_webhook"
+	AuditActionRedeliverWebhook = "redeliver_webhook"
+	AuditActionCreateCampaign   = "create_campaign"
+	AuditActionFundCampaign     = "fund_campaign"
+	AuditActionIssueCampaign    = "issue_campaign_batch"
+	AuditActionCreateRain       = "create_rain"
+)
+
+// AdminAuditLog 管理员操作审计日志，只允许插入
+type AdminAuditLog struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdminID    uint64    `gorm:"not null;index:idx_admin_id" json:"admin_id"`
+	Action     string    `gorm:"type:varchar(50);not null" json:"action"`
+	TargetType string    `gorm:"type:varchar(20);not null;index:idx_target,priority:1" json:"target_type"`
+	TargetID   uint64    `gorm:"not null;index:idx_target,priority:2" json:"target_id"`
+	Detail     string    `gorm:"type:text" json:"detail"`
+	IP         string    `gorm:"type:varchar(64)" json:"ip"`
+	CreatedAt  time.Time `gorm:"not null;index:idx_created_at" json:"created_at"`
+}
+
+var errAuditLogImmutable = errors.New("admin audit log is immutable")
+
+func (*AdminAuditLog) BeforeUpdate(*gorm.DB) error { return errAuditLogImmutable }
+
+func (*AdminAuditLog) BeforeDelete(*gorm.DB) error { return errAuditLogImmutable }
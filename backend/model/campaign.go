@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// Campaign 公司出资的营销活动。预算账户（Budget/Balance）独立于用户钱包，
+// 活动红包按批次从 Balance 中一次性扣出，过期未领完的部分退回 Balance
+type Campaign struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name         string    `gorm:"type:varchar(100);not null" json:"name"`
+	Currency     string    `gorm:"type:char(3);not null" json:"currency"`
+	Budget       uint64    `gorm:"not null;default:0" json:"budget"`         // 累计注入的预算
+	Balance      uint64    `gorm:"not null;default:0" json:"balance"`        // 预算账户余额，尚未发放到红包的部分
+	PerUserLimit uint32    `gorm:"not null;default:0" json:"per_user_limit"` // 每人在本活动内最多领取的红包个数，0 表示不限
+	StartAt      time.Time `gorm:"not null" json:"start_at"`
+	EndAt        time.Time `gorm:"not null" json:"end_at"`
+	CreatedBy    uint64    `gorm:"not null" json:"created_by"`
+	CreatedAt    time.Time `gorm:"not null;index:idx_created_at" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"not null" json:"updated_at"`
+}
+
+// Active 当前时间是否在活动期内
+func (c *Campaign) Active(now time.Time) bool {
+	return !now.Before(c.StartAt) && now.Before(c.EndAt)
+}
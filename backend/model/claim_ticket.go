@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 排队领取请求状态
+const (
+	ClaimTicketPending   = 1 // 排队中
+	ClaimTicketSucceeded = 2 // 领取成功
+	ClaimTicketFailed    = 3 // 领取失败，原因见 Reason
+)
+
+// ClaimTicket 排队领取请求：热门红包的领取先入队立即返回，由每个红包一个的 worker 按 ID 顺序处理。
+// 口令在入队时校验，只记录是否通过；分享令牌仅为处理时校验而暂存，处理后清空
+type ClaimTicket struct {
+	ID             uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	RedPacketID    uint64     `gorm:"not null;index:idx_packet_status,priority:1;index:idx_packet_receiver,priority:1" json:"red_packet_id"`
+	ReceiverID     uint64     `gorm:"not null;index:idx_packet_receiver,priority:2" json:"receiver_id"`
+	SecretVerified bool       `gorm:"not null;default:false" json:"-"`
+	ShareToken     string     `gorm:"type:varchar(100)" json:"-"`
+	Status         int8       `gorm:"not null;default:1;index:idx_packet_status,priority:2" json:"status"`
+	Amount         uint64     `gorm:"not null;default:0" json:"amount"`
+	Reason         string     `gorm:"type:varchar(100)" json:"reason"`
+	ProcessedAt    *time.Time `json:"processed_at"`
+	CreatedAt      time.Time  `gorm:"not null" json:"created_at"`
+}
+
+// ClaimQueue 红包排队领取的计数和处理租约，每个红包一行。
+// Pending 以条件更新原子增减，持有未过期租约的实例才能处理该红包的队列
+type ClaimQueue struct {
+	RedPacketID uint64     `gorm:"primaryKey;autoIncrement:false" json:"red_packet_id"`
+	Pending     int        `gorm:"not null;default:0" json:"pending"`
+	LeaseOwner  string     `gorm:"type:varchar(100);not null;default:''" json:"lease_owner"`
+	LeaseUntil  *time.Time `json:"lease_until"`
+	UpdatedAt   time.Time  `gorm:"not null" json:"updated_at"`
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// 收款（AA）状态
+const (
+	CollectionStatusOpen      = 1 // 收款中
+	CollectionStatusSettled   = 2 // 全部付清，已结算给发起人
+	CollectionStatusCancelled = 3 // 发起人取消，已付款项原路退回
+	CollectionStatusExpired   = 4 // 到期未收齐，已收款项结算给发起人
+)
+
+// 参与人份额状态
+const (
+	CollectionShareUnpaid   = 1 // 待付款
+	CollectionSharePaid     = 2 // 已付款
+	CollectionShareRefunded = 3 // 收款取消，已退回
+	CollectionShareClosed   = 4 // 收款结束时仍未付款
+)
+
+// Collection 发起人向多人收款（如 AA 聚餐），与红包方向相反。
+// 参与人付款时即扣款，款项暂存平台，全部付清或到期时一次性结算给发起人
+type Collection struct {
+	ID               uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	InitiatorID      uint64     `gorm:"not null;index:idx_initiator_created,priority:1" json:"initiator_id"`
+	Title            string     `gorm:"type:varchar(100);not null" json:"title"`
+	Currency         string     `gorm:"type:char(3);not null" json:"currency"`
+	TotalAmount      uint64     `gorm:"not null" json:"total_amount"`
+	PaidAmount       uint64     `gorm:"not null;default:0" json:"paid_amount"`
+	ParticipantCount uint32     `gorm:"not null" json:"participant_count"`
+	PaidCount        uint32     `gorm:"not null;default:0" json:"paid_count"`
+	Status           int8       `gorm:"not null;index:idx_status_expired,priority:1" json:"status"`
+	RemindedAt       *time.Time `json:"reminded_at"`
+	ExpiredAt        time.Time  `gorm:"not null;index:idx_status_expired,priority:2" json:"expired_at"`
+	SettledAt        *time.Time `json:"settled_at"`
+	CreatedAt        time.Time  `gorm:"not null;index:idx_initiator_created,priority:2" json:"created_at"`
+	UpdatedAt        time.Time  `gorm:"not null" json:"updated_at"`
+}
+
+// CollectionShare 单个参与人应付的份额
+type CollectionShare struct {
+	ID           uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CollectionID uint64     `gorm:"not null;uniqueIndex:uk_collection_user" json:"collection_id"`
+	UserID       uint64     `gorm:"not null;uniqueIndex:uk_collection_user;index:idx_user_id" json:"user_id"`
+	Amount       uint64     `gorm:"not null" json:"amount"`
+	Status       int8       `gorm:"not null;default:1" json:"status"`
+	RemindCount  uint32     `gorm:"not null;default:0" json:"remind_count"`
+	PaidAt       *time.Time `json:"paid_at"`
+	CreatedAt    time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"not null" json:"updated_at"`
+}
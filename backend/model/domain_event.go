@@ -0,0 +1,113 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 领域事件类型
+const (
+	DomainEventTransactionCreated     = "transaction.created"       // 余额变动（每条流水一个事件）
+	DomainEventRedPacketCreated       = "red_packet.created"        // 红包创建
+	DomainEventRedPacketUpdated       = "red_packet.updated"        // 红包剩余、状态变化
+	DomainEventRecordCreated          = "red_packet_record.created" // 领取记录
+	DomainEventEntryCreated           = "red_packet_entry.created"  // 抽奖报名
+	DomainEventEntryUpdated           = "red_packet_entry.updated"  // 抽奖结果，通知报名者
+	DomainEventCollectionShareCreated = "collection_share.created"  // 发起收款，通知参与人
+	DomainEventCollectionShareUpdated = "collection_share.updated"  // 付款、催收提醒、取消或结束
+)
+
+// DomainEvent 领域事件，由模型钩子在修改数据的同一事务内写入。
+// Seq 在事务提交后由排序任务按可见顺序分配，消费方按 Seq 递增拉取不会漏掉晚提交的事件
+type DomainEvent struct {
+	ID            uint64    `gorm:"primaryKey;autoIncrement"`
+	Seq           *uint64   `gorm:"uniqueIndex:uk_seq"`
+	EventType     string    `gorm:"type:varchar(50);not null"`
+	AggregateType string    `gorm:"type:varchar(30);not null"`
+	AggregateID   uint64    `gorm:"not null"`
+	UserID        uint64    `gorm:"not null;index:idx_user_id"` // 事件相关用户：流水所属用户、红包发送者、领取者
+	Payload       string    `gorm:"type:text;not null"`         // 变更后数据快照 JSON
+	CreatedAt     time.Time `gorm:"not null"`
+}
+
+// DomainEventOffset 命名的序号位置：sequence 为已分配的最大序号，relay 为转发到消息中间件的进度
+type DomainEventOffset struct {
+	Name      string    `gorm:"primaryKey;type:varchar(50)"`
+	Value     uint64    `gorm:"not null;default:0"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// transactionSnapshot 流水模型没有 json 标签，事件中单独定义字段名
+type transactionSnapshot struct {
+	ID           uint64    `json:"id"`
+	UserID       uint64    `json:"user_id"`
+	Type         string    `json:"type"`
+	Direction    int8      `json:"direction"`
+	Currency     string    `json:"currency"`
+	Amount       uint64    `json:"amount"`
+	BalanceAfter uint64    `json:"balance_after"`
+	RelatedID    *uint64   `json:"related_id"`
+	Remark       string    `json:"remark"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (t *Transaction) AfterCreate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventTransactionCreated, "transaction", t.ID, t.UserID, transactionSnapshot{
+		ID:           t.ID,
+		UserID:       t.UserID,
+		Type:         t.Type,
+		Direction:    t.Direction,
+		Currency:     t.Currency,
+		Amount:       t.Amount,
+		BalanceAfter: t.BalanceAfter,
+		RelatedID:    t.RelatedID,
+		Remark:       t.Remark,
+		CreatedAt:    t.CreatedAt,
+	})
+}
+
+func (rp *RedPacket) AfterCreate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventRedPacketCreated, "red_packet", rp.ID, rp.SenderID, rp)
+}
+
+// AfterUpdate 只在通过 Save / Updates(&rp) 修改时触发，仓储层更新红包走 Save 或 Updates，条件更新时手动调用
+func (rp *RedPacket) AfterUpdate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventRedPacketUpdated, "red_packet", rp.ID, rp.SenderID, rp)
+}
+
+func (r *RedPacketRecord) AfterCreate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventRecordCreated, "red_packet", r.RedPacketID, r.ReceiverID, r)
+}
+
+func (e *RedPacketEntry) AfterCreate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventEntryCreated, "red_packet", e.RedPacketID, e.UserID, e)
+}
+
+func (e *RedPacketEntry) AfterUpdate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventEntryUpdated, "red_packet", e.RedPacketID, e.UserID, e)
+}
+
+func (s *CollectionShare) AfterCreate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventCollectionShareCreated, "collection", s.CollectionID, s.UserID, s)
+}
+
+func (s *CollectionShare) AfterUpdate(tx *gorm.DB) error {
+	return writeDomainEvent(tx, DomainEventCollectionShareUpdated, "collection", s.CollectionID, s.UserID, s)
+}
+
+func writeDomainEvent(tx *gorm.DB, eventType, aggregateType string, aggregateID, userID uint64, snapshot interface{}) error {
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	// 新会话复用当前事务连接，但不继承触发钩子的语句
+	return tx.Session(&gorm.Session{NewDB: true}).Create(&DomainEvent{
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		UserID:        userID,
+		Payload:       string(b),
+	}).Error
+}
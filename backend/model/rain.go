@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// 红包雨状态
+const (
+	RainStatusActive   = 1 // 未结束（开始前可预领领取券）
+	RainStatusFinished = 2 // 已结束，剩余金额已退回活动预算
+)
+
+// 红包雨领取请求状态
+const (
+	RainClaimQueued    = 1 // 排队中
+	RainClaimSucceeded = 2 // 抢到，金额已入账
+	RainClaimFailed    = 3 // 未抢到，原因见 Reason
+)
+
+// Rain 红包雨：活动预算出资的一池小额红包，在短时间窗口内开放。
+// 领取不逐个锁红包行，而是先入队，再由后台 worker 每次锁一次红包雨行批量处理
+type Rain struct {
+	ID              uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	CampaignID      uint64    `gorm:"not null;index:idx_campaign_id" json:"campaign_id"`
+	Currency        string    `gorm:"type:char(3);not null" json:"currency"`
+	TotalAmount     uint64    `gorm:"not null" json:"total_amount"`
+	DropCount       uint32    `gorm:"not null" json:"drop_count"` // 总份数
+	RemainingAmount uint64    `gorm:"not null" json:"remaining_amount"`
+	RemainingDrops  uint32    `gorm:"not null" json:"remaining_drops"`
+	PerUserCap      uint32    `gorm:"not null;default:1" json:"per_user_cap"` // 每人最多抢到的份数
+	Seed            string    `gorm:"type:char(64)" json:"-"`                 // 拆分种子，结束后公开
+	SeedHash        string    `gorm:"type:char(64)" json:"seed_hash"`
+	Status          int8      `gorm:"not null;default:1;index:idx_status_end,priority:1" json:"status"`
+	StartAt         time.Time `gorm:"not null" json:"start_at"`
+	EndAt           time.Time `gorm:"not null;index:idx_status_end,priority:2" json:"end_at"`
+	CreatedAt       time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"not null" json:"updated_at"`
+}
+
+// RainClaim 一次领取请求，凭领取券入队，nonce 唯一保证每张券只能用一次
+type RainClaim struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	RainID      uint64     `gorm:"not null;uniqueIndex:uk_rain_nonce,priority:1;index:idx_rain_status,priority:1;index:idx_rain_user,priority:1" json:"rain_id"`
+	Nonce       string     `gorm:"type:varchar(24);not null;uniqueIndex:uk_rain_nonce,priority:2" json:"-"`
+	UserID      uint64     `gorm:"not null;index:idx_rain_user,priority:2" json:"user_id"`
+	Status      int8       `gorm:"not null;default:1;index:idx_rain_status,priority:2" json:"status"`
+	Amount      uint64     `gorm:"not null;default:0" json:"amount"`
+	Seq         uint32     `gorm:"not null;default:0" json:"seq"` // 抢到的份序号，对应拆分种子中的下标
+	Reason      string     `gorm:"type:varchar(100)" json:"reason"`
+	ProcessedAt *time.Time `json:"processed_at"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+}
@@ -1,29 +1,86 @@
 package model
 
-import "time"
+import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // 红包类型
 const (
-	RedPacketTypeNormal = 1 // 普通红包（等额）
-	RedPacketTypeLucky  = 2 // 拼手气红包（随机）
+	RedPacketTypeNormal   = 1 // 普通红包（等额）
+	RedPacketTypeLucky    = 2 // 拼手气红包（随机）
+	RedPacketTypeLottery  = 3 // 抽奖红包（报名后开奖）
+	RedPacketTypeAssigned = 4 // 指定金额红包（发送者为每个领取人指定金额）
 )
 
 // 红包状态
 const (
-	RedPacketStatusActive  = 1 // 可领取
-	RedPacketStatusEmpty   = 2 // 已抢完
-	RedPacketStatusExpired = 3 // 已过期
+	RedPacketStatusActive   = 1 // 可领取
+	RedPacketStatusEmpty    = 2 // 已抢完
+	RedPacketStatusExpired  = 3 // 已过期
+	RedPacketStatusPending  = 4 // 未开启（定时红包）
+	RedPacketStatusRefunded = 5 // 已退款（管理员强制退款）
+	RedPacketStatusDrawn    = 6 // 已开奖（抽奖红包）
 )
 
 type RedPacket struct {
-	ID              uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
-	SenderID        uint64    `gorm:"not null;index:idx_sender_id" json:"sender_id"`
-	Type            int8      `gorm:"not null" json:"type"`
-	TotalAmount     uint64    `gorm:"not null" json:"total_amount"`
-	TotalCount      uint32    `gorm:"not null" json:"total_count"`
-	RemainingAmount uint64    `gorm:"not null" json:"remaining_amount"`
-	RemainingCount  uint32    `gorm:"not null" json:"remaining_count"`
-	Status          int8      `gorm:"not null;default:1;index:idx_status_expired" json:"status"`
-	ExpiredAt       time.Time `gorm:"not null;index:idx_status_expired" json:"expired_at"`
-	CreatedAt       time.Time `gorm:"not null" json:"created_at"`
+	ID              uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	PublicID        string     `gorm:"type:varchar(24);uniqueIndex:uk_public_id" json:"public_id"` // 对外路由使用的随机ID，可为空以便旧数据迁移后补齐
+	SenderID        uint64     `gorm:"not null;index:idx_sender_id" json:"sender_id"`              // 活动红包为 0
+	CampaignID      *uint64    `gorm:"index:idx_campaign_id" json:"campaign_id,omitempty"`         // 活动红包所属活动，由活动预算出资
+	Type            int8       `gorm:"not null" json:"type"`
+	Currency        string     `gorm:"type:char(3);not null;default:CNY" json:"currency"`
+	TotalAmount     uint64     `gorm:"not null" json:"total_amount"`
+	TotalCount      uint32     `gorm:"not null" json:"total_count"`
+	RemainingAmount uint64     `gorm:"not null" json:"remaining_amount"`
+	RemainingCount  uint32     `gorm:"not null" json:"remaining_count"`
+	Status          int8       `gorm:"not null;default:1;index:idx_status_expired;index:idx_status_open;index:idx_status_draw" json:"status"`
+	HasSecret       bool       `gorm:"not null;default:false" json:"has_secret"`
+	SecretSalt      string     `gorm:"type:varchar(32)" json:"-"` // 仅旧版 SHA-256 存储的口令有值
+	SecretHash      string     `gorm:"type:varchar(64)" json:"-"`
+	RequireShare    bool       `gorm:"not null;default:false" json:"require_share"` // 领取时必须携带有效的分享令牌
+	Seed            string     `gorm:"type:char(64)" json:"-"`                      // 拼手气红包的拆分种子，结束后才公开
+	SeedHash        string     `gorm:"type:char(64)" json:"seed_hash"`              // 种子承诺，发出时即公开
+	EntriesHash     string     `gorm:"type:char(64)" json:"-"`                      // 抽奖红包开奖时的报名名单摘要，与种子一起决定中奖者
+	OpenAt          time.Time  `gorm:"not null;index:idx_status_open" json:"open_at"`
+	DrawAt          *time.Time `gorm:"index:idx_status_draw" json:"draw_at"` // 抽奖红包报名截止并开奖的时间
+	ExpiredAt       time.Time  `gorm:"not null;index:idx_status_expired" json:"expired_at"`
+	Version         uint64     `gorm:"not null;default:0" json:"-"` // 每次更新 +1，乐观并发领取时比对
+	CreatedAt       time.Time  `gorm:"not null" json:"created_at"`
+}
+
+// SeedRevealed 红包结束（抢完、过期、退款、开奖）后可以公开种子
+func (rp *RedPacket) SeedRevealed() bool {
+	switch rp.Status {
+	case RedPacketStatusEmpty, RedPacketStatusExpired, RedPacketStatusRefunded, RedPacketStatusDrawn:
+		return true
+	}
+	return false
+}
+
+// publicIDBytes 随机字节数，base64url 编码后为 16 个字符
+const publicIDBytes = 12
+
+// NewPublicID 生成不可枚举的红包公开ID
+func NewPublicID() (string, error) {
+	b := make([]byte, publicIDBytes)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (rp *RedPacket) BeforeCreate(tx *gorm.DB) error {
+	if rp.PublicID != "" {
+		return nil
+	}
+	id, err := NewPublicID()
+	if err != nil {
+		return err
+	}
+	rp.PublicID = id
+	return nil
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// 指定金额红包的份额状态
+const (
+	AllocationStatusPending  = 1 // 待领取
+	AllocationStatusClaimed  = 2 // 已领取
+	AllocationStatusRefunded = 3 // 过期或被强制退款时仍未领取，已退回发送者
+)
+
+// RedPacketAllocation 指定金额红包中发送者为某个领取人预先分配的份额，发红包时一次性写入
+type RedPacketAllocation struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	RedPacketID uint64     `gorm:"not null;uniqueIndex:uk_packet_receiver" json:"red_packet_id"`
+	ReceiverID  uint64     `gorm:"not null;uniqueIndex:uk_packet_receiver;index:idx_receiver_id" json:"receiver_id"`
+	Amount      uint64     `gorm:"not null" json:"amount"`
+	Status      int8       `gorm:"not null;default:1" json:"status"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+	CreatedAt   time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"not null" json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// 抽奖报名状态
+const (
+	EntryStatusPending   = 1 // 等待开奖
+	EntryStatusWon       = 2 // 中奖，金额已入账
+	EntryStatusLost      = 3 // 未中奖
+	EntryStatusForfeited = 4 // 中奖但超出当日领取限额，份额退回发送者
+)
+
+// RedPacketEntry 抽奖红包的报名记录，开奖时更新结果
+type RedPacketEntry struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	RedPacketID uint64    `gorm:"not null;uniqueIndex:uk_packet_user" json:"red_packet_id"`
+	UserID      uint64    `gorm:"not null;uniqueIndex:uk_packet_user;index:idx_user_id" json:"user_id"`
+	Status      int8      `gorm:"not null;default:1" json:"status"`
+	Amount      uint64    `gorm:"not null;default:0" json:"amount"`
+	CreatedAt   time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"not null" json:"updated_at"`
+}
@@ -3,9 +3,10 @@ package model
 import "time"
 
 type RedPacketRecord struct {
-	ID          uint64    `gorm:"primaryKey;autoIncrement"`
-	RedPacketID uint64    `gorm:"not null;uniqueIndex:uk_packet_receiver;index:idx_red_packet_id"`
-	ReceiverID  uint64    `gorm:"not null;uniqueIndex:uk_packet_receiver;index:idx_receiver_id"`
-	Amount      uint64    `gorm:"not null"`
-	CreatedAt   time.Time `gorm:"not null"`
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	RedPacketID uint64    `gorm:"not null;uniqueIndex:uk_packet_receiver;index:idx_red_packet_id" json:"red_packet_id"`
+	ReceiverID  uint64    `gorm:"not null;uniqueIndex:uk_packet_receiver;index:idx_receiver_created,priority:1" json:"receiver_id"`
+	Amount      uint64    `gorm:"not null" json:"amount"`
+	Seq         uint32    `gorm:"not null;default:0" json:"seq"` // 领取顺序，从 0 开始，拼手气金额按此重算
+	CreatedAt   time.Time `gorm:"not null;index:idx_receiver_created,priority:2" json:"created_at"`
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RedPacketSecretAttempt 口令红包的口令错误次数，按 (红包, 用户) 计数
+type RedPacketSecretAttempt struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement"`
+	RedPacketID uint64    `gorm:"not null;uniqueIndex:uk_packet_user"`
+	UserID      uint64    `gorm:"not null;uniqueIndex:uk_packet_user"`
+	FailedCount uint32    `gorm:"not null;default:0"`
+	CreatedAt   time.Time `gorm:"not null"`
+	UpdatedAt   time.Time `gorm:"not null"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RiskDecision 风控决策日志，每次收发红包请求一条，同时作为速率计数的数据来源
+type RiskDecision struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint64    `gorm:"not null;index:idx_user_scene_created,priority:1" json:"user_id"`
+	Scene       string    `gorm:"type:varchar(20);not null;index:idx_user_scene_created,priority:2" json:"scene"`
+	IP          string    `gorm:"type:varchar(64);index:idx_ip_created,priority:1" json:"ip"`
+	DeviceID    string    `gorm:"type:varchar(128);index:idx_device_created,priority:1" json:"device_id"`
+	RedPacketID uint64    `json:"red_packet_id"`
+	Amount      uint64    `json:"amount"`
+	Action      string    `gorm:"type:varchar(20);not null" json:"action"`
+	Rule        string    `gorm:"type:varchar(64)" json:"rule"`
+	Reason      string    `gorm:"type:varchar(255)" json:"reason"`
+	CreatedAt   time.Time `gorm:"not null;index:idx_user_scene_created,priority:3;index:idx_ip_created,priority:2;index:idx_device_created,priority:2" json:"created_at"`
+}
@@ -4,10 +4,14 @@ import "time"
 
 // 流水类型
 const (
-	TransactionTypeRecharge = "recharge" // 充值
-	TransactionTypeSend     = "send"     // 发红包（扣款）
-	TransactionTypeReceive  = "receive"  // 领红包（到账）
-	TransactionTypeRefund   = "refund"   // 红包过期退款
+	TransactionTypeRecharge   = "recharge"   // 充值
+	TransactionTypeSend       = "send"       // 发红包（扣款）
+	TransactionTypeReceive    = "receive"    // 领红包（到账）
+	TransactionTypeRefund     = "refund"     // 红包过期退款
+	TransactionTypeAdjust     = "adjust"     // 管理员人工调账
+	TransactionTypeTransfer   = "transfer"   // 用户间转账（转出、到账、退回）
+	TransactionTypeCollection = "collection" // AA 收款（参与人付款、结算给发起人、取消退回）
+	TransactionTypeRain       = "rain"       // 抢到红包雨（到账）
 )
 
 // 资金方向
@@ -21,6 +25,7 @@ type Transaction struct {
 	UserID       uint64    `gorm:"not null;index:idx_user_created,priority:1"`
 	Type         string    `gorm:"type:varchar(20);not null"`
 	Direction    int8      `gorm:"not null"`
+	Currency     string    `gorm:"type:char(3);not null;default:CNY"`
 	Amount       uint64    `gorm:"not null"`
 	BalanceAfter uint64    `gorm:"not null"`
 	RelatedID    *uint64   `gorm:"index:idx_related_id"`
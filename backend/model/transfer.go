@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// 转账状态
+const (
+	TransferStatusPending  = 1 // 待收款
+	TransferStatusAccepted = 2 // 已到账
+	TransferStatusRejected = 3 // 已拒收退回
+	TransferStatusReturned = 4 // 超时未收款退回
+)
+
+// Transfer 用户间直接转账；需要确认收款的转账在发起时即扣款，收款或退回时再入账
+type Transfer struct {
+	ID            uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	FromUserID    uint64     `gorm:"not null;index:idx_from_created,priority:1" json:"from_user_id"`
+	ToUserID      uint64     `gorm:"not null;index:idx_to_created,priority:1" json:"to_user_id"`
+	Currency      string     `gorm:"type:char(3);not null" json:"currency"`
+	Amount        uint64     `gorm:"not null" json:"amount"`
+	Note          string     `gorm:"type:varchar(100)" json:"note"`
+	RequireAccept bool       `gorm:"not null;default:false" json:"require_accept"`
+	Status        int8       `gorm:"not null;index:idx_status_expired,priority:1" json:"status"`
+	ExpiredAt     *time.Time `gorm:"index:idx_status_expired,priority:2" json:"expired_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `gorm:"not null;index:idx_from_created,priority:2;index:idx_to_created,priority:2" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
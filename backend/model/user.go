@@ -2,11 +2,25 @@ package model
 
 import "time"
 
+// 用户角色
+const (
+	UserRoleUser    = "user"    // 普通用户
+	UserRoleSupport = "support" // 客服（只读）
+	UserRoleAdmin   = "admin"   // 管理员
+)
+
+// 用户状态
+const (
+	UserStatusNormal = 1 // 正常
+	UserStatusFrozen = 2 // 已冻结，禁止收发红包
+)
+
 type User struct {
 	ID           uint64    `gorm:"primaryKey;autoIncrement"`
 	Username     string    `gorm:"type:varchar(50);not null;uniqueIndex"`
 	PasswordHash string    `gorm:"type:varchar(255);not null"`
-	Balance      uint64    `gorm:"not null;default:0"`
+	Role         string    `gorm:"type:varchar(20);not null;default:user"`
+	Status       int8      `gorm:"not null;default:1"`
 	CreatedAt    time.Time `gorm:"not null"`
 	UpdatedAt    time.Time `gorm:"not null"`
 }
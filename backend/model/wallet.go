@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Wallet 用户在某一币种下的余额，每个用户每个币种一行
+type Wallet struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	UserID    uint64    `gorm:"not null;uniqueIndex:uk_user_currency"`
+	Currency  string    `gorm:"type:char(3);not null;uniqueIndex:uk_user_currency"`
+	Balance   uint64    `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// webhook 订阅状态
+const (
+	WebhookStatusEnabled  = 1 // 启用
+	WebhookStatusDisabled = 2 // 已删除（停用），保留记录供投递日志关联
+)
+
+// 投递状态
+const (
+	WebhookDeliveryPending   = 1 // 待投递 / 等待重试
+	WebhookDeliverySucceeded = 2 // 已成功
+	WebhookDeliveryDead      = 3 // 重试耗尽，进入死信
+)
+
+// WebhookSubscription 外部系统订阅的回调地址，Events 为逗号分隔的事件类型
+type WebhookSubscription struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	URL       string    `gorm:"type:varchar(500);not null"`
+	Secret    string    `gorm:"type:varchar(64);not null"`
+	Events    string    `gorm:"type:varchar(255);not null"`
+	Status    int8      `gorm:"not null;default:1"`
+	CreatedBy uint64    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// OutboxEvent 事务发件箱：与业务数据在同一事务中写入，由分发任务异步展开为各订阅的投递
+type OutboxEvent struct {
+	ID           uint64     `gorm:"primaryKey;autoIncrement"`
+	EventType    string     `gorm:"type:varchar(50);not null"`
+	RedPacketID  uint64     `gorm:"not null;index:idx_red_packet_id"`
+	Payload      string     `gorm:"type:text;not null"` // 事件数据 JSON
+	DispatchedAt *time.Time `gorm:"index:idx_dispatched,priority:1"`
+	CreatedAt    time.Time  `gorm:"not null"`
+}
+
+// WebhookDelivery 单个订阅对单个事件的投递，Body 为最终签名发送的完整请求体，重试时原样重发
+type WebhookDelivery struct {
+	ID             uint64    `gorm:"primaryKey;autoIncrement"`
+	SubscriptionID uint64    `gorm:"not null;uniqueIndex:uk_subscription_event,priority:1"`
+	OutboxEventID  uint64    `gorm:"not null;uniqueIndex:uk_subscription_event,priority:2"`
+	EventType      string    `gorm:"type:varchar(50);not null"`
+	Body           string    `gorm:"type:text;not null"`
+	Status         int8      `gorm:"not null;default:1;index:idx_status_next,priority:1"`
+	Attempts       uint32    `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"not null;index:idx_status_next,priority:2"`
+	LastStatusCode int       `gorm:"not null;default:0"`
+	LastError      string    `gorm:"type:varchar(500)"`
+	CreatedAt      time.Time `gorm:"not null"`
+	UpdatedAt      time.Time `gorm:"not null"`
+}
@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文档自身的路由，不需要出现在文档中
+const (
+	SpecPath     = "/openapi.json"
+	UIPath       = "/docs"
+	UIAssetsPath = "/docs/assets"
+)
+
+// uiCSP 文档页只加载同源资源，不依赖外部 CDN，离线和严格 CSP 下都能使用
+const uiCSP = "default-src 'self'; style-src 'self'; script-src 'self'; connect-src 'self'; img-src 'self' data:"
+
+//go:embed ui
+var uiFiles embed.FS
+
+var (
+	specOnce sync.Once
+	spec     *Document
+)
+
+func Spec() *Document {
+	specOnce.Do(func() {
+		spec = Build(Operations)
+	})
+	return spec
+}
+
+func SpecHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, Spec())
+}
+
+func UIHandler(c *gin.Context) {
+	html, err := uiFiles.ReadFile("ui/index.html")
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Content-Security-Policy", uiCSP)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
+
+// UIAssetHandler 文档页的脚本和样式，路由为 UIAssetsPath + "/*filepath"
+func UIAssetHandler(c *gin.Context) {
+	name := strings.TrimPrefix(path.Clean(c.Param("filepath")), "/")
+	data, err := fs.ReadFile(uiFiles, "ui/"+name)
+	if err != nil || name == "index.html" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	contentType := "application/octet-stream"
+	switch path.Ext(name) {
+	case ".js":
+		contentType = "text/javascript; charset=utf-8"
+	case ".css":
+		contentType = "text/css; charset=utf-8"
+	}
+	c.Header("Content-Security-Policy", uiCSP)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// MissingRoutes 返回已注册但未在 Operations 中登记的路由
+func MissingRoutes(routes gin.RoutesInfo) []string {
+	documented := make(map[string]bool, len(Operations))
+	for _, op := range Operations {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	var missing []string
+	for _, r := range routes {
+		if r.Path == SpecPath || r.Path == UIPath || strings.HasPrefix(r.Path, UIAssetsPath+"/") {
+			continue
+		}
+		if key := r.Method + " " + r.Path; !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
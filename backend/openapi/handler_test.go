@@ -0,0 +1,50 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUIServesEmbeddedAssets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET(UIPath, UIHandler)
+	r.GET(UIAssetsPath+"/*filepath", UIAssetHandler)
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{UIPath, http.StatusOK, "text/html"},
+		{UIAssetsPath + "/app.js", http.StatusOK, "text/javascript"},
+		{UIAssetsPath + "/app.css", http.StatusOK, "text/css"},
+		{UIAssetsPath + "/missing.js", http.StatusNotFound, ""},
+		{UIAssetsPath + "/../handler.go", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, w.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("GET %s: content type %q, want %s", tt.path, ct, tt.contentType)
+		}
+		if w.Header().Get("Content-Security-Policy") == "" {
+			t.Errorf("GET %s: missing Content-Security-Policy", tt.path)
+		}
+		// 页面不能引用外部资源
+		if body := w.Body.String(); strings.Contains(body, "http://") || strings.Contains(body, "https://") {
+			t.Errorf("GET %s: references external resources", tt.path)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package openapi
+
+import (
+	"red-packet/dto"
+	"red-packet/handler"
+	"red-packet/pkg/response"
+)
+
+var pageParams = []Param{
+	{Name: "page", In: "query", Type: "integer", Description: "页码，从 1 开始，默认 1；传 cursor 时忽略"},
+	{Name: "page_size", In: "query", Type: "integer", Description: "每页数量，用户接口默认 10、最大 50，后台接口默认 20、最大 100"},
+	{Name: "cursor", In: "query", Description: "上一页返回的 next_cursor，按游标翻页"},
+}
+
+// Operations 全部对外接口，新增路由时需在这里登记，否则 router 包的测试会失败
+var Operations = []Operation{
+	{Method: "GET", Path: "/ping", Tag: "system", Summary: "健康检查", Response: dto.Pong{}},
+
+	{Method: "POST", Path: "/api/auth/register", Tag: "auth", Summary: "注册", Request: handler.RegisterRequest{}, Response: dto.RegisterResponse{}},
+	{Method: "POST", Path: "/api/auth/login", Tag: "auth", Summary: "登录", Request: handler.LoginRequest{}, Response: dto.LoginResponse{}},
+
+	{Method: "GET", Path: "/api/user/profile", Tag: "user", Summary: "获取个人信息", Auth: true, Response: dto.Profile{}},
+	{Method: "GET", Path: "/api/user/balances", Tag: "user", Summary: "各币种余额", Auth: true, Response: dto.BalanceList{}},
+	{Method: "GET", Path: "/api/user/quota", Tag: "user", Summary: "当日剩余额度", Auth: true,
+		Params:   []Param{{Name: "currency", In: "query", Description: "币种，默认为默认币种"}},
+		Response: dto.Quota{}},
+	{Method: "GET", Path: "/api/user/report", Tag: "user", Summary: "个人收发红包报告（年度报告）", Auth: true,
+		Params: []Param{
+			{Name: "year", In: "query", Type: "integer", Description: "统计年份，默认今年"},
+			{Name: "start", In: "query", Description: "自定义区间起始日期 YYYY-MM-DD，与 end 同时传入时忽略 year"},
+			{Name: "end", In: "query", Description: "自定义区间结束日期 YYYY-MM-DD（含），区间最长一年"},
+			{Name: "currency", In: "query", Description: "币种，默认为默认币种"},
+		},
+		Response: dto.Report{}},
+	{Method: "GET", Path: "/api/user/statement", Tag: "user", Summary: "导出账单（CSV / XLSX，流式下载）", Auth: true,
+		Params: []Param{
+			{Name: "start", In: "query", Required: true, Description: "起始日期 YYYY-MM-DD"},
+			{Name: "end", In: "query", Required: true, Description: "结束日期 YYYY-MM-DD（含）"},
+			{Name: "format", In: "query", Description: "csv（默认）或 xlsx"},
+			{Name: "currency", In: "query", Description: "币种，不传导出全部币种"},
+			{Name: "lang", In: "query", Description: "表头语言 zh（默认）或 en"},
+		},
+		Files: []string{"text/csv", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}},
+	{Method: "GET", Path: "/api/user/red-packets/sent", Tag: "user", Summary: "我发出的红包", Auth: true, Params: pageParams,
+		Response: response.Page[dto.RedPacket]{}},
+	{Method: "GET", Path: "/api/user/red-packets/received", Tag: "user", Summary: "我收到的红包", Auth: true, Params: pageParams,
+		Response: response.Page[dto.ReceivedRedPacket]{}},
+
+	{Method: "POST", Path: "/api/red-packets", Tag: "red-packet", Summary: "发红包", Auth: true,
+		Params:  []Param{{Name: "X-Device-ID", In: "header", Description: "设备标识，用于风控"}},
+		Request: handler.SendRedPacketRequest{}, Response: dto.RedPacket{}},
+	{Method: "POST", Path: "/api/red-packets/:id/claim", Tag: "red-packet", Summary: "领红包（:id 为公开ID）", Auth: true,
+		Params: []Param{
+			{Name: "X-Device-ID", In: "header", Description: "设备标识，用于风控"},
+			{Name: "t", In: "query", Description: "分享令牌，也可放在请求体 share_token 中"},
+		},
+		Request: handler.ClaimRedPacketRequest{}, Response: dto.ClaimResponse{}},
+	{Method: "POST", Path: "/api/red-packets/:id/claim-tickets", Tag: "red-packet", Summary: "排队领红包（立即返回，异步处理）", Auth: true,
+		Params: []Param{
+			{Name: "X-Device-ID", In: "header", Description: "设备标识，用于风控"},
+			{Name: "t", In: "query", Description: "分享令牌，也可放在请求体 share_token 中"},
+		},
+		Request: handler.ClaimRedPacketRequest{}, Response: dto.ClaimTicket{}},
+	{Method: "GET", Path: "/api/red-packets/:id/claim-tickets/:ticket_id", Tag: "red-packet", Summary: "查询排队领取结果", Auth: true,
+		Params:   []Param{{Name: "wait", In: "query", Type: "integer", Description: "排队中时最多等待的秒数（长轮询），默认 0，最大 30"}},
+		Response: dto.ClaimTicket{}},
+	{Method: "POST", Path: "/api/red-packets/:id/enter", Tag: "red-packet", Summary: "报名抽奖红包", Auth: true,
+		Params: []Param{
+			{Name: "X-Device-ID", In: "header", Description: "设备标识，用于风控"},
+			{Name: "t", In: "query", Description: "分享令牌，也可放在请求体 share_token 中"},
+		},
+		Request: handler.ClaimRedPacketRequest{}, Response: dto.EnterResponse{}},
+	{Method: "POST", Path: "/api/red-packets/:id/share", Tag: "red-packet", Summary: "生成分享链接（仅发送者）", Auth: true, Response: dto.ShareLink{}},
+	{Method: "GET", Path: "/api/red-packets/:id/share/qr", Tag: "red-packet", Summary: "分享链接二维码", Auth: true,
+		Params: []Param{{Name: "t", In: "query", Required: true, Description: "分享令牌"}},
+		Files:  []string{"image/png"}},
+	{Method: "GET", Path: "/api/red-packets/:id", Tag: "red-packet", Summary: "红包详情", Auth: true, Response: dto.RedPacketDetail{}},
+	{Method: "GET", Path: "/api/red-packets/:id/records", Tag: "red-packet", Summary: "领取记录", Auth: true, Params: pageParams,
+		Response: response.Page[dto.RedPacketRecord]{}},
+	{Method: "GET", Path: "/api/red-packets/:id/fairness", Tag: "red-packet", Summary: "拼手气红包公平性校验（种子承诺与公开）", Auth: true,
+		Response: dto.Fairness{}},
+
+	{Method: "POST", Path: "/api/transfers", Tag: "transfer", Summary: "发起转账", Auth: true, Request: handler.CreateTransferRequest{}, Response: dto.Transfer{}},
+	{Method: "GET", Path: "/api/transfers", Tag: "transfer", Summary: "我的转账", Auth: true, Params: pageParams,
+		Response: response.Page[dto.Transfer]{}},
+	{Method: "POST", Path: "/api/transfers/:id/accept", Tag: "transfer", Summary: "确认收款", Auth: true, Response: dto.Transfer{}},
+	{Method: "POST", Path: "/api/transfers/:id/reject", Tag: "transfer", Summary: "拒收", Auth: true, Response: dto.Transfer{}},
+
+	{Method: "POST", Path: "/api/collections", Tag: "collection", Summary: "发起 AA 收款", Auth: true, Request: handler.CreateCollectionRequest{},
+		Response: dto.CollectionDetail{}},
+	{Method: "GET", Path: "/api/collections", Tag: "collection", Summary: "我发起或参与的收款", Auth: true, Params: pageParams,
+		Response: response.Page[dto.Collection]{}},
+	{Method: "GET", Path: "/api/collections/:id", Tag: "collection", Summary: "收款详情及付款进度", Auth: true, Response: dto.CollectionDetail{}},
+	{Method: "POST", Path: "/api/collections/:id/pay", Tag: "collection", Summary: "支付我的份额", Auth: true, Response: dto.CollectionDetail{}},
+	{Method: "POST", Path: "/api/collections/:id/remind", Tag: "collection", Summary: "提醒未付款的参与人", Auth: true, Response: dto.RemindResponse{}},
+	{Method: "POST", Path: "/api/collections/:id/cancel", Tag: "collection", Summary: "取消收款并退回已付款项", Auth: true, Response: dto.CollectionDetail{}},
+
+	{Method: "GET", Path: "/api/rains/:id", Tag: "rain", Summary: "红包雨详情（结束后公开种子）", Auth: true, Response: dto.Rain{}},
+	{Method: "POST", Path: "/api/rains/:id/tickets", Tag: "rain", Summary: "领取签名领取券（可在开始前预领）", Auth: true,
+		Params:   []Param{{Name: "X-Device-ID", In: "header", Description: "设备标识，用于风控"}},
+		Response: dto.RainTicket{}},
+	{Method: "POST", Path: "/api/rains/:id/claims", Tag: "rain", Summary: "凭券提交抢红包雨请求（入队，异步处理）", Auth: true,
+		Request: handler.SubmitRainClaimRequest{}, Response: dto.RainClaim{}},
+	{Method: "GET", Path: "/api/rains/:id/claims/:claim_id", Tag: "rain", Summary: "查询抢红包雨结果", Auth: true,
+		Params:   []Param{{Name: "wait", In: "query", Type: "integer", Description: "排队中时最多等待的秒数（长轮询），默认 0，最大 30"}},
+		Response: dto.RainClaim{}},
+
+	{Method: "GET", Path: "/api/admin/users", Tag: "admin", Summary: "搜索用户", Auth: true,
+		Params:   append([]Param{{Name: "keyword", In: "query", Description: "用户名前缀或用户ID"}}, pageParams...),
+		Response: response.Page[dto.AdminUser]{}},
+	{Method: "GET", Path: "/api/admin/users/:id", Tag: "admin", Summary: "用户详情", Auth: true, Response: dto.AdminUserDetail{}},
+	{Method: "POST", Path: "/api/admin/users/:id/freeze", Tag: "admin", Summary: "冻结账户", Auth: true, Request: handler.FreezeUserRequest{}},
+	{Method: "POST", Path: "/api/admin/users/:id/unfreeze", Tag: "admin", Summary: "解冻账户", Auth: true, Request: handler.FreezeUserRequest{}},
+	{Method: "POST", Path: "/api/admin/users/:id/balance-adjustments", Tag: "admin", Summary: "人工调账", Auth: true, Request: handler.AdjustBalanceRequest{},
+		Response: dto.AdjustBalanceResponse{}},
+	{Method: "GET", Path: "/api/admin/red-packets/:id", Tag: "admin", Summary: "红包详情及全部领取记录", Auth: true, Response: dto.AdminRedPacket{}},
+	{Method: "POST", Path: "/api/admin/red-packets/:id/refund", Tag: "admin", Summary: "强制退款", Auth: true, Request: handler.RefundRedPacketRequest{},
+		Response: dto.RefundResponse{}},
+	{Method: "GET", Path: "/api/admin/audit-logs", Tag: "admin", Summary: "审计日志", Auth: true,
+		Params: append([]Param{
+			{Name: "admin_id", In: "query", Type: "integer"},
+			{Name: "target_type", In: "query"},
+			{Name: "target_id", In: "query", Type: "integer"},
+		}, pageParams...),
+		Response: response.Page[dto.AuditLog]{}},
+	{Method: "POST", Path: "/api/admin/webhooks", Tag: "admin", Summary: "新建 webhook 订阅", Auth: true,
+		Request: handler.CreateWebhookRequest{}, Response: dto.WebhookCreated{}},
+	{Method: "GET", Path: "/api/admin/webhooks", Tag: "admin", Summary: "webhook 订阅列表", Auth: true,
+		Response: dto.WebhookSubscriptionList{}},
+	{Method: "DELETE", Path: "/api/admin/webhooks/:id", Tag: "admin", Summary: "删除 webhook 订阅", Auth: true},
+	{Method: "GET", Path: "/api/admin/webhook-deliveries", Tag: "admin", Summary: "webhook 投递记录", Auth: true,
+		Params: append([]Param{
+			{Name: "subscription_id", In: "query", Type: "integer"},
+			{Name: "status", In: "query", Type: "integer", Description: "1 待投递 2 成功 3 死信"},
+		}, pageParams...),
+		Response: response.Page[dto.WebhookDelivery]{}},
+	{Method: "POST", Path: "/api/admin/webhook-deliveries/:id/redeliver", Tag: "admin", Summary: "重新投递死信", Auth: true},
+	{Method: "GET", Path: "/api/admin/events", Tag: "admin", Summary: "按序号拉取领域事件", Auth: true,
+		Params: []Param{
+			{Name: "after", In: "query", Type: "integer", Description: "上次返回的 next_seq，首次传 0"},
+			{Name: "limit", In: "query", Type: "integer", Description: "每次最多返回条数，默认 100，最大 500"},
+			{Name: "types", In: "query", Description: "事件类型过滤，逗号分隔"},
+			{Name: "user_id", In: "query", Type: "integer", Description: "只看与该用户相关的事件"},
+		},
+		Response: dto.DomainEventList{}},
+	{Method: "POST", Path: "/api/admin/campaigns", Tag: "admin", Summary: "创建营销活动", Auth: true, Request: handler.CreateCampaignRequest{},
+		Response: dto.Campaign{}},
+	{Method: "GET", Path: "/api/admin/campaigns", Tag: "admin", Summary: "活动列表", Auth: true, Params: pageParams,
+		Response: response.Page[dto.Campaign]{}},
+	{Method: "GET", Path: "/api/admin/campaigns/:id", Tag: "admin", Summary: "活动详情及花费、参与统计", Auth: true, Response: dto.CampaignDetail{}},
+	{Method: "POST", Path: "/api/admin/campaigns/:id/fund", Tag: "admin", Summary: "活动预算注资", Auth: true, Request: handler.FundCampaignRequest{},
+		Response: dto.Campaign{}},
+	{Method: "POST", Path: "/api/admin/campaigns/:id/batches", Tag: "admin", Summary: "从活动预算发出一批红包", Auth: true,
+		Request: handler.IssueCampaignBatchRequest{}, Response: dto.CampaignBatch{}},
+	{Method: "POST", Path: "/api/admin/campaigns/:id/rains", Tag: "admin", Summary: "从活动预算创建红包雨", Auth: true,
+		Request: handler.CreateRainRequest{}, Response: dto.Rain{}},
+}
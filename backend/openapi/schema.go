@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema OpenAPI 3 Schema Object 的子集
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	typeArgPathRe = regexp.MustCompile(`[^\[\],*]*/`)
+)
+
+// schemaRegistry 收集具名结构体，生成 components/schemas 并用 $ref 引用
+type schemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]*Schema{}}
+}
+
+// schemaOf 由 Go 类型推导 Schema，具名结构体登记到 components 中
+func (r *schemaRegistry) schemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := r.schemaOf(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := r.schemas[name]; !ok {
+			// 先占位，防止自引用类型无限递归
+			r.schemas[name] = &Schema{}
+			*r.schemas[name] = *r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	r.collectFields(t, s)
+	return s
+}
+
+// collectFields 按 encoding/json 的规则收集字段，匿名嵌入的结构体字段平铺
+func (r *schemaRegistry) collectFields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				r.collectFields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := r.schemaOf(ft)
+		if strings.Contains(opts, "string") {
+			prop = &Schema{Type: "string"}
+		}
+		if applyBinding(prop, ft, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// applyBinding 把 gin binding 规则映射为 Schema 约束，返回是否必填
+func applyBinding(s *Schema, t reflect.Type, binding string) bool {
+	if binding == "" || s.Ref != "" {
+		return strings.Contains(binding, "required")
+	}
+	required := false
+	isString := t.Kind() == reflect.String
+	for _, rule := range strings.Split(binding, ",") {
+		key, val, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			if isString {
+				l := int(n)
+				if key != "max" {
+					s.MinLength = &l
+				}
+				if key != "min" {
+					s.MaxLength = &l
+				}
+			} else {
+				if key != "max" {
+					s.Minimum = &n
+				}
+				if key != "min" {
+					s.Maximum = &n
+				}
+			}
+		case "oneof":
+			for _, v := range strings.Fields(val) {
+				if n, err := strconv.Atoi(v); err == nil && !isString {
+					s.Enum = append(s.Enum, n)
+				} else {
+					s.Enum = append(s.Enum, v)
+				}
+			}
+		}
+	}
+	return required
+}
+
+// schemaName 用 包名.类型名 避免不同包的同名类型冲突，如 handler.LoginRequest
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	// 泛型实例化的类型名包含类型参数的完整包路径，如 Page[red-packet/dto.Transfer] -> Page_dto.Transfer
+	name := typeArgPathRe.ReplaceAllString(t.Name(), "")
+	name = strings.NewReplacer("[", "_", "]", "", "*", "", ",", "_").Replace(name)
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
@@ -0,0 +1,251 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Param 查询参数或路径参数
+type Param struct {
+	Name        string
+	In          string // query / path / header
+	Type        string // integer / string / boolean
+	Required    bool
+	Description string
+}
+
+// Operation 一个接口的文档描述，Method + Path 与 gin 路由一一对应
+type Operation struct {
+	Method   string
+	Path     string // gin 风格路径，如 /api/red-packets/:id
+	Tag      string
+	Summary  string
+	Auth     bool
+	Params   []Param
+	Request  interface{} // 请求体类型的零值，nil 表示无请求体
+	Response interface{} // data 字段类型的零值，nil 表示无具体结构
+	Files    []string    // 文件下载接口的响应 MIME 类型，设置后成功响应为二进制文件而非 JSON
+}
+
+type Document struct {
+	OpenAPI    string                        `json:"openapi"`
+	Info       Info                          `json:"info"`
+	Servers    []Server                      `json:"servers,omitempty"`
+	Paths      map[string]map[string]*PathOp `json:"paths"`
+	Components Components                    `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type PathOp struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+var pathParamRe = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// toOpenAPIPath 把 gin 的 :id 转成 OpenAPI 的 {id}
+func toOpenAPIPath(p string) string {
+	return pathParamRe.ReplaceAllString(p, "{$1}")
+}
+
+// Build 根据接口描述生成完整文档
+func Build(ops []Operation) *Document {
+	reg := newSchemaRegistry()
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "Red Packet API",
+			Version:     "1.0.0",
+			Description: errorCodesDescription,
+		},
+		Paths: map[string]map[string]*PathOp{},
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	for _, op := range ops {
+		path := toOpenAPIPath(op.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*PathOp{}
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = buildPathOp(reg, op)
+	}
+	doc.Components.Schemas = reg.schemas
+	return doc
+}
+
+func buildPathOp(reg *schemaRegistry, op Operation) *PathOp {
+	p := &PathOp{
+		Summary:     op.Summary,
+		OperationID: operationID(op),
+		Responses:   map[string]*Response{},
+	}
+	if op.Tag != "" {
+		p.Tags = []string{op.Tag}
+	}
+	if op.Auth {
+		p.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	for _, m := range pathParamRe.FindAllStringSubmatch(op.Path, -1) {
+		p.Parameters = append(p.Parameters, &Parameter{
+			Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	for _, prm := range op.Params {
+		typ := prm.Type
+		if typ == "" {
+			typ = "string"
+		}
+		p.Parameters = append(p.Parameters, &Parameter{
+			Name: prm.Name, In: prm.In, Required: prm.Required,
+			Description: prm.Description, Schema: &Schema{Type: typ},
+		})
+	}
+
+	if op.Request != nil {
+		p.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				"application/json": {Schema: reg.schemaOf(reflect.TypeOf(op.Request))},
+			},
+		}
+	}
+
+	if len(op.Files) > 0 {
+		content := map[string]*MediaType{}
+		for _, mime := range op.Files {
+			content[mime] = &MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+		}
+		p.Responses["200"] = &Response{Description: "文件下载；参数错误时返回 JSON 错误响应", Content: content}
+		return p
+	}
+
+	data := &Schema{Nullable: true}
+	if op.Response != nil {
+		data = reg.schemaOf(reflect.TypeOf(op.Response))
+	}
+	p.Responses["200"] = &Response{
+		Description: "code=0 表示成功，其他 code 见错误码说明",
+		Content: map[string]*MediaType{
+			"application/json": {Schema: envelope(data)},
+		},
+	}
+	return p
+}
+
+// envelope 统一响应格式 {code, message, data}
+func envelope(data *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer", Format: "int32"},
+			"message": {Type: "string"},
+			"data":    data,
+		},
+		Required: []string{"code", "message", "data"},
+	}
+}
+
+// operationID 如 POST /api/red-packets/:id/claim -> post_api_red_packets_id_claim
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method) + strings.NewReplacer("/", "_", "-", "_", ":", "").Replace(op.Path)
+	return strings.TrimSuffix(id, "_")
+}
+
+const errorCodesDescription = `统一响应格式为 {"code": 0, "message": "success", "data": ...}，code 含义：
+
+| code | 含义 |
+|------|------|
+| 0 | 成功 |
+| 400 | 请求参数错误 |
+| 401 | 未登录 / Token 无效 |
+| 403 | 无权限 |
+| 404 | 资源不存在 |
+| 500 | 服务器内部错误 |
+| 1001 | 余额不足 |
+| 1002 | 红包已抢完 |
+| 1003 | 红包已过期 |
+| 1004 | 已领取过该红包 |
+| 1005 | 红包未到开启时间 |
+| 1006 | 口令错误 |
+| 1007 | 口令错误次数过多 |
+| 1008 | 账户已冻结 |
+| 1009 | 风控拒绝 |
+| 1010 | 风控要求验证 |
+| 1011 | 超出收发限额 |
+| 1012 | 分享令牌无效或已过期 |
+| 1013 | 抽奖报名已截止 |
+| 1014 | 不是指定金额红包的领取人 |
+| 1015 | 红包排队人数已满，请稍后重试 |
+| 1016 | 并发领取冲突重试后仍失败，请稍后重试 |
+| 1101 | 收款人不存在 |
+| 1102 | 转账已过期或已处理 |
+| 1103 | 收款人超出当日领取限额 |
+| 1201 | 已支付过该收款 |
+| 1202 | 收款已结束 |
+| 1203 | 提醒过于频繁 |
+| 1204 | 参与人不存在 |
+| 1205 | 收款发起人超出当日领取限额 |
+| 1301 | 活动预算不足 |
+| 1302 | 活动已结束 |
+| 1303 | 活动未开始或已结束（领取时） |
+| 1304 | 已达到活动每人领取上限 |
+| 1401 | 红包雨已结束或已抢完 |
+| 1402 | 红包雨未开始 |
+| 1403 | 领取券无效或已过期 |
+| 1404 | 领取券已使用 |
+| 1405 | 已达到红包雨每人上限 |
+| 1406 | 红包雨排队已满，请稍后重试 |
+`
@@ -0,0 +1,155 @@
+// 内置的接口文档页：读取 /openapi.json 渲染接口列表并支持在线调试，不依赖任何外部资源
+(function () {
+  "use strict";
+
+  var tokenInput = document.getElementById("token");
+  tokenInput.value = localStorage.getItem("redPacketToken") || "";
+  tokenInput.addEventListener("change", function () {
+    localStorage.setItem("redPacketToken", tokenInput.value);
+  });
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) {
+      if (k === "text") node.textContent = attrs[k];
+      else node.setAttribute(k, attrs[k]);
+    });
+    (children || []).forEach(function (c) {
+      if (c) node.appendChild(typeof c === "string" ? document.createTextNode(c) : c);
+    });
+    return node;
+  }
+
+  // example 按 schema 生成示例值，$ref 指向 components.schemas
+  function example(spec, schema, depth) {
+    if (!schema || depth > 6) return null;
+    if (schema.$ref) return example(spec, spec.components.schemas[schema.$ref.split("/").pop()], depth + 1);
+    if (schema.enum) return schema.enum[0];
+    switch (schema.type) {
+      case "object":
+        var obj = {};
+        Object.keys(schema.properties || {}).forEach(function (k) {
+          obj[k] = example(spec, schema.properties[k], depth + 1);
+        });
+        return obj;
+      case "array":
+        return [example(spec, schema.items, depth + 1)];
+      case "integer":
+      case "number":
+        return schema.minimum || 0;
+      case "boolean":
+        return false;
+      case "string":
+        return schema.format === "date-time" ? new Date().toISOString() : "";
+    }
+    return null;
+  }
+
+  function jsonSchema(content) {
+    var media = content && (content["application/json"] || content[Object.keys(content)[0]]);
+    return media && media.schema;
+  }
+
+  function renderOperation(spec, path, method, op) {
+    var params = op.parameters || [];
+    var inputs = {};
+    var rows = params.map(function (p) {
+      var input = el("input", { placeholder: p.schema && p.schema.type || "string" });
+      inputs[p.name + "@" + p.in] = input;
+      return el("tr", {}, [
+        el("td", { text: p.name + (p.required ? " *" : "") }),
+        el("td", { text: p.in }),
+        el("td", { text: p.description || "" }),
+        el("td", {}, [input])
+      ]);
+    });
+
+    var reqSchema = op.requestBody && jsonSchema(op.requestBody.content);
+    var bodyInput = reqSchema ? el("textarea", {}, [JSON.stringify(example(spec, reqSchema, 0), null, 2)]) : null;
+    var okSchema = op.responses && op.responses["200"] && jsonSchema(op.responses["200"].content);
+    var output = el("pre", { text: "" });
+
+    var send = el("button", { type: "button", text: "发送请求" });
+    send.addEventListener("click", function () {
+      var url = path;
+      var query = [];
+      var headers = {};
+      params.forEach(function (p) {
+        var v = inputs[p.name + "@" + p.in].value;
+        if (v === "") return;
+        if (p.in === "path") url = url.replace("{" + p.name + "}", encodeURIComponent(v));
+        else if (p.in === "query") query.push(encodeURIComponent(p.name) + "=" + encodeURIComponent(v));
+        else if (p.in === "header") headers[p.name] = v;
+      });
+      if (query.length) url += "?" + query.join("&");
+      if (tokenInput.value) headers.Authorization = "Bearer " + tokenInput.value;
+      var init = { method: method.toUpperCase(), headers: headers };
+      if (bodyInput) {
+        headers["Content-Type"] = "application/json";
+        init.body = bodyInput.value;
+      }
+      output.textContent = "请求中…";
+      fetch(url, init).then(function (res) {
+        return res.text().then(function (text) {
+          try {
+            text = JSON.stringify(JSON.parse(text), null, 2);
+          } catch (e) {
+            // 非 JSON 响应（如账单下载）原样展示
+          }
+          output.textContent = res.status + " " + res.statusText + "\n\n" + text;
+        });
+      }).catch(function (err) {
+        output.textContent = String(err);
+      });
+    });
+
+    return el("details", {}, [
+      el("summary", {}, [
+        el("span", { "class": "method " + method.toUpperCase(), text: method.toUpperCase() }),
+        el("span", { "class": "path", text: path }),
+        " " + (op.summary || ""),
+        op.security ? el("span", { "class": "lock", text: "🔒" }) : null
+      ]),
+      el("div", { "class": "body" }, [
+        rows.length ? el("table", {}, [el("tr", {}, [el("th", { text: "参数" }), el("th", { text: "位置" }), el("th", { text: "说明" }), el("th", { text: "值" })])].concat(rows)) : null,
+        bodyInput ? el("h4", { text: "请求体" }) : null,
+        bodyInput,
+        okSchema ? el("h4", { text: "响应 data 示例" }) : null,
+        okSchema ? el("pre", { text: JSON.stringify(example(spec, okSchema, 0), null, 2) }) : null,
+        send,
+        output
+      ])
+    ]);
+  }
+
+  fetch("/openapi.json").then(function (res) {
+    return res.json();
+  }).then(function (spec) {
+    document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+    var app = document.getElementById("app");
+    app.textContent = "";
+    if (spec.info.description) app.appendChild(el("p", { text: spec.info.description }));
+
+    var groups = {};
+    var order = [];
+    Object.keys(spec.paths).sort().forEach(function (path) {
+      Object.keys(spec.paths[path]).forEach(function (method) {
+        var op = spec.paths[path][method];
+        var tag = (op.tags && op.tags[0]) || "default";
+        if (!groups[tag]) {
+          groups[tag] = [];
+          order.push(tag);
+        }
+        groups[tag].push(renderOperation(spec, path, method, op));
+      });
+    });
+    order.forEach(function (tag) {
+      app.appendChild(el("h2", { text: tag }));
+      groups[tag].forEach(function (node) {
+        app.appendChild(node);
+      });
+    });
+  }).catch(function (err) {
+    document.getElementById("app").textContent = "加载 /openapi.json 失败：" + err;
+  });
+})();
@@ -0,0 +1,50 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// Message 投递到消息中间件的一条消息，Key 用于分区或去重
+type Message struct {
+	Key  string
+	Body []byte
+}
+
+// Broker 消息中间件抽象，接入 Kafka / RabbitMQ 等时实现该接口即可
+type Broker interface {
+	Publish(ctx context.Context, topic string, msgs ...Message) error
+}
+
+type Handler func(topic string, msg Message)
+
+// Memory 进程内实现：同步回调订阅者，适合本地开发和单进程内的消费方
+type Memory struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewMemory() *Memory {
+	return &Memory{handlers: map[string][]Handler{}}
+}
+
+func (m *Memory) Subscribe(topic string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[topic] = append(m.handlers[topic], h)
+}
+
+func (m *Memory) Publish(ctx context.Context, topic string, msgs ...Message) error {
+	m.mu.RLock()
+	hs := m.handlers[topic]
+	m.mu.RUnlock()
+	for _, msg := range msgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, h := range hs {
+			h(topic, msg)
+		}
+	}
+	return nil
+}
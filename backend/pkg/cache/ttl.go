@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// TTL 带过期时间和容量上限的进程内缓存。过期条目在读取时淘汰，写入时每隔一个 TTL 整体清扫一次；
+// 达到容量上限时随机淘汰条目，内存占用不会无限增长
+type TTL[K comparable, V any] struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	maxEntries int
+	items      map[K]ttlItem[V]
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+type ttlItem[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// NewTTL maxEntries 为条目数上限，<= 0 表示不限制（仍会定期清扫过期条目）
+func NewTTL[K comparable, V any](ttl time.Duration, maxEntries int) *TTL[K, V] {
+	return &TTL[K, V]{ttl: ttl, maxEntries: maxEntries, items: map[K]ttlItem[V]{}, lastSweep: time.Now(), now: time.Now}
+}
+
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	item, ok := c.items[key]
+	c.mu.RUnlock()
+	now := c.now()
+	if ok && now.After(item.expiresAt) {
+		// 释放读锁后其他协程可能已写入新值，加写锁后再确认一次仍是过期条目才删除
+		c.mu.Lock()
+		if cur, exists := c.items[key]; exists && now.After(cur.expiresAt) {
+			delete(c.items, key)
+		}
+		c.mu.Unlock()
+		ok = false
+	}
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return item.value, true
+}
+
+func (c *TTL[K, V]) Set(key K, value V) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) >= c.ttl {
+		c.sweep(now)
+	}
+	if _, exists := c.items[key]; !exists && c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		for k := range c.items {
+			if len(c.items) < c.maxEntries {
+				break
+			}
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = ttlItem[V]{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Len 当前条目数，包含尚未清扫的过期条目
+func (c *TTL[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// sweep 删除全部过期条目，调用方持有写锁
+func (c *TTL[K, V]) sweep(now time.Time) {
+	for k, item := range c.items {
+		if now.After(item.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+	c.lastSweep = now
+}
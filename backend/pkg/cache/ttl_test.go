@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
+
+func newTestTTL(ttl time.Duration, maxEntries int) (*TTL[string, int], *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	c := NewTTL[string, int](ttl, maxEntries)
+	c.now = clock.Now
+	c.lastSweep = clock.Now()
+	return c, clock
+}
+
+func TestTTLExpires(t *testing.T) {
+	c, clock := newTestTTL(time.Minute, 0)
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get = %d, %v; want 1, true", v, ok)
+	}
+	clock.Advance(time.Minute + time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired entry returned")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("Len = %d after expired read, want 0", c.Len())
+	}
+}
+
+// 过期条目即使不再被读取，也会在之后的写入中被清扫
+func TestTTLSweepsUnreadEntries(t *testing.T) {
+	c, clock := newTestTTL(time.Minute, 0)
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	clock.Advance(2 * time.Minute)
+	c.Set("fresh", 1)
+	if c.Len() != 1 {
+		t.Fatalf("Len = %d after sweep, want 1", c.Len())
+	}
+}
+
+func TestTTLMaxEntries(t *testing.T) {
+	c, _ := newTestTTL(time.Hour, 100)
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+		if c.Len() > 100 {
+			t.Fatalf("Len = %d, exceeds max entries", c.Len())
+		}
+	}
+	if v, ok := c.Get("999"); !ok || v != 999 {
+		t.Fatalf("latest entry missing: %d, %v", v, ok)
+	}
+	// 覆盖已有的键不触发淘汰
+	c.Set("999", 1)
+	if c.Len() != 100 {
+		t.Fatalf("Len = %d after overwrite, want 100", c.Len())
+	}
+}
+
+// 读到过期条目后、删除前被重新写入的新值不能被删掉
+func TestTTLGetKeepsRefreshedValue(t *testing.T) {
+	c, clock := newTestTTL(time.Minute, 0)
+	c.Set("a", 1)
+	clock.Advance(2 * time.Minute)
+
+	// Get 释放读锁后才取当前时间，在这时写入新值模拟并发的 Set
+	inject := true
+	c.now = func() time.Time {
+		if inject {
+			inject = false
+			c.Set("a", 2)
+		}
+		return clock.Now()
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired value returned")
+	}
+	if v, ok := c.Get("a"); !ok || v != 2 {
+		t.Fatalf("Get = %d, %v; want refreshed value 2", v, ok)
+	}
+}
+
+func TestTTLConcurrent(t *testing.T) {
+	c := NewTTL[int, int](time.Millisecond, 50)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				c.Set(i%200, g)
+				c.Get(i % 200)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Len() > 50 {
+		t.Fatalf("Len = %d, exceeds max entries", c.Len())
+	}
+}
+
+func BenchmarkTTLGet(b *testing.B) {
+	c := NewTTL[int, int](time.Hour, 0)
+	for i := 0; i < 1024; i++ {
+		c.Set(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(i & 1023)
+			i++
+		}
+	})
+}
+
+func BenchmarkTTLSet(b *testing.B) {
+	c := NewTTL[int, int](time.Hour, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Set(i&1023, i)
+			i++
+		}
+	})
+}
+
+// 达到容量上限后每次写入新键都要淘汰
+func BenchmarkTTLSetAtCapacity(b *testing.B) {
+	c := NewTTL[int, int](time.Hour, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(i, i)
+	}
+}
+
+// 读多写少，接近用户名缓存的访问模式
+func BenchmarkTTLMixed(b *testing.B) {
+	c := NewTTL[int, int](time.Hour, 4096)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if _, ok := c.Get(i & 8191); !ok {
+				c.Set(i&8191, i)
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Currency 币种定义，金额一律以最小单位（如分）存储
+type Currency struct {
+	Code      string // ISO 4217 代码，如 CNY
+	Precision int    // 最小单位的小数位数，CNY=2，JPY=0
+}
+
+var (
+	mu          sync.RWMutex
+	currencies  = map[string]Currency{"CNY": {Code: "CNY", Precision: 2}}
+	defaultCode = "CNY"
+)
+
+// Init 用配置覆盖支持的币种列表，defaultCode 必须在列表中
+func Init(def string, list []Currency) error {
+	m := make(map[string]Currency, len(list))
+	for _, c := range list {
+		code := strings.ToUpper(c.Code)
+		if code == "" || c.Precision < 0 || c.Precision > 8 {
+			return fmt.Errorf("invalid currency config: %+v", c)
+		}
+		m[code] = Currency{Code: code, Precision: c.Precision}
+	}
+	def = strings.ToUpper(def)
+	if _, ok := m[def]; !ok {
+		return fmt.Errorf("default currency %q is not configured", def)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	currencies = m
+	defaultCode = def
+	return nil
+}
+
+func Default() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultCode
+}
+
+// Normalize 统一为大写，空值取默认币种；不支持的币种返回错误
+func Normalize(code string) (string, error) {
+	if code == "" {
+		return Default(), nil
+	}
+	code = strings.ToUpper(code)
+	if _, ok := Get(code); !ok {
+		return "", fmt.Errorf("unsupported currency: %s", code)
+	}
+	return code, nil
+}
+
+func Get(code string) (Currency, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := currencies[code]
+	return c, ok
+}
+
+// Format 将最小单位金额格式化为带小数的字符串，如 12345 分 -> "123.45"
+func Format(code string, amount uint64) string {
+	c, ok := Get(code)
+	if !ok || c.Precision == 0 {
+		return fmt.Sprintf("%d", amount)
+	}
+	var unit uint64 = 1
+	for i := 0; i < c.Precision; i++ {
+		unit *= 10
+	}
+	return fmt.Sprintf("%d.%0*d", amount/unit, c.Precision, amount%unit)
+}
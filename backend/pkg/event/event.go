@@ -0,0 +1,93 @@
+package event
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	RedPacketSent     = "red_packet.sent"     // 发出红包
+	RedPacketOpened   = "red_packet.opened"   // 定时红包开启
+	RedPacketClaimed  = "red_packet.claimed"  // 有人领取
+	RedPacketEmptied  = "red_packet.emptied"  // 最后一个名额被领取
+	RedPacketExpired  = "red_packet.expired"  // 过期，剩余金额已退回
+	RedPacketRefunded = "red_packet.refunded" // 管理员强制退款
+	RedPacketDrawn    = "red_packet.drawn"    // 抽奖红包开奖，未发出的金额已退回
+)
+
+// RedPacketTypes 可对外订阅的红包生命周期事件
+var RedPacketTypes = []string{
+	RedPacketSent, RedPacketOpened, RedPacketClaimed, RedPacketEmptied, RedPacketExpired, RedPacketRefunded,
+	RedPacketDrawn,
+}
+
+// RedPacketData 红包事件的业务数据，写入发件箱并作为 webhook 的 data 字段
+type RedPacketData struct {
+	RedPacketID     uint64 `json:"red_packet_id"`
+	PublicID        string `json:"public_id"`
+	SenderID        uint64 `json:"sender_id"`
+	CampaignID      uint64 `json:"campaign_id,omitempty"` // 活动红包所属活动，此时 sender_id 为 0
+	PacketType      int8   `json:"packet_type"`
+	Currency        string `json:"currency"`
+	TotalAmount     uint64 `json:"total_amount"`
+	TotalCount      uint32 `json:"total_count"`
+	RemainingAmount uint64 `json:"remaining_amount"`
+	RemainingCount  uint32 `json:"remaining_count"`
+	Status          int8   `json:"status"`
+	SeedHash        string `json:"seed_hash,omitempty"`     // 拼手气红包种子承诺
+	Seed            string `json:"seed,omitempty"`          // 红包结束后公开的种子
+	ReceiverID      uint64 `json:"receiver_id,omitempty"`   // claimed / emptied：领取者
+	Amount          uint64 `json:"amount,omitempty"`        // claimed / emptied：本次领取金额
+	RefundAmount    uint64 `json:"refund_amount,omitempty"` // expired / refunded / drawn：退回金额
+	EntryCount      uint32 `json:"entry_count,omitempty"`   // drawn：报名人数
+	WinnerCount     uint32 `json:"winner_count,omitempty"`  // drawn：中奖人数
+	EntriesHash     string `json:"entries_hash,omitempty"`  // drawn：报名名单摘要，见 fairsplit.EntriesHash
+}
+
+type Event struct {
+	Type        string    `json:"type"`
+	RedPacketID uint64    `json:"red_packet_id"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+type Handler func(Event)
+
+var (
+	mu       sync.RWMutex
+	handlers []Handler
+)
+
+// Subscribe 注册事件处理函数，进程内同步回调
+func Subscribe(h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, h)
+}
+
+func Publish(e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	log.Printf("event %s red_packet_id=%d", e.Type, e.RedPacketID)
+
+	mu.RLock()
+	hs := handlers
+	mu.RUnlock()
+	for _, h := range hs {
+		h(e)
+	}
+}
+
+// DomainEvent 领域事件的对外格式，拉取接口和消息中间件转发共用
+type DomainEvent struct {
+	Seq           uint64          `json:"seq"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uint64          `json:"aggregate_id"`
+	UserID        uint64          `json:"user_id"`
+	Data          json.RawMessage `json:"data"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
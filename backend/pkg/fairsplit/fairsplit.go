@@ -0,0 +1,147 @@
+// Package fairsplit 拼手气红包的可验证拆分：所有份额只由服务端种子和领取顺序决定。
+// 发红包时公布种子的 SHA-256 承诺，红包抢完、过期或退款后公开种子，任何人都可以按本包重算每一份金额。
+package fairsplit
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Algorithm 算法版本，随公开数据一起返回，算法变更时递增
+const Algorithm = "hmac-sha256-double-mean-v1"
+
+// SeedBytes 种子长度
+const SeedBytes = 32
+
+var ErrInvalidSeed = errors.New("invalid seed")
+
+// NewSeed 生成随机种子，返回十六进制字符串
+func NewSeed() (string, error) {
+	b := make([]byte, SeedBytes)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Commitment 种子的承诺值：hex(SHA-256(种子原始字节))
+func Commitment(seed string) (string, error) {
+	b, err := decodeSeed(seed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Share 第 index 个（从 0 开始）领取者的金额，remainingAmount、remainingCount 为领取前的剩余。
+// 与原有拼手气规则一致：最后一人拿走剩余，其余人在 [1, 剩余均值*2-1] 内均匀取值
+func Share(seed string, index uint32, remainingAmount uint64, remainingCount uint32) (uint64, error) {
+	key, err := decodeSeed(seed)
+	if err != nil {
+		return 0, err
+	}
+	return share(key, index, remainingAmount, remainingCount), nil
+}
+
+// Shares 按领取顺序重算全部份额，总和等于 total
+func Shares(seed string, total uint64, count uint32) ([]uint64, error) {
+	key, err := decodeSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 || total < uint64(count) {
+		return nil, errors.New("total must be >= count and count must be > 0")
+	}
+	shares := make([]uint64, 0, count)
+	remaining := total
+	for i := uint32(0); i < count; i++ {
+		s := share(key, i, remaining, count-i)
+		shares = append(shares, s)
+		remaining -= s
+	}
+	return shares, nil
+}
+
+func share(key []byte, index uint32, remainingAmount uint64, remainingCount uint32) uint64 {
+	if remainingCount <= 1 {
+		return remainingAmount
+	}
+	maxAmount := remainingAmount/uint64(remainingCount)*2 - 1
+	if maxAmount < 1 {
+		maxAmount = 1
+	}
+	return uniform(key, strconv.FormatUint(uint64(index), 10), maxAmount) + 1
+}
+
+// EntriesHash 报名名单摘要：按报名顺序把每个用户ID写成 "<user_id>\n" 拼接后取 hex(SHA-256)
+func EntriesHash(userIDs []uint64) string {
+	h := sha256.New()
+	for _, id := range userIDs {
+		h.Write([]byte(strconv.FormatUint(id, 10) + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DrawSeed 开奖种子：hex(SHA-256(种子原始字节 || 名单摘要原始字节))。
+// 名单在报名截止后才确定，之后的任何一个报名都会改变结果，知道种子也无法在报名期间挑选中奖的位置
+func DrawSeed(seed, entriesHash string) (string, error) {
+	key, err := decodeSeed(seed)
+	if err != nil {
+		return "", err
+	}
+	digest, err := hex.DecodeString(entriesHash)
+	if err != nil || len(digest) != sha256.Size {
+		return "", errors.New("invalid entries hash")
+	}
+	sum := sha256.Sum256(append(key, digest...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Winners 从 n 个报名者（按报名顺序编号 0..n-1）中抽出 k 个，返回的顺序即中奖名次。
+// 做法为部分 Fisher-Yates 洗牌：第 i 轮在 [i, n) 中取 j = i + uniform("draw:i", n-i) 并交换。
+// seed 应传 DrawSeed 的结果，直接用红包种子时中奖位置在报名前就已确定
+func Winners(seed string, n, k int) ([]int, error) {
+	key, err := decodeSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	k = min(k, n)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	for i := 0; i < k; i++ {
+		j := i + int(uniform(key, "draw:"+strconv.Itoa(i), uint64(n-i)))
+		order[i], order[j] = order[j], order[i]
+	}
+	return order[:k], nil
+}
+
+// uniform 返回 [0, n) 内的均匀随机数：取 HMAC-SHA256(种子, "标签:尝试次数") 前 8 字节（大端），
+// 落在 n 的整数倍之外时换下一个尝试次数重抽，避免取模偏差。拆分金额的标签为领取序号
+func uniform(key []byte, label string, n uint64) uint64 {
+	limit := math.MaxUint64 - math.MaxUint64%n
+	for attempt := 0; ; attempt++ {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(label + ":" + strconv.Itoa(attempt)))
+		v := binary.BigEndian.Uint64(mac.Sum(nil)[:8])
+		if v < limit {
+			return v % n
+		}
+	}
+}
+
+func decodeSeed(seed string) ([]byte, error) {
+	b, err := hex.DecodeString(seed)
+	if err != nil || len(b) != SeedBytes {
+		return nil, ErrInvalidSeed
+	}
+	return b, nil
+}
@@ -0,0 +1,84 @@
+package fairsplit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"testing"
+)
+
+const testSeed = "5b0e4a0f3c2d1e0f5b0e4a0f3c2d1e0f5b0e4a0f3c2d1e0f5b0e4a0f3c2d77d3"
+
+func TestEntriesHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("7\n42\n3\n"))
+	if got := EntriesHash([]uint64{7, 42, 3}); got != hex.EncodeToString(sum[:]) {
+		t.Fatalf("EntriesHash = %s", got)
+	}
+	// 顺序不同摘要不同
+	if EntriesHash([]uint64{7, 42, 3}) == EntriesHash([]uint64{42, 7, 3}) {
+		t.Fatal("entries hash ignores order")
+	}
+}
+
+func TestDrawSeed(t *testing.T) {
+	h := EntriesHash([]uint64{1, 2, 3})
+	ds, err := DrawSeed(testSeed, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := hex.DecodeString(testSeed)
+	digest, _ := hex.DecodeString(h)
+	sum := sha256.Sum256(append(key, digest...))
+	if ds != hex.EncodeToString(sum[:]) {
+		t.Fatalf("DrawSeed = %s", ds)
+	}
+	if _, err := DrawSeed(testSeed, "abc"); err == nil {
+		t.Fatal("invalid entries hash accepted")
+	}
+	if _, err := DrawSeed("abc", h); err == nil {
+		t.Fatal("invalid seed accepted")
+	}
+}
+
+// 报名截止前追加一个报名，中奖位置随之改变：只知道种子无法预先挑选中奖的位置
+func TestWinnersDependOnEntries(t *testing.T) {
+	entries := make([]uint64, 50)
+	for i := range entries {
+		entries[i] = uint64(i + 1)
+	}
+	winnersFor := func(list []uint64) []int {
+		ds, err := DrawSeed(testSeed, EntriesHash(list))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := Winners(ds, len(list), 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+	base := winnersFor(entries)
+	if !slices.Equal(base, winnersFor(entries)) {
+		t.Fatal("winners are not deterministic")
+	}
+	if slices.Equal(base, winnersFor(append(slices.Clone(entries), 51))[:len(base)]) {
+		t.Fatal("winners unchanged after an extra entry")
+	}
+}
+
+func TestWinnersDistinct(t *testing.T) {
+	w, err := Winners(testSeed, 10, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w) != 10 {
+		t.Fatalf("len = %d, want 10", len(w))
+	}
+	seen := map[int]bool{}
+	for _, i := range w {
+		if i < 0 || i >= 10 || seen[i] {
+			t.Fatalf("invalid winners %v", w)
+		}
+		seen[i] = true
+	}
+}
@@ -0,0 +1,113 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Params 列表查询参数：传 cursor 时按游标翻页，否则按 page 偏移翻页
+type Params struct {
+	Page     int
+	PageSize int
+	Cursor   *Cursor
+}
+
+// Cursor 指向上一页最后一条记录的 (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint64
+}
+
+// Result 分页结果元信息，next_cursor 在两种模式下都会返回，便于客户端切换到游标翻页
+type Result struct {
+	Total      int64
+	NextCursor string
+	HasMore    bool
+}
+
+type cursorToken struct {
+	T  int64  `json:"t"`
+	ID uint64 `json:"id"`
+}
+
+// Parse 解析并校验 page / page_size / cursor 参数
+func Parse(c *gin.Context, defaultSize, maxSize int) (Params, error) {
+	p := Params{Page: 1, PageSize: defaultSize}
+
+	if v := c.Query("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSize {
+			return p, errors.New("page_size must be between 1 and " + strconv.Itoa(maxSize))
+		}
+		p.PageSize = n
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cur, err := DecodeCursor(v)
+		if err != nil {
+			return p, err
+		}
+		p.Cursor = cur
+		return p, nil
+	}
+
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, errors.New("page must be a positive integer")
+		}
+		p.Page = n
+	}
+	return p, nil
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(cursorToken{T: c.CreatedAt.UnixNano(), ID: c.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var t cursorToken
+	if err := json.Unmarshal(b, &t); err != nil || t.ID == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &Cursor{CreatedAt: time.Unix(0, t.T), ID: t.ID}, nil
+}
+
+// Apply 追加排序、游标条件和 limit；多取一条用于判断 has_more
+func (p Params) Apply(db *gorm.DB, createdCol, idCol string, desc bool) *gorm.DB {
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	db = db.Order(createdCol + " " + dir).Order(idCol + " " + dir)
+	if p.Cursor != nil {
+		db = db.Where("("+createdCol+", "+idCol+") "+cmp+" (?, ?)", p.Cursor.CreatedAt, p.Cursor.ID)
+	} else {
+		db = db.Offset((p.Page - 1) * p.PageSize)
+	}
+	return db.Limit(p.PageSize + 1)
+}
+
+// Trim 去掉 Apply 多取的一条并生成分页元信息
+func Trim[T any](list []T, p Params, total int64, key func(T) Cursor) ([]T, Result) {
+	r := Result{Total: total}
+	if len(list) > p.PageSize {
+		list = list[:p.PageSize]
+		r.HasMore = true
+	}
+	if r.HasMore && len(list) > 0 {
+		r.NextCursor = key(list[len(list)-1]).Encode()
+	}
+	return list, r
+}
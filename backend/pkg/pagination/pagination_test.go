@@ -0,0 +1,122 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2026, 2, 17, 20, 0, 1, 123456789, time.UTC), ID: 42}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Fatalf("decoded %+v, want %+v", got, c)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	valid := Cursor{CreatedAt: time.Now(), ID: 7}.Encode()
+	for name, s := range map[string]string{
+		"not base64":  "!!!",
+		"not json":    base64.RawURLEncoding.EncodeToString([]byte("hello")),
+		"zero id":     base64.RawURLEncoding.EncodeToString([]byte(`{"t":1,"id":0}`)),
+		"wrong types": base64.RawURLEncoding.EncodeToString([]byte(`{"t":"x","id":1}`)),
+		"truncated":   valid[:len(valid)-4],
+		"padded":      valid + "==",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if c, err := DecodeCursor(s); err == nil || err.Error() != "invalid cursor" {
+				t.Fatalf("DecodeCursor(%q) = %+v, %v, want invalid cursor", s, c, err)
+			}
+		})
+	}
+}
+
+func parseQuery(t *testing.T, query string) (Params, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+	return Parse(c, 20, 100)
+}
+
+func TestParse(t *testing.T) {
+	cur := Cursor{CreatedAt: time.Unix(100, 0), ID: 9}
+	p, err := parseQuery(t, "page=3&page_size=10&cursor="+cur.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 传了游标时忽略 page
+	if p.Page != 1 || p.PageSize != 10 || p.Cursor == nil || p.Cursor.ID != 9 {
+		t.Fatalf("params = %+v", p)
+	}
+
+	for _, q := range []string{"cursor=bogus", "page_size=0", "page_size=101", "page=0", "page=x"} {
+		if _, err := parseQuery(t, q); err == nil {
+			t.Errorf("Parse(%q) succeeded", q)
+		}
+	}
+}
+
+// 时间戳相同的记录按 id 决胜：游标条件比较 (created_at, id) 元组，排序也带上 id，翻页不漏不重
+func TestApplyTieBreaksOnID(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		ID        uint64
+		CreatedAt time.Time
+	}
+	at := time.Date(2026, 2, 17, 20, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		desc      bool
+		wantOrder string
+		wantCmp   string
+	}{
+		{true, "ORDER BY created_at DESC,id DESC", "(created_at, id) < (?, ?)"},
+		{false, "ORDER BY created_at ASC,id ASC", "(created_at, id) > (?, ?)"},
+	} {
+		p := Params{PageSize: 2, Cursor: &Cursor{CreatedAt: at, ID: 5}}
+		stmt := p.Apply(db.Table("rows"), "created_at", "id", tc.desc).Find(&[]row{}).Statement
+		sql := stmt.SQL.String()
+		if !strings.Contains(sql, tc.wantCmp) || !strings.Contains(sql, tc.wantOrder) || !strings.HasSuffix(sql, "LIMIT ?") {
+			t.Errorf("desc=%v: sql = %s", tc.desc, sql)
+		}
+		if !reflect.DeepEqual(stmt.Vars, []interface{}{at, uint64(5), 3}) {
+			t.Errorf("desc=%v: vars = %v", tc.desc, stmt.Vars)
+		}
+	}
+
+	// 同一时间戳的三条记录，第一页的游标必须带上最后一条的 id
+	rows := []row{{ID: 9, CreatedAt: at}, {ID: 8, CreatedAt: at}, {ID: 7, CreatedAt: at}}
+	page, r := Trim(rows, Params{PageSize: 2}, 3, func(x row) Cursor { return Cursor{CreatedAt: x.CreatedAt, ID: x.ID} })
+	if len(page) != 2 || !r.HasMore {
+		t.Fatalf("page = %v, has_more = %v", page, r.HasMore)
+	}
+	next, err := DecodeCursor(r.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != 8 || !next.CreatedAt.Equal(at) {
+		t.Fatalf("next cursor = %+v, want id 8 at %v", next, at)
+	}
+}
+
+func TestTrimLastPage(t *testing.T) {
+	list, r := Trim([]int{1, 2}, Params{PageSize: 2}, 2, func(int) Cursor { return Cursor{} })
+	if len(list) != 2 || r.HasMore || r.NextCursor != "" {
+		t.Fatalf("list = %v, result = %+v", list, r)
+	}
+}
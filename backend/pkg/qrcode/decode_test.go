@@ -0,0 +1,380 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 测试用的解码器，按 ISO/IEC 18004 独立实现，不复用编码器的表和函数，
+// 用来校验编码结果能被标准读码流程还原
+
+// specBlocks 纠错等级 M 的分块结构：(块数, 每块数据码字数) 两组 + 每块纠错码字数
+var specBlocks = map[int]struct {
+	n1, d1, n2, d2, ec int
+}{
+	1:  {1, 16, 0, 0, 10},
+	2:  {1, 28, 0, 0, 16},
+	3:  {1, 44, 0, 0, 26},
+	4:  {2, 32, 0, 0, 18},
+	5:  {2, 43, 0, 0, 24},
+	6:  {4, 27, 0, 0, 16},
+	7:  {4, 31, 0, 0, 18},
+	8:  {2, 38, 2, 39, 22},
+	9:  {3, 36, 2, 37, 22},
+	10: {4, 43, 1, 44, 26},
+}
+
+var specAlignment = map[int][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+}
+
+// specFormatM 纠错等级 M 下掩码 0~7 的 15 位格式信息（已异或 101010000010010）
+var specFormatM = [8]string{
+	"101010000010010",
+	"101000100100101",
+	"101111001111100",
+	"101101101001011",
+	"100010111111001",
+	"100000011001110",
+	"100111110010111",
+	"100101010100000",
+}
+
+// specVersionInfo 版本 7~10 的 18 位版本信息
+var specVersionInfo = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+
+type decoded struct {
+	version int
+	mask    int
+	data    []byte
+}
+
+func decode(c *Code) (*decoded, error) {
+	size := c.Size
+	if size < 21 || (size-17)%4 != 0 {
+		return nil, fmt.Errorf("invalid size %d", size)
+	}
+	ver := (size - 17) / 4
+	blk, ok := specBlocks[ver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported version %d", ver)
+	}
+	dark := func(col, row int) bool { return c.Dark(col, row) }
+
+	if err := checkFixedPatterns(c, ver); err != nil {
+		return nil, err
+	}
+
+	// 格式信息两份，按 MSB 在前读取
+	var f1, f2 []byte
+	for k := 0; k < 15; k++ {
+		var col, row int
+		switch {
+		case k <= 5:
+			col, row = k, 8
+		case k == 6:
+			col, row = 7, 8
+		case k == 7:
+			col, row = 8, 8
+		case k == 8:
+			col, row = 8, 7
+		default:
+			col, row = 8, 14-k
+		}
+		f1 = append(f1, bitChar(dark(col, row)))
+		if k <= 6 {
+			f2 = append(f2, bitChar(dark(8, size-1-k)))
+		} else {
+			f2 = append(f2, bitChar(dark(size-15+k, 8)))
+		}
+	}
+	if string(f1) != string(f2) {
+		return nil, fmt.Errorf("format copies differ: %s / %s", f1, f2)
+	}
+	mask := -1
+	for m, s := range specFormatM {
+		if s == string(f1) {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return nil, fmt.Errorf("format %s is not level M", f1)
+	}
+
+	if ver >= 7 {
+		var tr, bl int
+		for i := 0; i < 18; i++ {
+			if dark(size-11+i%3, i/3) {
+				tr |= 1 << i
+			}
+			if dark(i/3, size-11+i%3) {
+				bl |= 1 << i
+			}
+		}
+		if tr != specVersionInfo[ver] || bl != specVersionInfo[ver] {
+			return nil, fmt.Errorf("version info %05X / %05X, want %05X", tr, bl, specVersionInfo[ver])
+		}
+	}
+
+	reserved := reservedModules(size, ver)
+	var bits []bool
+	upward := true
+	for col := size - 1; col > 0; col -= 2 {
+		if col == 6 {
+			col--
+		}
+		for i := 0; i < size; i++ {
+			row := i
+			if upward {
+				row = size - 1 - i
+			}
+			for _, x := range []int{col, col - 1} {
+				if reserved[row][x] {
+					continue
+				}
+				bits = append(bits, dark(x, row) != maskBit(mask, row, x))
+			}
+		}
+		upward = !upward
+	}
+
+	totalData := blk.n1*blk.d1 + blk.n2*blk.d2
+	total := totalData + (blk.n1+blk.n2)*blk.ec
+	if len(bits) < total*8 {
+		return nil, fmt.Errorf("only %d data modules", len(bits))
+	}
+	codewords := make([]byte, total)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				codewords[i] |= 0x80 >> j
+			}
+		}
+	}
+
+	// 反交错：先按列取数据码字，再取纠错码字
+	var lens []int
+	for i := 0; i < blk.n1; i++ {
+		lens = append(lens, blk.d1)
+	}
+	for i := 0; i < blk.n2; i++ {
+		lens = append(lens, blk.d2)
+	}
+	blocks := make([][]byte, len(lens))
+	k := 0
+	for i := 0; i < blk.d1+1; i++ {
+		for b, n := range lens {
+			if i < n {
+				blocks[b] = append(blocks[b], codewords[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < blk.ec; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[k])
+			k++
+		}
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		if !syndromesZero(block, blk.ec) {
+			return nil, fmt.Errorf("block %d fails Reed-Solomon check", b)
+		}
+		data = append(data, block[:lens[b]]...)
+	}
+
+	text, err := parseByteSegment(data, ver)
+	if err != nil {
+		return nil, err
+	}
+	return &decoded{version: ver, mask: mask, data: text}, nil
+}
+
+// checkFixedPatterns 定位图形及分隔符、定时图形、对齐图形和固定深色模块
+func checkFixedPatterns(c *Code, ver int) error {
+	size := c.Size
+	for _, o := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := -1; dy <= 7; dy++ {
+			for dx := -1; dx <= 7; dx++ {
+				x, y := o[0]+dx, o[1]+dy
+				if x < 0 || y < 0 || x >= size || y >= size {
+					continue
+				}
+				ring := max(abs(dx-3), abs(dy-3))
+				if want := ring != 2 && ring != 4; c.Dark(x, y) != want {
+					return fmt.Errorf("finder module (%d,%d) wrong", x, y)
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+			return fmt.Errorf("timing pattern wrong at %d", i)
+		}
+	}
+	for _, cx := range specAlignment[ver] {
+		for _, cy := range specAlignment[ver] {
+			if overlapsFinder(size, cx, cy) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					if want := max(abs(dx), abs(dy)) != 1; c.Dark(cx+dx, cy+dy) != want {
+						return fmt.Errorf("alignment module (%d,%d) wrong", cx+dx, cy+dy)
+					}
+				}
+			}
+		}
+	}
+	if !c.Dark(8, size-8) {
+		return errors.New("dark module missing")
+	}
+	return nil
+}
+
+func overlapsFinder(size, cx, cy int) bool {
+	return (cx < 9 && cy < 9) || (cx > size-10 && cy < 9) || (cx < 9 && cy > size-10)
+}
+
+// reservedModules 不承载数据的模块
+func reservedModules(size, ver int) [][]bool {
+	r := make([][]bool, size)
+	for i := range r {
+		r[i] = make([]bool, size)
+	}
+	mark := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				r[y][x] = true
+			}
+		}
+	}
+	mark(0, 0, 9, 9)
+	mark(size-8, 0, 8, 9)
+	mark(0, size-8, 9, 8)
+	mark(0, 6, size, 1)
+	mark(6, 0, 1, size)
+	for _, cx := range specAlignment[ver] {
+		for _, cy := range specAlignment[ver] {
+			if !overlapsFinder(size, cx, cy) {
+				mark(cx-2, cy-2, 5, 5)
+			}
+		}
+	}
+	if ver >= 7 {
+		mark(size-11, 0, 3, 6)
+		mark(0, size-11, 6, 3)
+	}
+	return r
+}
+
+// maskBit 掩码条件，i 为行、j 为列
+func maskBit(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMulLog(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// syndromesZero 码字多项式在 α^0 … α^(ec-1) 处取值均为 0 即无错
+func syndromesZero(block []byte, ec int) bool {
+	for j := 0; j < ec; j++ {
+		var s byte
+		for _, c := range block {
+			s = gfMulLog(s, gfExp[j]) ^ c
+		}
+		if s != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseByteSegment 单个字节模式段，校验终止符和填充码字
+func parseByteSegment(data []byte, ver int) ([]byte, error) {
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(data[pos>>3]>>(7-pos&7)&1)
+			pos++
+		}
+		return v
+	}
+	if mode := read(4); mode != 0b0100 {
+		return nil, fmt.Errorf("mode %04b, want byte mode", mode)
+	}
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+	n := read(countBits)
+	if pos+n*8 > len(data)*8 {
+		return nil, fmt.Errorf("length %d exceeds data", n)
+	}
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(read(8))
+	}
+	if term := min(4, len(data)*8-pos); read(term) != 0 {
+		return nil, errors.New("terminator not zero")
+	}
+	if pos%8 != 0 && read(8-pos%8) != 0 {
+		return nil, errors.New("padding bits not zero")
+	}
+	for i, pad := pos/8, byte(0xEC); i < len(data); i, pad = i+1, pad^0xEC^0x11 {
+		if data[i] != pad {
+			return nil, fmt.Errorf("pad codeword %d = %#x, want %#x", i, data[i], pad)
+		}
+	}
+	return out, nil
+}
+
+func bitChar(b bool) byte {
+	if b {
+		return '1'
+	}
+	return '0'
+}
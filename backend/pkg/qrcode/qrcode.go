@@ -0,0 +1,467 @@
+// Package qrcode 生成分享链接用的二维码：字节模式、纠错等级 M、版本 1~10（最多 213 字节），
+// 足够容纳带签名的分享 URL，不引入第三方依赖。
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Code 二维码矩阵，modules[y][x] 为 true 表示深色模块
+type Code struct {
+	Size    int
+	modules [][]bool
+}
+
+// versionInfo 纠错等级 M 下各版本的码字结构
+type versionInfo struct {
+	total     int   // 总码字数
+	ecPerBlk  int   // 每块纠错码字数
+	blocks    int   // 块数
+	alignment []int // 对齐图形中心坐标
+}
+
+var versions = [...]versionInfo{
+	1:  {26, 10, 1, nil},
+	2:  {44, 16, 1, []int{6, 18}},
+	3:  {70, 26, 1, []int{6, 22}},
+	4:  {100, 18, 2, []int{6, 26}},
+	5:  {134, 24, 2, []int{6, 30}},
+	6:  {172, 16, 4, []int{6, 34}},
+	7:  {196, 18, 4, []int{6, 22, 38}},
+	8:  {242, 22, 4, []int{6, 24, 42}},
+	9:  {292, 22, 5, []int{6, 26, 46}},
+	10: {346, 26, 5, []int{6, 28, 50}},
+}
+
+const maxVersion = 10
+
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// Encode 以字节模式编码文本，自动选择能容纳的最小版本和惩罚分最低的掩码
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	ver := 0
+	for v := 1; v <= maxVersion; v++ {
+		if len(data) <= capacity(v) {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := interleave(ver, dataCodewords(ver, data))
+
+	q := newMatrix(ver)
+	q.drawFunctionPatterns(ver)
+	q.drawCodewords(codewords)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask) // 异或两次即还原
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+
+	return &Code{Size: q.size, modules: q.modules}, nil
+}
+
+// Dark 坐标 (x, y) 处是否为深色模块
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// PNG 输出黑白 PNG，scale 为每个模块的像素数，四周保留 4 个模块宽的静区
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	const quiet = 4
+	n := (c.Size + quiet*2) * scale
+	img := image.NewPaletted(image.Rect(0, 0, n, n), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quiet)*scale+dx, (y+quiet)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func dataLen(ver int) int {
+	v := versions[ver]
+	return v.total - v.ecPerBlk*v.blocks
+}
+
+// capacity 字节模式可容纳的字节数：模式 4 位 + 长度（版本 1~9 为 8 位，10 起为 16 位）
+func capacity(ver int) int {
+	return (dataLen(ver)*8 - 4 - countBits(ver)) / 8
+}
+
+func countBits(ver int) int {
+	if ver < 10 {
+		return 8
+	}
+	return 16
+}
+
+// dataCodewords 模式指示符 + 长度 + 数据 + 终止符，再用 0xEC/0x11 补齐
+func dataCodewords(ver int, data []byte) []byte {
+	var bb bitBuffer
+	bb.append(0b0100, 4)
+	bb.append(len(data), countBits(ver))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	capBits := dataLen(ver) * 8
+	bb.append(0, min(4, capBits-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capBits; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	out := make([]byte, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			out[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, (val>>i)&1 == 1)
+	}
+}
+
+// interleave 分块计算纠错码后交错排列；短块在前，长块比短块多一个数据码字
+func interleave(ver int, data []byte) []byte {
+	v := versions[ver]
+	numShort := v.blocks - v.total%v.blocks
+	shortLen := v.total / v.blocks
+	divisor := rsDivisor(v.ecPerBlk)
+
+	blocks := make([][]byte, 0, v.blocks)
+	k := 0
+	for i := 0; i < v.blocks; i++ {
+		n := shortLen - v.ecPerBlk
+		if i >= numShort {
+			n++
+		}
+		dat := append([]byte(nil), data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(dat, divisor)
+		if i < numShort {
+			dat = append(dat, 0) // 占位，交错时跳过
+		}
+		blocks = append(blocks, append(dat, ecc...))
+	}
+
+	out := make([]byte, 0, v.total)
+	for i := range blocks[0] {
+		for j, blk := range blocks {
+			if i != shortLen-v.ecPerBlk || j >= numShort {
+				out = append(out, blk[i])
+			}
+		}
+	}
+	return out
+}
+
+// rsDivisor Reed-Solomon 生成多项式，GF(256) 本原多项式 0x11D
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMul(coef, factor)
+		}
+	}
+	return result
+}
+
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+type matrix struct {
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newMatrix(ver int) *matrix {
+	size := ver*4 + 17
+	m := &matrix{size: size, modules: make([][]bool, size), isFunction: make([][]bool, size)}
+	for i := 0; i < size; i++ {
+		m.modules[i] = make([]bool, size)
+		m.isFunction[i] = make([]bool, size)
+	}
+	return m
+}
+
+func (m *matrix) setFunction(x, y int, dark bool) {
+	m.modules[y][x] = dark
+	m.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns 定时图形、定位图形、对齐图形、格式信息占位和版本信息
+func (m *matrix) drawFunctionPatterns(ver int) {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	m.drawFinder(3, 3)
+	m.drawFinder(m.size-4, 3)
+	m.drawFinder(3, m.size-4)
+
+	pos := versions[ver].alignment
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // 与定位图形重叠
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					m.setFunction(pos[i]+dx, pos[j]+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	m.drawFormatBits(0)
+	m.drawVersion(ver)
+}
+
+func (m *matrix) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= m.size || y < 0 || y >= m.size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			m.setFunction(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+// drawFormatBits 格式信息：纠错等级 M(00) + 掩码号，BCH(15,5) 编码后与 0x5412 异或，两处各放一份
+func (m *matrix) drawFormatBits(mask int) {
+	data := mask // 等级 M 的指示位为 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		m.setFunction(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(i))
+	}
+	m.setFunction(8, m.size-8, true) // 固定深色模块
+}
+
+// drawVersion 版本 7 起需要 18 位版本信息，BCH(18,6) 编码
+func (m *matrix) drawVersion(ver int) {
+	if ver < 7 {
+		return
+	}
+	rem := ver
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := ver<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := m.size-11+i%3, i/3
+		m.setFunction(a, b, dark)
+		m.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords 从右下角开始按两列一组蛇形填充数据位，跳过功能区和第 6 列定时图形
+func (m *matrix) drawCodewords(data []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < m.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = m.size - 1 - vert
+				}
+				if !m.isFunction[y][x] && i < len(data)*8 {
+					m.modules[y][x] = (data[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (m *matrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !m.isFunction[y][x] {
+				m.modules[y][x] = !m.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty 按标准的四条规则计算惩罚分，用于挑选掩码
+func (m *matrix) penalty() int {
+	score := 0
+	get := func(x, y int, vertical bool) bool {
+		if vertical {
+			return m.modules[x][y]
+		}
+		return m.modules[y][x]
+	}
+
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < m.size; y++ {
+			// 规则 1：同色连续 5 个及以上
+			run := 1
+			for x := 1; x < m.size; x++ {
+				if get(x, y, vertical) == get(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+			// 规则 3：类定位图形 1011101 且一侧有 4 个浅色
+			for x := 0; x+10 < m.size; x++ {
+				var p [11]bool
+				for k := range p {
+					p[k] = get(x+k, y, vertical)
+				}
+				core := p[0] && !p[1] && p[2] && p[3] && p[4] && !p[5] && p[6]
+				if core && !p[7] && !p[8] && !p[9] && !p[10] {
+					score += 40
+				}
+				core = p[4] && !p[5] && p[6] && p[7] && p[8] && !p[9] && p[10]
+				if core && !p[0] && !p[1] && !p[2] && !p[3] {
+					score += 40
+				}
+			}
+		}
+	}
+
+	// 规则 2：2x2 同色块
+	dark := 0
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			c := m.modules[y][x]
+			if c {
+				dark++
+			}
+			if x+1 < m.size && y+1 < m.size &&
+				c == m.modules[y][x+1] && c == m.modules[y+1][x] && c == m.modules[y+1][x+1] {
+				score += 3
+			}
+		}
+	}
+
+	// 规则 4：深色比例每偏离 50% 五个百分点加 10 分
+	total := m.size * m.size
+	score += abs(dark*20-total*10) / total * 10
+	return score
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
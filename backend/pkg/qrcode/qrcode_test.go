@@ -0,0 +1,128 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// specCapacity 纠错等级 M 下各版本字节模式的容量
+var specCapacity = []int{0, 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	texts := []string{
+		"",
+		"a",
+		"https://hb.example.com/s/q3Xo1mZ8hR2kVt0c?t=eyJhbGciOiJIUzI1NiJ9.eyJwIjoicTNYbzEifQ.sig",
+		"恭喜发财，大吉大利",
+		strings.Repeat("0", 100),
+	}
+	// 每个版本的容量边界，字节随机以覆盖不同掩码
+	rng := rand.New(rand.NewSource(1))
+	for v := 1; v <= maxVersion; v++ {
+		for _, n := range []int{specCapacity[v-1] + 1, specCapacity[v]} {
+			b := make([]byte, n)
+			rng.Read(b)
+			texts = append(texts, string(b))
+		}
+	}
+
+	masks := map[int]bool{}
+	for _, text := range texts {
+		c, err := Encode(text)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", len(text), err)
+		}
+		d, err := decode(c)
+		if err != nil {
+			t.Fatalf("decode(%d bytes): %v", len(text), err)
+		}
+		if !bytes.Equal(d.data, []byte(text)) {
+			t.Fatalf("round trip of %d bytes returned %q", len(text), d.data)
+		}
+		if want := minVersion(len(text)); d.version != want {
+			t.Errorf("%d bytes encoded as version %d, want %d", len(text), d.version, want)
+		}
+		masks[d.mask] = true
+	}
+	if len(masks) < 4 {
+		t.Errorf("only masks %v chosen, mask selection looks stuck", masks)
+	}
+}
+
+func minVersion(n int) int {
+	for v := 1; v < len(specCapacity); v++ {
+		if n <= specCapacity[v] {
+			return v
+		}
+	}
+	return 0
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", specCapacity[maxVersion]+1)); !errors.Is(err, ErrTooLong) {
+		t.Fatalf("err = %v, want ErrTooLong", err)
+	}
+}
+
+// 规范附录示例 1-M "01234567" 的纠错码字，数据码字取自数字模式编码结果
+func TestReedSolomonKnownAnswer(t *testing.T) {
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("ecc = % X, want % X", got, want)
+	}
+}
+
+// 单个模块被翻转后 RS 校验应当失败，确保解码器的校验确实生效
+func TestDecodeDetectsCorruption(t *testing.T) {
+	c, err := Encode("https://hb.example.com/s/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved := reservedModules(c.Size, (c.Size-17)/4)
+	x, y := c.Size-1, c.Size-1
+	if reserved[y][x] {
+		t.Fatal("bottom-right module unexpectedly reserved")
+	}
+	c.modules[y][x] = !c.modules[y][x]
+	if _, err := decode(c); err == nil {
+		t.Fatal("decode succeeded on corrupted code")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	c, err := Encode("https://hb.example.com/s/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const scale = 3
+	b, err := c.PNG(scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := (c.Size + 8) * scale
+	if img.Bounds().Dx() != n || img.Bounds().Dy() != n {
+		t.Fatalf("image %v, want %dx%d", img.Bounds(), n, n)
+	}
+	isDark := func(px, py int) bool {
+		r, _, _, _ := img.At(px, py).RGBA()
+		return r < 0x8000
+	}
+	for y := -4; y < c.Size+4; y++ {
+		for x := -4; x < c.Size+4; x++ {
+			want := x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.Dark(x, y)
+			px, py := (x+4)*scale, (y+4)*scale
+			if isDark(px, py) != want || isDark(px+scale-1, py+scale-1) != want {
+				t.Fatalf("pixel for module (%d,%d) wrong", x, y)
+			}
+		}
+	}
+}
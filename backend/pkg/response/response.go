@@ -1,6 +1,10 @@
 package response
 
-import "github.com/gin-gonic/gin"
+import (
+	"red-packet/pkg/pagination"
+
+	"github.com/gin-gonic/gin"
+)
 
 type Response struct {
 	Code    int         `json:"code"`
@@ -15,3 +19,18 @@ func Success(c *gin.Context, data interface{}) {
 func Fail(c *gin.Context, httpStatus int, code int, message string) {
 	c.JSON(httpStatus, Response{Code: code, Message: message, Data: nil})
 }
+
+// Page 分页列表的统一结构，list 为空时输出 [] 而不是 null
+type Page[T any] struct {
+	Total      int64  `json:"total"`
+	List       []T    `json:"list"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+func NewPage[T any](list []T, r pagination.Result) Page[T] {
+	if list == nil {
+		list = []T{}
+	}
+	return Page[T]{Total: r.Total, List: list, NextCursor: r.NextCursor, HasMore: r.HasMore}
+}
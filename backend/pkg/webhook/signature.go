@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 请求头
+const (
+	HeaderSignature = "X-RedPacket-Signature" // t=<unix 秒>,v1=<hex(HMAC-SHA256(secret, "<t>.<body>"))>
+	HeaderEvent     = "X-RedPacket-Event"
+	HeaderDelivery  = "X-RedPacket-Delivery"
+)
+
+// Sign 生成签名头的值，时间戳参与签名，接收方可据此拒绝重放
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + mac(secret, t, body)
+}
+
+// Verify 校验签名头，tolerance 为允许的时间偏差，0 表示不校验时间
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	if t == "" || v1 == "" {
+		return errors.New("malformed signature header")
+	}
+	if !hmac.Equal([]byte(v1), []byte(mac(secret, t, body))) {
+		return errors.New("signature mismatch")
+	}
+	if tolerance > 0 {
+		sec, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return errors.New("malformed signature timestamp")
+		}
+		if d := time.Since(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+			return errors.New("signature timestamp out of tolerance")
+		}
+	}
+	return nil
+}
+
+func mac(secret, t string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Payload 推送的请求体，ID 为事件 ID，同一事件重试时保持不变，接收方可据此去重
+type Payload struct {
+	ID         uint64          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
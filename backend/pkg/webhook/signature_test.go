@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":1,"type":"red_packet.sent"}`)
+	header := Sign("secret", time.Now(), body)
+	if !strings.HasPrefix(header, "t=") || !strings.Contains(header, ",v1=") {
+		t.Fatalf("unexpected header format: %s", header)
+	}
+	if err := Verify("secret", header, body, time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+	}{
+		{"wrong secret", "other", Sign("secret", now, body), body},
+		{"tampered body", "secret", Sign("secret", now, body), []byte(`{"id":2}`)},
+		{"tampered timestamp", "secret", strings.Replace(Sign("secret", now, body), "t=", "t=1", 1), body},
+		{"missing v1", "secret", "t=1700000000", body},
+		{"missing t", "secret", "v1=abcd", body},
+		{"empty", "secret", "", body},
+		{"expired", "secret", Sign("secret", now.Add(-10*time.Minute), body), body},
+		{"future", "secret", Sign("secret", now.Add(10*time.Minute), body), body},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute); err == nil {
+			t.Errorf("%s: Verify succeeded, want error", tt.name)
+		}
+	}
+}
+
+// tolerance 为 0 时不校验时间，只校验签名
+func TestVerifyWithoutTolerance(t *testing.T) {
+	body := []byte(`{}`)
+	header := Sign("secret", time.Unix(1000, 0), body)
+	if err := Verify("secret", header, body, 0); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	// 多余的空格和未知字段不影响解析
+	if err := Verify("secret", " v2=x, "+strings.Replace(header, ",", " , ", 1), body, 0); err != nil {
+		t.Fatalf("Verify with extra fields: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"127.8.8.8", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		{"fe80::1", false},
+		{"ff02::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHostLiteral(t *testing.T) {
+	ctx := context.Background()
+	if err := CheckHost(ctx, "8.8.8.8"); err != nil {
+		t.Errorf("CheckHost(8.8.8.8): %v", err)
+	}
+	for _, host := range []string{"127.0.0.1", "::1", "169.254.169.254", "10.0.0.1"} {
+		if err := CheckHost(ctx, host); !errors.Is(err, ErrBlockedTarget) {
+			t.Errorf("CheckHost(%s) = %v, want ErrBlockedTarget", host, err)
+		}
+	}
+}
+
+func TestCheckHostLocalhost(t *testing.T) {
+	// localhost 由本机 hosts 解析，无需网络
+	if err := CheckHost(context.Background(), "localhost"); !errors.Is(err, ErrBlockedTarget) {
+		t.Errorf("CheckHost(localhost) = %v, want ErrBlockedTarget", err)
+	}
+}
+
+// 连接阶段的校验：即使创建时校验通过，实际连接（含 DNS 重绑定、跳转）到回环地址同样被拒绝
+func TestNewClientBlocksPrivateDial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second, false).Get(srv.URL)
+	if !errors.Is(err, ErrBlockedTarget) {
+		t.Fatalf("Get = %v, want ErrBlockedTarget", err)
+	}
+
+	resp, err := NewClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get with allowPrivate: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", resp.StatusCode)
+	}
+}
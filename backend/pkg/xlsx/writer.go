@@ -0,0 +1,157 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDecimals 数字单元格支持的最大小数位，与币种精度上限一致
+const maxDecimals = 8
+
+// Writer 流式写出只有一个工作表的 xlsx：行数据直接写入 zip 条目，不在内存中保留整张表
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// Cell 单元格，用 String / Number 构造
+type Cell struct {
+	value  string
+	number bool
+}
+
+func String(s string) Cell {
+	return Cell{value: s}
+}
+
+// Number 数字单元格，保留字符串中的小数位数作为显示格式，如 "12.50" 显示为两位小数
+func Number(s string) Cell {
+	return Cell{value: s, number: true}
+}
+
+// NewWriter 先写入工作簿的固定部件，再打开工作表条目等待逐行写入
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML()},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err := sheet.WriteString(xml.Header + `<worksheet xmlns="` + mainNS + `"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+func (w *Writer) WriteRow(cells ...Cell) error {
+	w.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, w.rows)
+	for _, c := range cells {
+		if c.number {
+			fmt.Fprintf(&b, `<c s="%d"><v>%s</v></c>`, decimals(c.value), escape(c.value))
+			continue
+		}
+		fmt.Fprintf(&b, `<c t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, escape(c.value))
+	}
+	b.WriteString(`</row>`)
+	_, err := w.sheet.WriteString(b.String())
+	return err
+}
+
+// Flush 把已写入的行推给底层 writer，长时间导出时可定期调用
+func (w *Writer) Flush() error {
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Flush()
+}
+
+// Close 写入工作表结尾和 zip 目录，不关闭底层 writer
+func (w *Writer) Close() error {
+	if _, err := w.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+// decimals 样式下标即小数位数，0 为常规格式
+func decimals(v string) int {
+	i := strings.IndexByte(v, '.')
+	if i < 0 {
+		return 0
+	}
+	return min(len(v)-i-1, maxDecimals)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const mainNS = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookXML = xml.Header + `<workbook xmlns="` + mainNS + `" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// stylesXML 第 n 个单元格样式为 n 位小数的数字格式（自定义格式 ID 从 164 开始）
+func stylesXML() string {
+	var fmts, xfs strings.Builder
+	xfs.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>`)
+	for d := 1; d <= maxDecimals; d++ {
+		id := 163 + d
+		fmt.Fprintf(&fmts, `<numFmt numFmtId="%d" formatCode="0.%s"/>`, id, strings.Repeat("0", d))
+		fmt.Fprintf(&xfs, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, id)
+	}
+	return xml.Header + `<styleSheet xmlns="` + mainNS + `">` +
+		fmt.Sprintf(`<numFmts count="%d">%s</numFmts>`, maxDecimals, fmts.String()) +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		fmt.Sprintf(`<cellXfs count="%d">%s</cellXfs>`, maxDecimals+1, xfs.String()) +
+		`</styleSheet>`
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"red-packet/model"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+)
+
+// CreateAuditLog 审计日志与被审计的操作写在同一事务中
+func CreateAuditLog(tx *gorm.DB, log *model.AdminAuditLog) error {
+	return tx.Create(log).Error
+}
+
+type AuditLogFilter struct {
+	AdminID    uint64
+	TargetType string
+	TargetID   uint64
+}
+
+func ListAuditLogs(db *gorm.DB, filter AuditLogFilter, p pagination.Params) ([]model.AdminAuditLog, int64, error) {
+	var list []model.AdminAuditLog
+	var total int64
+	query := db.Model(&model.AdminAuditLog{})
+	if filter.AdminID != 0 {
+		query = query.Where("admin_id = ?", filter.AdminID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	// 同一条件既用于计数又用于查询列表，需开启新会话避免语句互相污染
+	query = query.Session(&gorm.Session{})
+	query.Count(&total)
+	err := p.Apply(query, "created_at", "id", true).Find(&list).Error
+	return list, total, err
+}
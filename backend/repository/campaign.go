@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"red-packet/model"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateCampaign(tx *gorm.DB, c *model.Campaign) error {
+	return tx.Create(c).Error
+}
+
+func GetCampaignByID(db *gorm.DB, id uint64) (*model.Campaign, error) {
+	var c model.Campaign
+	err := db.First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetCampaignForUpdate 锁住活动行，注资、发放批次都在此锁内修改预算余额
+func GetCampaignForUpdate(tx *gorm.DB, id uint64) (*model.Campaign, error) {
+	var c model.Campaign
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func UpdateCampaign(tx *gorm.DB, c *model.Campaign) error {
+	return tx.Save(c).Error
+}
+
+// ReturnCampaignBudget 活动红包过期或退款时把剩余金额加回预算余额，
+// 用原子自增而不加活动行锁，避免每个过期红包都与发放批次争锁
+func ReturnCampaignBudget(tx *gorm.DB, campaignID, amount uint64) error {
+	return tx.Model(&model.Campaign{}).Where("id = ?", campaignID).
+		Update("balance", gorm.Expr("balance + ?", amount)).Error
+}
+
+func ListCampaigns(db *gorm.DB, p pagination.Params) ([]model.Campaign, int64, error) {
+	var list []model.Campaign
+	var total int64
+	db.Model(&model.Campaign{}).Count(&total)
+	err := p.Apply(db, "created_at", "id", true).Find(&list).Error
+	return list, total, err
+}
+
+// CountCampaignClaims 用户在某活动所有红包中的领取次数
+func CountCampaignClaims(tx *gorm.DB, campaignID, userID uint64) (int64, error) {
+	var count int64
+	err := tx.Table("red_packet_records AS r").
+		Joins("JOIN red_packets AS p ON p.id = r.red_packet_id").
+		Where("p.campaign_id = ? AND r.receiver_id = ?", campaignID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// CampaignPacketStats 活动发出的红包汇总，Outstanding 为仍可领取（未开启或可领取）的剩余金额
+type CampaignPacketStats struct {
+	PacketCount int64
+	Issued      uint64
+	Outstanding uint64
+}
+
+// CampaignClaimStats 活动红包的领取汇总
+type CampaignClaimStats struct {
+	ClaimCount   int64
+	Claimed      uint64
+	Participants int64
+}
+
+func GetCampaignPacketStats(db *gorm.DB, campaignID uint64) (CampaignPacketStats, error) {
+	var result CampaignPacketStats
+	err := db.Model(&model.RedPacket{}).
+		Select("COUNT(*) AS packet_count, COALESCE(SUM(total_amount), 0) AS issued, "+
+			"COALESCE(SUM(CASE WHEN status IN ? THEN remaining_amount ELSE 0 END), 0) AS outstanding",
+			[]int8{model.RedPacketStatusActive, model.RedPacketStatusPending}).
+		Where("campaign_id = ?", campaignID).
+		Scan(&result).Error
+	return result, err
+}
+
+func GetCampaignClaimStats(db *gorm.DB, campaignID uint64) (CampaignClaimStats, error) {
+	var result CampaignClaimStats
+	err := db.Table("red_packet_records AS r").
+		Joins("JOIN red_packets AS p ON p.id = r.red_packet_id").
+		Select("COUNT(*) AS claim_count, COALESCE(SUM(r.amount), 0) AS claimed, COUNT(DISTINCT r.receiver_id) AS participants").
+		Where("p.campaign_id = ?", campaignID).
+		Scan(&result).Error
+	return result, err
+}
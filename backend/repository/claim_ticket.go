@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateClaimTicket(db *gorm.DB, t *model.ClaimTicket) error {
+	return db.Create(t).Error
+}
+
+func GetClaimTicket(db *gorm.DB, redPacketID, ticketID uint64) (*model.ClaimTicket, error) {
+	var t model.ClaimTicket
+	err := db.Where("id = ? AND red_packet_id = ?", ticketID, redPacketID).First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func GetClaimTicketForUpdate(tx *gorm.DB, id uint64) (*model.ClaimTicket, error) {
+	var t model.ClaimTicket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetPendingClaimTicket 用户在该红包上排队中的请求，重复提交时直接返回它
+func GetPendingClaimTicket(db *gorm.DB, redPacketID, receiverID uint64) (*model.ClaimTicket, error) {
+	var t model.ClaimTicket
+	err := db.Where("red_packet_id = ? AND receiver_id = ? AND status = ?", redPacketID, receiverID, model.ClaimTicketPending).
+		First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func CountPendingClaimTickets(db *gorm.DB, redPacketID uint64) (int64, error) {
+	var count int64
+	err := db.Model(&model.ClaimTicket{}).
+		Where("red_packet_id = ? AND status = ?", redPacketID, model.ClaimTicketPending).
+		Count(&count).Error
+	return count, err
+}
+
+// GetPendingClaimTicketIDs 按入队顺序取出一批排队中的请求ID
+func GetPendingClaimTicketIDs(db *gorm.DB, redPacketID uint64, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.ClaimTicket{}).
+		Where("red_packet_id = ? AND status = ?", redPacketID, model.ClaimTicketPending).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func UpdateClaimTicket(tx *gorm.DB, t *model.ClaimTicket) error {
+	return tx.Save(t).Error
+}
+
+// GetRedPacketIDsWithPendingTickets 有排队请求的红包，用于重启后恢复 worker
+func GetRedPacketIDsWithPendingTickets(db *gorm.DB, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.ClaimTicket{}).
+		Where("status = ?", model.ClaimTicketPending).
+		Distinct("red_packet_id").
+		Limit(limit).
+		Pluck("red_packet_id", &ids).Error
+	return ids, err
+}
+
+// EnsureClaimQueue 红包的排队计数行不存在时创建；单独提交，避免在入队事务内先取共享锁再升级为排他锁
+func EnsureClaimQueue(db *gorm.DB, redPacketID uint64) error {
+	q := &model.ClaimQueue{RedPacketID: redPacketID, UpdatedAt: time.Now()}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(q).Error
+}
+
+// IncrClaimQueue 排队数未达上限时加一，返回是否成功；条件更新保证并发入队不会超过上限
+func IncrClaimQueue(tx *gorm.DB, redPacketID uint64, limit int) (bool, error) {
+	result := tx.Model(&model.ClaimQueue{}).
+		Where("red_packet_id = ? AND pending < ?", redPacketID, limit).
+		Updates(map[string]interface{}{"pending": gorm.Expr("pending + 1"), "updated_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DecrClaimQueue 请求处理完成后排队数减一
+func DecrClaimQueue(tx *gorm.DB, redPacketID uint64) error {
+	return tx.Model(&model.ClaimQueue{}).
+		Where("red_packet_id = ? AND pending > 0", redPacketID).
+		Updates(map[string]interface{}{"pending": gorm.Expr("pending - 1"), "updated_at": time.Now()}).Error
+}
+
+// AcquireClaimQueueLease 租约空闲、已过期或本就属于 owner 时占用（续期）到 now+ttl，返回是否持有
+func AcquireClaimQueueLease(db *gorm.DB, redPacketID uint64, owner string, now time.Time, ttl time.Duration) (bool, error) {
+	if err := EnsureClaimQueue(db, redPacketID); err != nil {
+		return false, err
+	}
+	until := now.Add(ttl)
+	result := db.Model(&model.ClaimQueue{}).
+		Where("red_packet_id = ? AND (lease_owner = ? OR lease_until IS NULL OR lease_until < ?)", redPacketID, owner, now).
+		Updates(map[string]interface{}{"lease_owner": owner, "lease_until": until, "updated_at": now})
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.RowsAffected > 0, result.Error
+	}
+	// MySQL 只统计值有变化的行，同一毫秒内续期时影响行数为 0，需再确认租约归属
+	var q model.ClaimQueue
+	if err := db.Select("lease_owner").First(&q, redPacketID).Error; err != nil {
+		return false, err
+	}
+	return q.LeaseOwner == owner, nil
+}
+
+// ReleaseClaimQueueLease 释放 owner 持有的租约
+func ReleaseClaimQueueLease(db *gorm.DB, redPacketID uint64, owner string) error {
+	return db.Model(&model.ClaimQueue{}).
+		Where("red_packet_id = ? AND lease_owner = ?", redPacketID, owner).
+		Updates(map[string]interface{}{"lease_owner": "", "lease_until": nil, "updated_at": time.Now()}).Error
+}
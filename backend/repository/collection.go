@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateCollection(tx *gorm.DB, c *model.Collection) error {
+	return tx.Create(c).Error
+}
+
+func CreateCollectionShares(tx *gorm.DB, shares []model.CollectionShare) error {
+	return tx.Create(&shares).Error
+}
+
+func GetCollectionByID(db *gorm.DB, id uint64) (*model.Collection, error) {
+	var c model.Collection
+	err := db.First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func GetCollectionForUpdate(tx *gorm.DB, id uint64) (*model.Collection, error) {
+	var c model.Collection
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func UpdateCollection(tx *gorm.DB, c *model.Collection) error {
+	return tx.Save(c).Error
+}
+
+// GetCollectionShares 按创建顺序返回全部份额
+func GetCollectionShares(tx *gorm.DB, collectionID uint64) ([]model.CollectionShare, error) {
+	var list []model.CollectionShare
+	err := tx.Where("collection_id = ?", collectionID).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// UpdateCollectionShare 用 Save 更新，触发通知参与人的领域事件钩子
+func UpdateCollectionShare(tx *gorm.DB, s *model.CollectionShare) error {
+	return tx.Save(s).Error
+}
+
+// GetUserCollections 查询用户发起或参与的收款
+func GetUserCollections(db *gorm.DB, userID uint64, p pagination.Params) ([]model.Collection, int64, error) {
+	var list []model.Collection
+	var total int64
+	query := db.Model(&model.Collection{}).
+		Where("initiator_id = ? OR id IN (?)", userID,
+			db.Model(&model.CollectionShare{}).Select("collection_id").Where("user_id = ?", userID))
+	// 同一条件既用于计数又用于查询列表，需开启新会话避免语句互相污染
+	query = query.Session(&gorm.Session{})
+	query.Count(&total)
+	err := p.Apply(query, "created_at", "id", true).Find(&list).Error
+	return list, total, err
+}
+
+// GetExpiredOpenCollectionIDs 查询已到期仍在收款中的收款
+func GetExpiredOpenCollectionIDs(db *gorm.DB, now time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.Collection{}).
+		Where("status = ? AND expired_at <= ?", model.CollectionStatusOpen, now).
+		Order("expired_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"red-packet/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 序号位置名称
+const (
+	DomainEventOffsetSequence = "sequence"
+	DomainEventOffsetRelay    = "relay"
+)
+
+// GetDomainEventOffsetForUpdate 加锁读取序号位置，不存在时先插入 0
+func GetDomainEventOffsetForUpdate(tx *gorm.DB, name string) (*model.DomainEventOffset, error) {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.DomainEventOffset{Name: name}).Error
+	if err != nil {
+		return nil, err
+	}
+	var o model.DomainEventOffset
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&o, "name = ?", name).Error
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func GetDomainEventOffset(db *gorm.DB, name string) (uint64, error) {
+	var o model.DomainEventOffset
+	err := db.Where("name = ?", name).Limit(1).Find(&o).Error
+	return o.Value, err
+}
+
+func UpdateDomainEventOffset(tx *gorm.DB, name string, value uint64) error {
+	return tx.Model(&model.DomainEventOffset{}).Where("name = ?", name).Update("value", value).Error
+}
+
+// GetUnsequencedDomainEventIDs 已提交但尚未分配序号的事件，按写入顺序
+func GetUnsequencedDomainEventIDs(tx *gorm.DB, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := tx.Model(&model.DomainEvent{}).
+		Where("seq IS NULL").
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func SetDomainEventSeq(tx *gorm.DB, id, seq uint64) error {
+	return tx.Model(&model.DomainEvent{}).Where("id = ?", id).Update("seq", seq).Error
+}
+
+type DomainEventFilter struct {
+	AfterSeq uint64
+	Types    []string
+	UserID   uint64
+}
+
+// ListDomainEvents 按序号升序拉取 AfterSeq 之后的事件
+func ListDomainEvents(db *gorm.DB, filter DomainEventFilter, limit int) ([]model.DomainEvent, error) {
+	var list []model.DomainEvent
+	query := db.Where("seq > ?", filter.AfterSeq)
+	if len(filter.Types) > 0 {
+		query = query.Where("event_type IN ?", filter.Types)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	err := query.Order("seq ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
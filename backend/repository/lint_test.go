@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestNoPackageLevelDB repository 层的每次数据库访问都必须使用调用方传入的连接：
+// 事务内的读写才能落在同一事务里，回滚时不留下孤立数据。规则：
+//   - 不导入 red-packet/database，也不调用 gorm.Open 自建连接
+//   - 不声明包级别的 *gorm.DB 变量
+//   - 每个函数的第一个参数都是 *gorm.DB
+func TestNoPackageLevelDB(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	checked := 0
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checked++
+
+		gormName := ""
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			switch path {
+			case "red-packet/database":
+				t.Errorf("%s: imports %s, pass the connection in instead", fset.Position(imp.Pos()), path)
+			case "gorm.io/gorm":
+				gormName = "gorm"
+				if imp.Name != nil {
+					gormName = imp.Name.Name
+				}
+			}
+		}
+
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range d.Specs {
+					vs := spec.(*ast.ValueSpec)
+					if mentionsGormDB(vs, gormName) {
+						t.Errorf("%s: package-level *gorm.DB variable %s", fset.Position(vs.Pos()), vs.Names[0].Name)
+					}
+				}
+			case *ast.FuncDecl:
+				if !firstParamIsGormDB(d.Type, gormName) {
+					t.Errorf("%s: %s must take *gorm.DB as its first parameter", fset.Position(d.Pos()), d.Name.Name)
+				}
+			}
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			if isSelector(n, gormName, "Open") {
+				t.Errorf("%s: opens its own connection with gorm.Open", fset.Position(n.Pos()))
+			}
+			return true
+		})
+	}
+	if checked == 0 {
+		t.Fatal("no repository files checked")
+	}
+}
+
+// 包级别变量的类型或初值中出现 gorm.DB 即视为持有连接
+func mentionsGormDB(vs *ast.ValueSpec, gormName string) bool {
+	found := false
+	ast.Inspect(vs, func(n ast.Node) bool {
+		if isSelector(n, gormName, "DB") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func firstParamIsGormDB(ft *ast.FuncType, gormName string) bool {
+	if ft.Params == nil || len(ft.Params.List) == 0 {
+		return false
+	}
+	star, ok := ft.Params.List[0].Type.(*ast.StarExpr)
+	return ok && isSelector(star.X, gormName, "DB")
+}
+
+func isSelector(n ast.Node, pkg, name string) bool {
+	sel, ok := n.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && pkg != "" && id.Name == pkg
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateRain(tx *gorm.DB, r *model.Rain) error {
+	return tx.Create(r).Error
+}
+
+func GetRainByID(db *gorm.DB, id uint64) (*model.Rain, error) {
+	var r model.Rain
+	err := db.First(&r, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetRainForUpdate 锁住红包雨行，批量处理与结算都在此锁内进行
+func GetRainForUpdate(tx *gorm.DB, id uint64) (*model.Rain, error) {
+	var r model.Rain
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&r, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// TryLockRain 跳过已被其他 worker 锁住的行，返回 nil 表示该红包雨正在别处处理
+func TryLockRain(tx *gorm.DB, id uint64) (*model.Rain, error) {
+	var list []model.Rain
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id = ?", id).Limit(1).Find(&list).Error
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func UpdateRain(tx *gorm.DB, r *model.Rain) error {
+	return tx.Save(r).Error
+}
+
+func CreateRainClaim(db *gorm.DB, c *model.RainClaim) error {
+	return db.Create(c).Error
+}
+
+func GetRainClaim(db *gorm.DB, rainID, claimID uint64) (*model.RainClaim, error) {
+	var c model.RainClaim
+	err := db.Where("id = ? AND rain_id = ?", claimID, rainID).First(&c).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func UpdateRainClaim(tx *gorm.DB, c *model.RainClaim) error {
+	return tx.Save(c).Error
+}
+
+func RainNonceUsed(db *gorm.DB, rainID uint64, nonce string) (bool, error) {
+	var count int64
+	err := db.Model(&model.RainClaim{}).Where("rain_id = ? AND nonce = ?", rainID, nonce).Count(&count).Error
+	return count > 0, err
+}
+
+// CountQueuedRainClaims 红包雨当前排队中的请求数，用于限制队列长度
+func CountQueuedRainClaims(db *gorm.DB, rainID uint64) (int64, error) {
+	var count int64
+	err := db.Model(&model.RainClaim{}).
+		Where("rain_id = ? AND status = ?", rainID, model.RainClaimQueued).
+		Count(&count).Error
+	return count, err
+}
+
+// CountUserRainClaims 用户在红包雨中排队中和已抢到的请求数
+func CountUserRainClaims(db *gorm.DB, rainID, userID uint64) (int64, error) {
+	var count int64
+	err := db.Model(&model.RainClaim{}).
+		Where("rain_id = ? AND user_id = ? AND status IN ?", rainID, userID,
+			[]int8{model.RainClaimQueued, model.RainClaimSucceeded}).
+		Count(&count).Error
+	return count, err
+}
+
+// GetQueuedRainClaims 按入队顺序取出一批待处理的请求
+func GetQueuedRainClaims(tx *gorm.DB, rainID uint64, limit int) ([]model.RainClaim, error) {
+	var list []model.RainClaim
+	err := tx.Where("rain_id = ? AND status = ?", rainID, model.RainClaimQueued).
+		Order("id ASC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// CountRainWins 批量查询用户在红包雨中已抢到的份数
+func CountRainWins(tx *gorm.DB, rainID uint64, userIDs []uint64) (map[uint64]uint32, error) {
+	var rows []struct {
+		UserID uint64
+		Wins   uint32
+	}
+	err := tx.Model(&model.RainClaim{}).
+		Select("user_id, COUNT(*) AS wins").
+		Where("rain_id = ? AND status = ? AND user_id IN ?", rainID, model.RainClaimSucceeded, userIDs).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	wins := make(map[uint64]uint32, len(rows))
+	for _, r := range rows {
+		wins[r.UserID] = r.Wins
+	}
+	return wins, nil
+}
+
+// GetRainIDsWithQueuedClaims 有排队请求的红包雨
+func GetRainIDsWithQueuedClaims(db *gorm.DB, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.RainClaim{}).
+		Where("status = ?", model.RainClaimQueued).
+		Distinct("rain_id").
+		Limit(limit).
+		Pluck("rain_id", &ids).Error
+	return ids, err
+}
+
+// GetSettleableRainIDs 已过结束时间或已抢完、尚未结算的红包雨
+func GetSettleableRainIDs(db *gorm.DB, now time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.Rain{}).
+		Where("status = ? AND (end_at <= ? OR remaining_drops = 0)", model.RainStatusActive, now).
+		Order("end_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
@@ -1,21 +1,32 @@
 package repository
 
 import (
-	"errors"
-	"red-packet/database"
+	"time"
+
 	"red-packet/model"
+	"red-packet/pkg/pagination"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-func CreateRedPacket(rp *model.RedPacket) error {
-	return database.DB.Create(rp).Error
+func CreateRedPacket(db *gorm.DB, rp *model.RedPacket) error {
+	return db.Create(rp).Error
+}
+
+func GetRedPacketByID(db *gorm.DB, id uint64) (*model.RedPacket, error) {
+	var rp model.RedPacket
+	err := db.First(&rp, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rp, nil
 }
 
-func GetRedPacketByID(id uint64) (*model.RedPacket, error) {
+// GetRedPacketByPublicID 按对外公开ID查询，用户侧路由只接受公开ID
+func GetRedPacketByPublicID(db *gorm.DB, publicID string) (*model.RedPacket, error) {
 	var rp model.RedPacket
-	err := database.DB.First(&rp, id).Error
+	err := db.Where("public_id = ?", publicID).First(&rp).Error
 	if err != nil {
 		return nil, err
 	}
@@ -33,58 +44,103 @@ func GetRedPacketForUpdate(tx *gorm.DB, id uint64) (*model.RedPacket, error) {
 }
 
 func UpdateRedPacket(tx *gorm.DB, rp *model.RedPacket) error {
+	rp.Version++
 	return tx.Save(rp).Error
 }
 
+// CompareAndSwapRedPacket 只在版本号未变时写入，返回是否写入成功；失败时调用方应回滚重试
+func CompareAndSwapRedPacket(tx *gorm.DB, rp *model.RedPacket) (bool, error) {
+	version := rp.Version
+	rp.Version++
+	result := tx.Model(rp).Where("version = ?", version).Select("*").Updates(rp)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// TakeRedPacketShare 条件更新扣减一份：仍可领取且剩余足够时剩余个数 -1、剩余金额 -amount，
+// 最后一份把剩余金额清零（普通红包的尾差归最后一人）。返回更新后的红包，未命中时返回 nil
+func TakeRedPacketShare(tx *gorm.DB, id, amount uint64) (*model.RedPacket, error) {
+	// MySQL 单表 UPDATE 按从左到右的顺序赋值，status 看到的是已扣减的 remaining_count
+	result := tx.Exec("UPDATE red_packets SET "+
+		"remaining_amount = IF(remaining_count = 1, 0, remaining_amount - ?), "+
+		"remaining_count = remaining_count - 1, "+
+		"status = IF(remaining_count = 0, ?, ?), "+
+		"version = version + 1 "+
+		"WHERE id = ? AND status = ? AND remaining_count > 0 AND remaining_amount >= ?",
+		amount, model.RedPacketStatusEmpty, model.RedPacketStatusActive,
+		id, model.RedPacketStatusActive, amount)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	rp, err := GetRedPacketByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 条件更新不经过 Save，补写 red_packet.updated 领域事件
+	return rp, rp.AfterUpdate(tx)
+}
+
 func CreateRedPacketRecord(tx *gorm.DB, record *model.RedPacketRecord) error {
 	return tx.Create(record).Error
 }
 
-func GetRedPacketRecord(redPacketID, receiverID uint64) (*model.RedPacketRecord, error) {
+func GetRedPacketRecord(db *gorm.DB, redPacketID, receiverID uint64) (*model.RedPacketRecord, error) {
 	var record model.RedPacketRecord
-	err := database.DB.Where("red_packet_id = ? AND receiver_id = ?", redPacketID, receiverID).First(&record).Error
+	err := db.Where("red_packet_id = ? AND receiver_id = ?", redPacketID, receiverID).First(&record).Error
 	if err != nil {
 		return nil, err
 	}
 	return &record, nil
 }
 
-func GetRedPacketRecords(redPacketID uint64, offset, limit int) ([]model.RedPacketRecord, int64, error) {
+func GetRedPacketRecords(db *gorm.DB, redPacketID uint64, p pagination.Params) ([]model.RedPacketRecord, int64, error) {
 	var records []model.RedPacketRecord
 	var total int64
-	database.DB.Model(&model.RedPacketRecord{}).Where("red_packet_id = ?", redPacketID).Count(&total)
-	err := database.DB.Where("red_packet_id = ?", redPacketID).
-		Order("created_at ASC").
-		Offset(offset).Limit(limit).
+	db.Model(&model.RedPacketRecord{}).Where("red_packet_id = ?", redPacketID).Count(&total)
+	err := p.Apply(db.Where("red_packet_id = ?", redPacketID), "created_at", "id", false).
 		Find(&records).Error
 	return records, total, err
 }
 
-func CountRedPacketClaimed(redPacketID uint64) (int64, error) {
+func CountRedPacketClaimed(db *gorm.DB, redPacketID uint64) (int64, error) {
 	var count int64
-	err := database.DB.Model(&model.RedPacketRecord{}).Where("red_packet_id = ?", redPacketID).Count(&count).Error
+	err := db.Model(&model.RedPacketRecord{}).Where("red_packet_id = ?", redPacketID).Count(&count).Error
 	return count, err
 }
 
-func GetSentRedPackets(senderID uint64, offset, limit int) ([]model.RedPacket, int64, error) {
+func GetSentRedPackets(db *gorm.DB, senderID uint64, p pagination.Params) ([]model.RedPacket, int64, error) {
 	var list []model.RedPacket
 	var total int64
-	database.DB.Model(&model.RedPacket{}).Where("sender_id = ?", senderID).Count(&total)
-	err := database.DB.Where("sender_id = ?", senderID).
-		Order("created_at DESC").
-		Offset(offset).Limit(limit).
+	db.Model(&model.RedPacket{}).Where("sender_id = ?", senderID).Count(&total)
+	err := p.Apply(db.Where("sender_id = ?", senderID), "created_at", "id", true).
 		Find(&list).Error
 	return list, total, err
 }
 
-func GetReceivedRedPackets(receiverID uint64, offset, limit int) ([]model.RedPacketRecord, int64, error) {
-	var list []model.RedPacketRecord
+// ReceivedRow 领取记录及其所属红包的发送者、币种
+type ReceivedRow struct {
+	ID          uint64 // 领取记录ID
+	RedPacketID uint64
+	Amount      uint64
+	CreatedAt   time.Time
+	SenderID    uint64
+	Currency    string
+	PublicID    string
+}
+
+// GetReceivedRedPackets 领取记录 LEFT JOIN 红包，一次查询带出发送者和币种；红包缺失时 SenderID 为 0
+func GetReceivedRedPackets(db *gorm.DB, receiverID uint64, p pagination.Params) ([]ReceivedRow, int64, error) {
+	var list []ReceivedRow
 	var total int64
-	database.DB.Model(&model.RedPacketRecord{}).Where("receiver_id = ?", receiverID).Count(&total)
-	err := database.DB.Where("receiver_id = ?", receiverID).
-		Order("created_at DESC").
-		Offset(offset).Limit(limit).
-		Find(&list).Error
+	db.Model(&model.RedPacketRecord{}).Where("receiver_id = ?", receiverID).Count(&total)
+	query := db.Table("red_packet_records AS r").
+		Select("r.id, r.red_packet_id, r.amount, r.created_at, COALESCE(p.sender_id, 0) AS sender_id, COALESCE(p.currency, '') AS currency, COALESCE(p.public_id, '') AS public_id").
+		Joins("LEFT JOIN red_packets AS p ON p.id = r.red_packet_id").
+		Where("r.receiver_id = ?", receiverID)
+	err := p.Apply(query, "r.created_at", "r.id", true).
+		Scan(&list).Error
 	return list, total, err
 }
 
@@ -92,25 +148,83 @@ func CreateTransaction(tx *gorm.DB, t *model.Transaction) error {
 	return tx.Create(t).Error
 }
 
-func DeductUserBalance(tx *gorm.DB, userID uint64, amount uint64) error {
-	result := tx.Model(&model.User{}).Where("id = ? AND balance >= ?", userID, amount).
-		UpdateColumn("balance", gorm.Expr("balance - ?", amount))
-	if result.Error != nil {
-		return result.Error
+// GetDuePendingRedPacketIDs 查询已到开启时间、仍处于未开启状态的定时红包
+func GetDuePendingRedPacketIDs(db *gorm.DB, now time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.RedPacket{}).
+		Where("status = ? AND open_at <= ?", model.RedPacketStatusPending, now).
+		Order("open_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// OpenRedPacket 把已到开启时间的定时红包置为可领取，返回是否由本次更新开启。
+// 条件更新只有一个事务能命中，开启事件不会重复；不改版本号，不影响乐观策略随后的比对
+func OpenRedPacket(tx *gorm.DB, id uint64, now time.Time) (bool, error) {
+	result := tx.Model(&model.RedPacket{}).
+		Where("id = ? AND status = ? AND open_at <= ?", id, model.RedPacketStatusPending, now).
+		UpdateColumn("status", model.RedPacketStatusActive)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetExpiredRedPacketIDs 查询已过期但尚未结算的红包（可领取或未开启）
+func GetExpiredRedPacketIDs(db *gorm.DB, now time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.RedPacket{}).
+		Where("status IN ? AND expired_at <= ?", []int8{model.RedPacketStatusActive, model.RedPacketStatusPending}, now).
+		Order("expired_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// TakeSecretAttempt 口令尝试次数 +1（不存在则插入）并返回加 1 后的次数。
+// 自成一个事务提交：读回时行锁仍由本事务持有，并发尝试各自拿到不同的次数
+func TakeSecretAttempt(db *gorm.DB, redPacketID, userID uint64) (uint32, error) {
+	var counts []uint32
+	err := db.Transaction(func(tx *gorm.DB) error {
+		attempt := &model.RedPacketSecretAttempt{
+			RedPacketID: redPacketID,
+			UserID:      userID,
+			FailedCount: 1,
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "red_packet_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failed_count": gorm.Expr("failed_count + 1"),
+				"updated_at":   time.Now(),
+			}),
+		}).Create(attempt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&model.RedPacketSecretAttempt{}).
+			Where("red_packet_id = ? AND user_id = ?", redPacketID, userID).
+			Pluck("failed_count", &counts).Error
+	})
+	if err != nil {
+		return 0, err
 	}
-	if result.RowsAffected == 0 {
-		return errors.New("insufficient balance")
+	if len(counts) == 0 {
+		return 0, gorm.ErrRecordNotFound
 	}
-	return nil
+	return counts[0], nil
 }
 
-func AddUserBalance(tx *gorm.DB, userID uint64, amount uint64) error {
-	return tx.Model(&model.User{}).Where("id = ?", userID).
-		UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error
+// ReturnSecretAttempt 口令正确时退回占用的一次尝试
+func ReturnSecretAttempt(db *gorm.DB, redPacketID, userID uint64) error {
+	return db.Model(&model.RedPacketSecretAttempt{}).
+		Where("red_packet_id = ? AND user_id = ? AND failed_count > 0", redPacketID, userID).
+		UpdateColumns(map[string]interface{}{
+			"failed_count": gorm.Expr("failed_count - 1"),
+			"updated_at":   time.Now(),
+		}).Error
 }
 
-func GetUserBalanceInTx(tx *gorm.DB, userID uint64) (uint64, error) {
-	var user model.User
-	err := tx.Select("balance").First(&user, userID).Error
-	return user.Balance, err
+// GetAllRedPacketRecords 不分页按领取顺序返回红包的全部领取记录，红包最多 100 个名额
+func GetAllRedPacketRecords(db *gorm.DB, redPacketID uint64) ([]model.RedPacketRecord, error) {
+	var records []model.RedPacketRecord
+	err := db.Where("red_packet_id = ?", redPacketID).Order("seq ASC, id ASC").Find(&records).Error
+	return records, err
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"red-packet/model"
+
+	"gorm.io/gorm"
+)
+
+func CreateRedPacketAllocations(tx *gorm.DB, list []model.RedPacketAllocation) error {
+	return tx.Create(&list).Error
+}
+
+// GetRedPacketAllocation 查询某领取人的份额
+func GetRedPacketAllocation(tx *gorm.DB, redPacketID, receiverID uint64) (*model.RedPacketAllocation, error) {
+	var a model.RedPacketAllocation
+	err := tx.Where("red_packet_id = ? AND receiver_id = ?", redPacketID, receiverID).First(&a).Error
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func UpdateRedPacketAllocation(tx *gorm.DB, a *model.RedPacketAllocation) error {
+	return tx.Save(a).Error
+}
+
+// RefundPendingAllocations 红包结束时把未领取的份额标记为已退回
+func RefundPendingAllocations(tx *gorm.DB, redPacketID uint64) error {
+	return tx.Model(&model.RedPacketAllocation{}).
+		Where("red_packet_id = ? AND status = ?", redPacketID, model.AllocationStatusPending).
+		Update("status", model.AllocationStatusRefunded).Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+
+	"gorm.io/gorm"
+)
+
+func CreateRedPacketEntry(tx *gorm.DB, e *model.RedPacketEntry) error {
+	return tx.Create(e).Error
+}
+
+// UpdateRedPacketEntry 用 Save 更新，触发结果通知的领域事件钩子
+func UpdateRedPacketEntry(tx *gorm.DB, e *model.RedPacketEntry) error {
+	return tx.Save(e).Error
+}
+
+func GetRedPacketEntry(db *gorm.DB, redPacketID, userID uint64) (*model.RedPacketEntry, error) {
+	var e model.RedPacketEntry
+	err := db.Where("red_packet_id = ? AND user_id = ?", redPacketID, userID).First(&e).Error
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func CountRedPacketEntries(tx *gorm.DB, redPacketID uint64) (int64, error) {
+	var count int64
+	err := tx.Model(&model.RedPacketEntry{}).Where("red_packet_id = ?", redPacketID).Count(&count).Error
+	return count, err
+}
+
+// GetRedPacketEntries 按报名顺序返回全部报名，开奖时的编号即此顺序下标
+func GetRedPacketEntries(tx *gorm.DB, redPacketID uint64) ([]model.RedPacketEntry, error) {
+	var list []model.RedPacketEntry
+	err := tx.Where("red_packet_id = ?", redPacketID).Order("id ASC").Find(&list).Error
+	return list, err
+}
+
+// GetDueLotteryIDs 查询报名已截止、尚未开奖的抽奖红包
+func GetDueLotteryIDs(db *gorm.DB, now time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.RedPacket{}).
+		Where("status = ? AND draw_at <= ?", model.RedPacketStatusActive, now).
+		Order("draw_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
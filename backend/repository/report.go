@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+
+	"gorm.io/gorm"
+)
+
+// 以下统计均为 [start, end) 区间内、单一币种的聚合查询，供年度报告使用
+
+// AmountCount 金额合计与笔数
+type AmountCount struct {
+	Total uint64
+	Count int64
+}
+
+// SumUserTransactionsBetween 统计用户某类流水在区间内的总金额和笔数
+func SumUserTransactionsBetween(db *gorm.DB, userID uint64, txType, currency string, start, end time.Time) (AmountCount, error) {
+	var result AmountCount
+	err := db.Model(&model.Transaction{}).
+		Select("COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("user_id = ? AND type = ? AND currency = ? AND created_at >= ? AND created_at < ?",
+			userID, txType, currency, start, end).
+		Scan(&result).Error
+	return result, err
+}
+
+// MonthlyAmount 按月聚合结果，Month 形如 2026-01
+type MonthlyAmount struct {
+	Month string
+	Total uint64
+	Count int64
+}
+
+// SumUserTransactionsByMonth 按月统计用户某类流水
+func SumUserTransactionsByMonth(db *gorm.DB, userID uint64, txType, currency string, start, end time.Time) ([]MonthlyAmount, error) {
+	var list []MonthlyAmount
+	err := db.Model(&model.Transaction{}).
+		Select("DATE_FORMAT(created_at, '%Y-%m') AS month, COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("user_id = ? AND type = ? AND currency = ? AND created_at >= ? AND created_at < ?",
+			userID, txType, currency, start, end).
+		Group("month").
+		Order("month ASC").
+		Scan(&list).Error
+	return list, err
+}
+
+// receivedQuery 用户在区间内领到的、指定币种红包的领取记录
+func receivedQuery(db *gorm.DB, receiverID uint64, currency string, start, end time.Time) *gorm.DB {
+	return db.Table("red_packet_records AS r").
+		Joins("JOIN red_packets AS p ON p.id = r.red_packet_id").
+		Where("r.receiver_id = ? AND p.currency = ? AND r.created_at >= ? AND r.created_at < ?",
+			receiverID, currency, start, end)
+}
+
+// BiggestClaim 单次领取金额最大的一笔
+type BiggestClaim struct {
+	RedPacketID uint64
+	SenderID    uint64
+	Amount      uint64
+	CreatedAt   time.Time
+}
+
+// GetBiggestClaim 区间内单笔最大领取，无记录时返回 nil
+func GetBiggestClaim(db *gorm.DB, receiverID uint64, currency string, start, end time.Time) (*BiggestClaim, error) {
+	var list []BiggestClaim
+	err := receivedQuery(db, receiverID, currency, start, end).
+		Select("r.red_packet_id, p.sender_id, r.amount, r.created_at").
+		Order("r.amount DESC").Order("r.id ASC").
+		Limit(1).
+		Scan(&list).Error
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// CountBestLuck 统计用户在已抢完的拼手气红包中拿到最大金额的次数，并列最大都算
+func CountBestLuck(db *gorm.DB, receiverID uint64, currency string, start, end time.Time) (int64, error) {
+	var count int64
+	err := receivedQuery(db, receiverID, currency, start, end).
+		Where("p.type = ? AND p.status = ?", model.RedPacketTypeLucky, model.RedPacketStatusEmpty).
+		Where("r.amount = (SELECT MAX(r2.amount) FROM red_packet_records AS r2 WHERE r2.red_packet_id = r.red_packet_id)").
+		Count(&count).Error
+	return count, err
+}
+
+// CounterpartAmount 与某个用户之间的红包往来合计
+type CounterpartAmount struct {
+	UserID uint64
+	Total  uint64
+	Count  int64
+}
+
+// GetTopSendersToUser 区间内给该用户发红包（被该用户领到）金额最多的发送者
+func GetTopSendersToUser(db *gorm.DB, receiverID uint64, currency string, start, end time.Time, limit int) ([]CounterpartAmount, error) {
+	var list []CounterpartAmount
+	err := receivedQuery(db, receiverID, currency, start, end).
+		Select("p.sender_id AS user_id, SUM(r.amount) AS total, COUNT(*) AS count").
+		Where("p.sender_id <> ?", receiverID).
+		Group("p.sender_id").
+		Order("total DESC").Order("user_id ASC").
+		Limit(limit).
+		Scan(&list).Error
+	return list, err
+}
+
+// GetTopReceiversFromUser 区间内领取该用户所发红包金额最多的领取者
+func GetTopReceiversFromUser(db *gorm.DB, senderID uint64, currency string, start, end time.Time, limit int) ([]CounterpartAmount, error) {
+	var list []CounterpartAmount
+	err := db.Table("red_packet_records AS r").
+		Joins("JOIN red_packets AS p ON p.id = r.red_packet_id").
+		Select("r.receiver_id AS user_id, SUM(r.amount) AS total, COUNT(*) AS count").
+		Where("p.sender_id = ? AND p.currency = ? AND r.created_at >= ? AND r.created_at < ? AND r.receiver_id <> ?",
+			senderID, currency, start, end, senderID).
+		Group("r.receiver_id").
+		Order("total DESC").Order("user_id ASC").
+		Limit(limit).
+		Scan(&list).Error
+	return list, err
+}
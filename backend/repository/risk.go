@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+
+	"gorm.io/gorm"
+)
+
+func CreateRiskDecision(db *gorm.DB, d *model.RiskDecision) error {
+	return db.Create(d).Error
+}
+
+func CountUserRiskDecisions(db *gorm.DB, userID uint64, scene string, since time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&model.RiskDecision{}).
+		Where("user_id = ? AND scene = ? AND created_at >= ?", userID, scene, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountDeviceOtherAccounts 统计设备在时间窗口内出现过的其他账户数
+func CountDeviceOtherAccounts(db *gorm.DB, deviceID string, userID uint64, since time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&model.RiskDecision{}).
+		Where("device_id = ? AND user_id <> ? AND created_at >= ?", deviceID, userID, since).
+		Distinct("user_id").Count(&count).Error
+	return count, err
+}
+
+// CountIPOtherAccounts 统计 IP 在时间窗口内出现过的其他账户数
+func CountIPOtherAccounts(db *gorm.DB, ip string, userID uint64, since time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&model.RiskDecision{}).
+		Where("ip = ? AND user_id <> ? AND created_at >= ?", ip, userID, since).
+		Distinct("user_id").Count(&count).Error
+	return count, err
+}
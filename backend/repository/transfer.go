@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateTransfer(tx *gorm.DB, t *model.Transfer) error {
+	return tx.Create(t).Error
+}
+
+func GetTransferForUpdate(tx *gorm.DB, id uint64) (*model.Transfer, error) {
+	var t model.Transfer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func UpdateTransfer(tx *gorm.DB, t *model.Transfer) error {
+	return tx.Save(t).Error
+}
+
+// GetUserTransfers 查询用户转出或收到的转账
+func GetUserTransfers(db *gorm.DB, userID uint64, p pagination.Params) ([]model.Transfer, int64, error) {
+	var list []model.Transfer
+	var total int64
+	query := db.Model(&model.Transfer{}).Where("from_user_id = ? OR to_user_id = ?", userID, userID)
+	// 同一条件既用于计数又用于查询列表，需开启新会话避免语句互相污染
+	query = query.Session(&gorm.Session{})
+	query.Count(&total)
+	err := p.Apply(query, "created_at", "id", true).Find(&list).Error
+	return list, total, err
+}
+
+// GetExpiredPendingTransferIDs 查询超时未收款的转账
+func GetExpiredPendingTransferIDs(db *gorm.DB, now time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.Transfer{}).
+		Where("status = ? AND expired_at <= ?", model.TransferStatusPending, now).
+		Order("expired_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
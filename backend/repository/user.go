@@ -1,28 +1,87 @@
 package repository
 
 import (
-	"red-packet/database"
+	"strconv"
+
 	"red-packet/model"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func CreateUser(user *model.User) error {
-	return database.DB.Create(user).Error
+func CreateUser(db *gorm.DB, user *model.User) error {
+	return db.Create(user).Error
+}
+
+func GetUserByUsername(db *gorm.DB, username string) (*model.User, error) {
+	var user model.User
+	err := db.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-func GetUserByUsername(username string) (*model.User, error) {
+func GetUserByID(db *gorm.DB, id uint64) (*model.User, error) {
 	var user model.User
-	err := database.DB.Where("username = ?", username).First(&user).Error
+	err := db.First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func GetUserByID(id uint64) (*model.User, error) {
+// SearchUsers 按用户名模糊匹配或按 ID 精确匹配
+func SearchUsers(db *gorm.DB, keyword string, p pagination.Params) ([]model.User, int64, error) {
+	var list []model.User
+	var total int64
+	query := db.Model(&model.User{})
+	if keyword != "" {
+		if id, err := strconv.ParseUint(keyword, 10, 64); err == nil {
+			query = query.Where("id = ? OR username LIKE ?", id, keyword+"%")
+		} else {
+			query = query.Where("username LIKE ?", keyword+"%")
+		}
+	}
+	// 同一条件既用于计数又用于查询列表，需开启新会话避免语句互相污染
+	query = query.Session(&gorm.Session{})
+	query.Count(&total)
+	err := p.Apply(query, "created_at", "id", false).Find(&list).Error
+	return list, total, err
+}
+
+func GetUserForUpdate(tx *gorm.DB, id uint64) (*model.User, error) {
 	var user model.User
-	err := database.DB.First(&user, id).Error
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+func UpdateUserStatus(tx *gorm.DB, id uint64, status int8) error {
+	return tx.Model(&model.User{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func UpdateUserRoleByUsername(db *gorm.DB, username, role string) (int64, error) {
+	result := db.Model(&model.User{}).Where("username = ?", username).Update("role", role)
+	return result.RowsAffected, result.Error
+}
+
+// GetUsernamesByIDs 一次 IN 查询批量获取用户名
+func GetUsernamesByIDs(db *gorm.DB, ids []uint64) (map[uint64]string, error) {
+	names := make(map[uint64]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var users []model.User
+	err := db.Select("id", "username").Where("id IN ?", ids).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	return names, nil
+}
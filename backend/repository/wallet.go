@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"red-packet/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeductUserBalance 扣减指定币种余额，条件更新保证不会扣成负数
+func DeductUserBalance(tx *gorm.DB, userID uint64, currency string, amount uint64) error {
+	result := tx.Model(&model.Wallet{}).
+		Where("user_id = ? AND currency = ? AND balance >= ?", userID, currency, amount).
+		UpdateColumn("balance", gorm.Expr("balance - ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("insufficient balance")
+	}
+	return nil
+}
+
+// AddUserBalance 增加指定币种余额，钱包不存在时自动创建
+func AddUserBalance(tx *gorm.DB, userID uint64, currency string, amount uint64) error {
+	now := time.Now()
+	wallet := &model.Wallet{
+		UserID:    userID,
+		Currency:  currency,
+		Balance:   amount,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "currency"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance":    gorm.Expr("balance + ?", amount),
+			"updated_at": now,
+		}),
+	}).Create(wallet).Error
+}
+
+func GetUserBalance(db *gorm.DB, userID uint64, currency string) (uint64, error) {
+	var wallet model.Wallet
+	err := db.Select("balance").Where("user_id = ? AND currency = ?", userID, currency).First(&wallet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return wallet.Balance, err
+}
+
+func ListUserWallets(db *gorm.DB, userID uint64) ([]model.Wallet, error) {
+	var list []model.Wallet
+	err := db.Where("user_id = ?", userID).Order("currency ASC").Find(&list).Error
+	return list, err
+}
+
+// ForShare 之后的查询改为共享锁读。可重复读隔离级别下普通读沿用事务首次读建立的快照，
+// 看不到等锁期间其他事务提交的流水；锁用户行后的用量统计需用锁定读取得最新数据
+func ForShare(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "SHARE"})
+}
+
+// SumUserTransactions 统计用户在 since 之后指定类型、方向的流水总金额和笔数
+func SumUserTransactions(db *gorm.DB, userID uint64, txTypes []string, direction int8, currency string, since time.Time) (uint64, int64, error) {
+	var result struct {
+		Total uint64
+		Count int64
+	}
+	err := db.Model(&model.Transaction{}).
+		Select("COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("user_id = ? AND type IN ? AND direction = ? AND currency = ? AND created_at >= ?", userID, txTypes, direction, currency, since).
+		Scan(&result).Error
+	return result.Total, result.Count, err
+}
+
+// SumCollectionSettlements 用户作为发起人收到的 AA 收款结算：collection 收入流水中关联收款由本人发起的部分，
+// 参与人收到的取消、到期退回不计入
+func SumCollectionSettlements(db *gorm.DB, userID uint64, currency string, since time.Time) (uint64, int64, error) {
+	var result struct {
+		Total uint64
+		Count int64
+	}
+	err := db.Table("transactions").
+		Joins("JOIN collections ON collections.id = transactions.related_id AND collections.initiator_id = transactions.user_id").
+		Select("COALESCE(SUM(transactions.amount), 0) AS total, COUNT(*) AS count").
+		Where("transactions.user_id = ? AND transactions.type = ? AND transactions.direction = ? AND transactions.currency = ? AND transactions.created_at >= ?",
+			userID, model.TransactionTypeCollection, model.TransactionDirectionIn, currency, since).
+		Scan(&result).Error
+	return result.Total, result.Count, err
+}
+
+// SumTransferReceipts 用户作为收款人收到的转账：transfer 收入流水中关联转账的收款人是本人的部分，
+// 转出方收到的拒收、超时退回不计入
+func SumTransferReceipts(db *gorm.DB, userID uint64, currency string, since time.Time) (uint64, int64, error) {
+	var result struct {
+		Total uint64
+		Count int64
+	}
+	err := db.Table("transactions").
+		Joins("JOIN transfers ON transfers.id = transactions.related_id AND transfers.to_user_id = transactions.user_id").
+		Select("COALESCE(SUM(transactions.amount), 0) AS total, COUNT(*) AS count").
+		Where("transactions.user_id = ? AND transactions.type = ? AND transactions.direction = ? AND transactions.currency = ? AND transactions.created_at >= ?",
+			userID, model.TransactionTypeTransfer, model.TransactionDirectionIn, currency, since).
+		Scan(&result).Error
+	return result.Total, result.Count, err
+}
+
+// EachUserTransaction 按时间顺序逐行读取用户在 [start, end) 内的流水，currency 为空表示全部币种；
+// 结果集以游标方式读取，导出多年流水也不会一次性加载到内存
+func EachUserTransaction(db *gorm.DB, userID uint64, currency string, start, end time.Time, fn func(*model.Transaction) error) error {
+	query := db.Model(&model.Transaction{}).
+		Where("user_id = ? AND created_at >= ? AND created_at < ?", userID, start, end)
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+	rows, err := query.Order("created_at ASC").Order("id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t model.Transaction
+		if err := db.ScanRows(rows, &t); err != nil {
+			return err
+		}
+		if err := fn(&t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"time"
+
+	"red-packet/model"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreateOutboxEvent(tx *gorm.DB, e *model.OutboxEvent) error {
+	return tx.Create(e).Error
+}
+
+// GetUndispatchedOutboxIDs 按写入顺序取尚未展开投递的事件
+func GetUndispatchedOutboxIDs(db *gorm.DB, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := db.Model(&model.OutboxEvent{}).
+		Where("dispatched_at IS NULL").
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func GetOutboxEventForUpdate(tx *gorm.DB, id uint64) (*model.OutboxEvent, error) {
+	var e model.OutboxEvent
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&e, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func MarkOutboxDispatched(tx *gorm.DB, id uint64, at time.Time) error {
+	return tx.Model(&model.OutboxEvent{}).Where("id = ?", id).Update("dispatched_at", at).Error
+}
+
+func CreateWebhookSubscription(tx *gorm.DB, s *model.WebhookSubscription) error {
+	return tx.Create(s).Error
+}
+
+func GetWebhookSubscription(db *gorm.DB, id uint64) (*model.WebhookSubscription, error) {
+	var s model.WebhookSubscription
+	err := db.First(&s, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func ListWebhookSubscriptions(db *gorm.DB, status int8) ([]model.WebhookSubscription, error) {
+	var list []model.WebhookSubscription
+	query := db.Order("id ASC")
+	if status != 0 {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&list).Error
+	return list, err
+}
+
+func UpdateWebhookSubscriptionStatus(tx *gorm.DB, id uint64, status int8) error {
+	return tx.Model(&model.WebhookSubscription{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// CreateWebhookDeliveries 批量创建投递，同一订阅同一事件已存在时忽略（分发任务重入时幂等）
+func CreateWebhookDeliveries(tx *gorm.DB, list []model.WebhookDelivery) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&list).Error
+}
+
+// GetDueWebhookDeliveries 到达重试时间的待投递记录
+func GetDueWebhookDeliveries(db *gorm.DB, now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var list []model.WebhookDelivery
+	err := db.
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// LeaseWebhookDelivery 用条件更新抢占一条投递：把下次尝试时间推到租约到期，
+// 只有 next_attempt_at 未被别的实例改动时才能抢到，避免多实例重复投递
+func LeaseWebhookDelivery(db *gorm.DB, d *model.WebhookDelivery, until time.Time) (bool, error) {
+	result := db.Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", d.ID, model.WebhookDeliveryPending, d.NextAttemptAt).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func UpdateWebhookDelivery(db *gorm.DB, d *model.WebhookDelivery) error {
+	return db.Save(d).Error
+}
+
+func GetWebhookDelivery(db *gorm.DB, id uint64) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	err := db.First(&d, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID uint64
+	Status         int8
+}
+
+func ListWebhookDeliveries(db *gorm.DB, filter WebhookDeliveryFilter, p pagination.Params) ([]model.WebhookDelivery, int64, error) {
+	var list []model.WebhookDelivery
+	var total int64
+	query := db.Model(&model.WebhookDelivery{})
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	// 同一条件既用于计数又用于查询列表，需开启新会话避免语句互相污染
+	query = query.Session(&gorm.Session{})
+	query.Count(&total)
+	err := p.Apply(query, "created_at", "id", true).Find(&list).Error
+	return list, total, err
+}
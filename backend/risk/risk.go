@@ -0,0 +1,63 @@
+package risk
+
+import "time"
+
+// 风控场景
+const (
+	SceneSend  = "send"
+	SceneClaim = "claim"
+)
+
+// 风控结论，严重程度 Deny > Challenge > Review > Allow。Review 只记录命中的规则并放行，用于观察规则效果
+const (
+	ActionAllow     = "allow"
+	ActionReview    = "review"
+	ActionChallenge = "challenge"
+	ActionDeny      = "deny"
+)
+
+// Request 一次收发红包请求的风控上下文
+type Request struct {
+	Scene       string
+	UserID      uint64
+	AccountAge  time.Duration // 注册至今的时长
+	IP          string
+	DeviceID    string // 客户端通过 X-Device-ID 请求头上报
+	Amount      uint64 // 发红包金额，领红包时为 0
+	RedPacketID uint64 // 领红包时的红包ID
+
+	// 速率计数，由调用方根据决策日志统计
+	UserActions1h     int64 // 该用户 1 小时内同场景请求次数
+	DeviceAccounts24h int64 // 该设备 24 小时内出现过的账户数
+	IPAccounts24h     int64 // 该 IP 24 小时内出现过的账户数
+}
+
+type Decision struct {
+	Action string
+	Rule   string // 命中的规则名，放行时为空
+	Reason string
+}
+
+// Engine 风控引擎，收发红包前调用
+type Engine interface {
+	Evaluate(req Request) Decision
+}
+
+// AllowAll 不做任何拦截，未启用风控时使用
+type AllowAll struct{}
+
+func (AllowAll) Evaluate(Request) Decision {
+	return Decision{Action: ActionAllow}
+}
+
+func severity(action string) int {
+	switch action {
+	case ActionDeny:
+		return 3
+	case ActionChallenge:
+		return 2
+	case ActionReview:
+		return 1
+	}
+	return 0
+}
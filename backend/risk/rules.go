@@ -0,0 +1,87 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"red-packet/config"
+)
+
+// RulesEngine 基于配置的规则引擎：规则内所有条件同时满足才命中，多条命中取最严重的结论
+type RulesEngine struct {
+	rules []config.RiskRule
+}
+
+func NewRulesEngine(rules []config.RiskRule) (*RulesEngine, error) {
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("risk rule name is required")
+		}
+		if r.Action != ActionReview && r.Action != ActionChallenge && r.Action != ActionDeny {
+			return nil, fmt.Errorf("risk rule %s: invalid action %q", r.Name, r.Action)
+		}
+		if r.Scene != "" && r.Scene != "*" && r.Scene != SceneSend && r.Scene != SceneClaim {
+			return nil, fmt.Errorf("risk rule %s: invalid scene %q", r.Name, r.Scene)
+		}
+	}
+	return &RulesEngine{rules: rules}, nil
+}
+
+func (e *RulesEngine) Evaluate(req Request) Decision {
+	decision := Decision{Action: ActionAllow}
+	for _, r := range e.rules {
+		reasons, ok := match(r, req)
+		if !ok || severity(r.Action) <= severity(decision.Action) {
+			continue
+		}
+		decision = Decision{Action: r.Action, Rule: r.Name, Reason: strings.Join(reasons, "; ")}
+	}
+	return decision
+}
+
+// match 返回规则是否命中及命中的条件描述；没有任何条件的规则不命中
+func match(r config.RiskRule, req Request) ([]string, bool) {
+	if r.Scene != "" && r.Scene != "*" && r.Scene != req.Scene {
+		return nil, false
+	}
+
+	var reasons []string
+	if r.AccountAgeLtHours > 0 {
+		if req.AccountAge >= time.Duration(r.AccountAgeLtHours)*time.Hour {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("account age %s < %dh", req.AccountAge.Truncate(time.Minute), r.AccountAgeLtHours))
+	}
+	if r.UserActions1hGte > 0 {
+		if req.UserActions1h < r.UserActions1hGte {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("user actions in 1h %d >= %d", req.UserActions1h, r.UserActions1hGte))
+	}
+	if r.DeviceAccounts24hGte > 0 {
+		if req.DeviceID == "" || req.DeviceAccounts24h < r.DeviceAccounts24hGte {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("device accounts in 24h %d >= %d", req.DeviceAccounts24h, r.DeviceAccounts24hGte))
+	}
+	if r.IPAccounts24hGte > 0 {
+		if req.IPAccounts24h < r.IPAccounts24hGte {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("ip accounts in 24h %d >= %d", req.IPAccounts24h, r.IPAccounts24hGte))
+	}
+	if r.AmountGte > 0 {
+		if req.Amount < r.AmountGte {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("amount %d >= %d", req.Amount, r.AmountGte))
+	}
+	if r.DeviceMissing {
+		if req.DeviceID != "" {
+			return nil, false
+		}
+		reasons = append(reasons, "device id missing")
+	}
+	return reasons, len(reasons) > 0
+}
@@ -0,0 +1,41 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"red-packet/config"
+)
+
+func TestRulesEngineSeverity(t *testing.T) {
+	engine, err := NewRulesEngine([]config.RiskRule{
+		{Name: "new_account", Scene: SceneClaim, AccountAgeLtHours: 24, Action: ActionReview},
+		{Name: "no_device", Scene: "*", DeviceMissing: true, Action: ActionDeny},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		want string
+		rule string
+	}{
+		{"old account", Request{Scene: SceneClaim, AccountAge: 48 * time.Hour, DeviceID: "d"}, ActionAllow, ""},
+		{"review only", Request{Scene: SceneClaim, AccountAge: time.Hour, DeviceID: "d"}, ActionReview, "new_account"},
+		{"deny wins", Request{Scene: SceneClaim, AccountAge: time.Hour}, ActionDeny, "no_device"},
+	}
+	for _, tt := range tests {
+		d := engine.Evaluate(tt.req)
+		if d.Action != tt.want || d.Rule != tt.rule {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.name, d.Action, d.Rule, tt.want, tt.rule)
+		}
+	}
+}
+
+func TestNewRulesEngineRejectsUnknownAction(t *testing.T) {
+	if _, err := NewRulesEngine([]config.RiskRule{{Name: "x", AmountGte: 1, Action: "block"}}); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
@@ -0,0 +1,162 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"red-packet/config"
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/repository"
+	"red-packet/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testDSNEnv 依赖 MySQL 的测试使用的连接串，未设置时跳过；测试数据不会清理，请使用专用的测试库
+const testDSNEnv = "RED_PACKET_TEST_DSN"
+
+var (
+	testDBOnce     sync.Once
+	testDBErr      error
+	testAccountSeq atomic.Int64
+)
+
+func requireTestDB(tb testing.TB) {
+	tb.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		tb.Skip(testDSNEnv + " not set")
+	}
+	testDBOnce.Do(func() {
+		testDBErr = database.Init(&config.Config{Database: config.DatabaseConfig{DSN: dsn}})
+		service.InitUserService("router-test-secret", 1)
+	})
+	if testDBErr != nil {
+		tb.Fatalf("init test database: %v", testDBErr)
+	}
+}
+
+// newTestAccount 注册指定角色的用户并登录，返回用户和 token
+func newTestAccount(tb testing.TB, role string) (*model.User, string) {
+	tb.Helper()
+	name := fmt.Sprintf("r%x_%d", time.Now().UnixNano(), testAccountSeq.Add(1))
+	user, err := service.Register(name, "password")
+	if err != nil {
+		tb.Fatalf("register: %v", err)
+	}
+	if role != model.UserRoleUser {
+		if _, err := repository.UpdateUserRoleByUsername(database.DB, name, role); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	token, err := service.Login(name, "password")
+	if err != nil {
+		tb.Fatalf("login: %v", err)
+	}
+	return user, token
+}
+
+func serve(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func countAuditLogs(tb testing.TB, action string, targetID uint64) int64 {
+	tb.Helper()
+	var n int64
+	err := database.DB.Model(&model.AdminAuditLog{}).
+		Where("action = ? AND target_type = ? AND target_id = ?", action, "user", targetID).Count(&n).Error
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return n
+}
+
+// 后台接口按角色权限放行：未登录 401，普通用户 403，客服只读，管理员可操作；
+// 被拒绝的请求不产生任何变更，管理员的变更操作写审计日志
+func TestAdminRoutesRequirePermission(t *testing.T) {
+	requireTestDB(t)
+	gin.SetMode(gin.TestMode)
+	r := NewRouter()
+
+	target, _ := newTestAccount(t, model.UserRoleUser)
+	_, userToken := newTestAccount(t, model.UserRoleUser)
+	_, supportToken := newTestAccount(t, model.UserRoleSupport)
+	admin, adminToken := newTestAccount(t, model.UserRoleAdmin)
+
+	read := fmt.Sprintf("/api/admin/users/%d", target.ID)
+	freeze := fmt.Sprintf("/api/admin/users/%d/freeze", target.ID)
+	adjust := fmt.Sprintf("/api/admin/users/%d/balance-adjustments", target.ID)
+	adjustBody := `{"direction":1,"amount":100,"remark":"test"}`
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"anonymous read", http.MethodGet, read, "", "", http.StatusUnauthorized},
+		{"user read", http.MethodGet, read, userToken, "", http.StatusForbidden},
+		{"user freeze", http.MethodPost, freeze, userToken, "", http.StatusForbidden},
+		{"user adjust", http.MethodPost, adjust, userToken, adjustBody, http.StatusForbidden},
+		{"support read", http.MethodGet, read, supportToken, "", http.StatusOK},
+		{"support freeze", http.MethodPost, freeze, supportToken, "", http.StatusForbidden},
+		{"support adjust", http.MethodPost, adjust, supportToken, adjustBody, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if w := serve(r, tc.method, tc.path, tc.token, tc.body); w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+	if n := countAuditLogs(t, model.AuditActionFreezeUser, target.ID); n != 0 {
+		t.Fatalf("%d freeze audit logs after forbidden requests", n)
+	}
+	if u, err := repository.GetUserByID(database.DB, target.ID); err != nil || u.Status == model.UserStatusFrozen {
+		t.Fatalf("target frozen by forbidden request: %v", err)
+	}
+
+	if w := serve(r, http.MethodPost, adjust, adminToken, adjustBody); w.Code != http.StatusOK {
+		t.Fatalf("admin adjust status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(r, http.MethodPost, freeze, adminToken, `{"reason":"test"}`); w.Code != http.StatusOK {
+		t.Fatalf("admin freeze status = %d: %s", w.Code, w.Body.String())
+	}
+	for _, action := range []string{model.AuditActionAdjustBalance, model.AuditActionFreezeUser} {
+		var logs []model.AdminAuditLog
+		err := database.DB.Where("action = ? AND target_type = ? AND target_id = ?", action, "user", target.ID).Find(&logs).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != 1 || logs[0].AdminID != admin.ID {
+			t.Errorf("%s: %d audit logs %+v, want one by admin %d", action, len(logs), logs, admin.ID)
+		}
+	}
+
+	// 冻结的管理员视为无角色，后台接口一律拒绝
+	if err := service.SetUserFrozen(service.AdminContext{AdminID: admin.ID}, admin.ID, true, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(r, http.MethodGet, read, adminToken, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("frozen admin read status = %d, want 403", w.Code)
+	}
+}
@@ -1,8 +1,11 @@
 package router
 
 import (
+	"red-packet/dto"
 	"red-packet/handler"
 	"red-packet/middleware"
+	"red-packet/openapi"
+	"red-packet/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,8 +14,11 @@ func NewRouter() *gin.Engine {
 	r := gin.Default()
 
 	r.GET("/ping", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "pong"})
+		c.JSON(200, dto.Pong{Message: "pong"})
 	})
+	r.GET(openapi.SpecPath, openapi.SpecHandler)
+	r.GET(openapi.UIPath, openapi.UIHandler)
+	r.GET(openapi.UIAssetsPath+"/*filepath", openapi.UIAssetHandler)
 
 	api := r.Group("/api")
 	{
@@ -25,6 +31,10 @@ func NewRouter() *gin.Engine {
 		user := api.Group("/user").Use(middleware.Auth())
 		{
 			user.GET("/profile", handler.GetProfile)
+			user.GET("/balances", handler.ListBalances)
+			user.GET("/quota", handler.GetQuota)
+			user.GET("/report", handler.GetReport)
+			user.GET("/statement", handler.ExportStatement)
 			user.GET("/red-packets/sent", handler.GetSentRedPackets)
 			user.GET("/red-packets/received", handler.GetReceivedRedPackets)
 		}
@@ -33,8 +43,64 @@ func NewRouter() *gin.Engine {
 		{
 			rp.POST("", handler.SendRedPacket)
 			rp.POST("/:id/claim", handler.ClaimRedPacket)
+			rp.POST("/:id/claim-tickets", handler.EnqueueClaim)
+			rp.GET("/:id/claim-tickets/:ticket_id", handler.GetClaimTicket)
+			rp.POST("/:id/enter", handler.EnterLottery)
+			rp.POST("/:id/share", handler.CreateShareLink)
+			rp.GET("/:id/share/qr", handler.GetShareQRCode)
 			rp.GET("/:id", handler.GetRedPacketDetail)
 			rp.GET("/:id/records", handler.GetRedPacketRecords)
+			rp.GET("/:id/fairness", handler.GetRedPacketFairness)
+		}
+
+		transfer := api.Group("/transfers").Use(middleware.Auth())
+		{
+			transfer.POST("", handler.CreateTransfer)
+			transfer.GET("", handler.GetTransfers)
+			transfer.POST("/:id/accept", handler.AcceptTransfer)
+			transfer.POST("/:id/reject", handler.RejectTransfer)
+		}
+
+		collection := api.Group("/collections").Use(middleware.Auth())
+		{
+			collection.POST("", handler.CreateCollection)
+			collection.GET("", handler.GetCollections)
+			collection.GET("/:id", handler.GetCollection)
+			collection.POST("/:id/pay", handler.PayCollection)
+			collection.POST("/:id/remind", handler.RemindCollection)
+			collection.POST("/:id/cancel", handler.CancelCollection)
+		}
+
+		rain := api.Group("/rains").Use(middleware.Auth())
+		{
+			rain.GET("/:id", handler.GetRain)
+			rain.POST("/:id/tickets", handler.IssueRainTicket)
+			rain.POST("/:id/claims", handler.SubmitRainClaim)
+			rain.GET("/:id/claims/:claim_id", handler.GetRainClaim)
+		}
+
+		admin := api.Group("/admin").Use(middleware.Auth())
+		{
+			admin.GET("/users", middleware.RequirePermission(service.PermUserRead), handler.AdminSearchUsers)
+			admin.GET("/users/:id", middleware.RequirePermission(service.PermUserRead), handler.AdminGetUser)
+			admin.POST("/users/:id/freeze", middleware.RequirePermission(service.PermUserFreeze), handler.AdminFreezeUser)
+			admin.POST("/users/:id/unfreeze", middleware.RequirePermission(service.PermUserFreeze), handler.AdminUnfreezeUser)
+			admin.POST("/users/:id/balance-adjustments", middleware.RequirePermission(service.PermBalanceAdjust), handler.AdminAdjustBalance)
+			admin.GET("/red-packets/:id", middleware.RequirePermission(service.PermPacketRead), handler.AdminGetRedPacket)
+			admin.POST("/red-packets/:id/refund", middleware.RequirePermission(service.PermPacketRefund), handler.AdminRefundRedPacket)
+			admin.GET("/audit-logs", middleware.RequirePermission(service.PermAuditRead), handler.AdminListAuditLogs)
+			admin.POST("/webhooks", middleware.RequirePermission(service.PermWebhookManage), handler.AdminCreateWebhook)
+			admin.GET("/webhooks", middleware.RequirePermission(service.PermWebhookManage), handler.AdminListWebhooks)
+			admin.DELETE("/webhooks/:id", middleware.RequirePermission(service.PermWebhookManage), handler.AdminDeleteWebhook)
+			admin.GET("/webhook-deliveries", middleware.RequirePermission(service.PermWebhookManage), handler.AdminListWebhookDeliveries)
+			admin.POST("/webhook-deliveries/:id/redeliver", middleware.RequirePermission(service.PermWebhookManage), handler.AdminRedeliverWebhook)
+			admin.GET("/events", middleware.RequirePermission(service.PermEventRead), handler.AdminPullEvents)
+			admin.POST("/campaigns", middleware.RequirePermission(service.PermCampaignManage), handler.AdminCreateCampaign)
+			admin.GET("/campaigns", middleware.RequirePermission(service.PermCampaignRead), handler.AdminListCampaigns)
+			admin.GET("/campaigns/:id", middleware.RequirePermission(service.PermCampaignRead), handler.AdminGetCampaign)
+			admin.POST("/campaigns/:id/fund", middleware.RequirePermission(service.PermCampaignManage), handler.AdminFundCampaign)
+			admin.POST("/campaigns/:id/batches", middleware.RequirePermission(service.PermCampaignManage), handler.AdminIssueCampaignBatch)
+			admin.POST("/campaigns/:id/rains", middleware.RequirePermission(service.PermCampaignManage), handler.AdminCreateRain)
 		}
 	}
 
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"testing"
+
+	"red-packet/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文档与路由保持一致：新增接口需登记到 openapi.Operations
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if missing := openapi.MissingRoutes(NewRouter().Routes()); len(missing) > 0 {
+		t.Errorf("routes missing from openapi spec: %v", missing)
+	}
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"red-packet/service"
+)
+
+// Start 启动后台定时任务，按 interval 轮询
+func Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			activateRedPackets()
+			resumeClaimQueues()
+			drawLotteries()
+			expireRedPackets()
+			returnTransfers()
+			settleCollections()
+			settleRains()
+			dispatchWebhooks()
+			relayDomainEvents()
+		}
+	}()
+}
+
+// StartRainWorkers 启动 n 个红包雨 worker，各自按 interval 轮询排队中的领取请求；
+// 本轮有处理时立即进入下一轮，队列清空后才等待
+func StartRainWorkers(n int, interval time.Duration) {
+	for i := 0; i < n; i++ {
+		go func() {
+			for {
+				processed, err := service.ProcessRainClaims()
+				if err != nil {
+					log.Printf("scheduler: process rain claims failed: %v", err)
+				}
+				if processed == 0 || err != nil {
+					time.Sleep(interval)
+				}
+			}
+		}()
+	}
+}
+
+// activateRedPackets 开启已到时间的定时红包
+func activateRedPackets() {
+	n, err := service.ActivateDueRedPackets()
+	if err != nil {
+		log.Printf("scheduler: activate red packets failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: activated %d red packets", n)
+	}
+}
+
+// resumeClaimQueues 为遗留的排队领取请求恢复 worker，如进程重启或 worker 因数据库异常退出后
+func resumeClaimQueues() {
+	n, err := service.ResumeClaimWorkers()
+	if err != nil {
+		log.Printf("scheduler: resume claim queues failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: resumed %d claim queues", n)
+	}
+}
+
+// drawLotteries 为报名截止的抽奖红包开奖，需在过期结算之前执行
+func drawLotteries() {
+	n, err := service.DrawLotteries()
+	if err != nil {
+		log.Printf("scheduler: draw lotteries failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: drew %d lotteries", n)
+	}
+}
+
+// expireRedPackets 结算过期红包并退回剩余金额
+func expireRedPackets() {
+	n, err := service.ExpireRedPackets()
+	if err != nil {
+		log.Printf("scheduler: expire red packets failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: expired %d red packets", n)
+	}
+}
+
+// returnTransfers 退回超时未收款的转账
+func returnTransfers() {
+	n, err := service.ReturnExpiredTransfers()
+	if err != nil {
+		log.Printf("scheduler: return transfers failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: returned %d transfers", n)
+	}
+}
+
+// settleCollections 到期未收齐的收款把已收部分结算给发起人
+func settleCollections() {
+	n, err := service.SettleExpiredCollections()
+	if err != nil {
+		log.Printf("scheduler: settle collections failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: settled %d expired collections", n)
+	}
+}
+
+// settleRains 结算已结束或已抢完的红包雨，剩余金额退回活动预算
+func settleRains() {
+	n, err := service.SettleRains()
+	if err != nil {
+		log.Printf("scheduler: settle rains failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("scheduler: settled %d rains", n)
+	}
+}
+
+// dispatchWebhooks 先把发件箱事件展开为投递，再发送到期的投递
+func dispatchWebhooks() {
+	if n, err := service.DispatchOutbox(); err != nil {
+		log.Printf("scheduler: dispatch outbox failed: %v", err)
+	} else if n > 0 {
+		log.Printf("scheduler: dispatched %d outbox events", n)
+	}
+
+	ok, dead, err := service.DeliverWebhooks()
+	if err != nil {
+		log.Printf("scheduler: deliver webhooks failed: %v", err)
+	}
+	if dead > 0 {
+		log.Printf("scheduler: %d webhook deliveries moved to dead letter", dead)
+	}
+	if ok > 0 {
+		log.Printf("scheduler: delivered %d webhooks", ok)
+	}
+}
+
+// relayDomainEvents 给新提交的领域事件分配序号，并在开启转发时推送到消息中间件
+func relayDomainEvents() {
+	if _, err := service.SequenceDomainEvents(); err != nil {
+		log.Printf("scheduler: sequence domain events failed: %v", err)
+		return
+	}
+	if _, err := service.RelayDomainEvents(); err != nil {
+		log.Printf("scheduler: relay domain events failed: %v", err)
+	}
+}
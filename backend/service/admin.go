@@ -0,0 +1,300 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/pkg/event"
+	"red-packet/pkg/pagination"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+// 权限点
+const (
+	PermUserRead       = "user:read"
+	PermUserFreeze     = "user:freeze"
+	PermPacketRead     = "packet:read"
+	PermPacketRefund   = "packet:refund"
+	PermBalanceAdjust  = "balance:adjust"
+	PermAuditRead      = "audit:read"
+	PermWebhookManage  = "webhook:manage"
+	PermEventRead      = "event:read"
+	PermCampaignRead   = "campaign:read"
+	PermCampaignManage = "campaign:manage"
+)
+
+// rolePermissions 角色 -> 权限，普通用户没有任何后台权限
+var rolePermissions = map[string][]string{
+	model.UserRoleSupport: {PermUserRead, PermPacketRead, PermAuditRead, PermCampaignRead},
+	model.UserRoleAdmin: {
+		PermUserRead, PermUserFreeze, PermPacketRead, PermPacketRefund,
+		PermBalanceAdjust, PermAuditRead, PermWebhookManage, PermEventRead,
+		PermCampaignRead, PermCampaignManage,
+	},
+}
+
+func HasPermission(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminContext 发起后台操作的管理员信息，写审计日志用
+type AdminContext struct {
+	AdminID uint64
+	IP      string
+}
+
+type AdminRedPacketDetail struct {
+	*model.RedPacket
+	SenderName string
+	Records    []RecordItem
+}
+
+type AdjustBalanceParams struct {
+	UserID    uint64
+	Currency  string
+	Direction int8
+	Amount    uint64
+	Remark    string
+}
+
+// EnsureAdmins 把配置中的用户名提升为管理员，用于初始化后台账号
+func EnsureAdmins(usernames []string) {
+	for _, name := range usernames {
+		n, err := repository.UpdateUserRoleByUsername(database.DB, name, model.UserRoleAdmin)
+		if err != nil {
+			log.Printf("ensure admin %s failed: %v", name, err)
+			continue
+		}
+		if n == 0 {
+			log.Printf("ensure admin %s: user not found or already admin", name)
+		}
+	}
+}
+
+func SearchUsers(keyword string, p pagination.Params) ([]model.User, pagination.Result, error) {
+	list, total, err := repository.SearchUsers(database.DB, keyword, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(u model.User) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+	})
+	return list, result, nil
+}
+
+func GetUserForAdmin(userID uint64) (*model.User, []BalanceItem, error) {
+	user, err := repository.GetUserByID(database.DB, userID)
+	if err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+	balances, err := ListBalances(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, balances, nil
+}
+
+// SetUserFrozen 冻结或解冻账户，冻结后禁止收发红包
+func SetUserFrozen(admin AdminContext, userID uint64, frozen bool, reason string) error {
+	status := int8(model.UserStatusNormal)
+	action := model.AuditActionUnfreezeUser
+	if frozen {
+		status = model.UserStatusFrozen
+		action = model.AuditActionFreezeUser
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := repository.GetUserForUpdate(tx, userID)
+		if err != nil {
+			return errors.New("user not found")
+		}
+		if user.Status == status {
+			return nil
+		}
+		if err := repository.UpdateUserStatus(tx, userID, status); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, action, "user", userID, map[string]interface{}{
+			"from":   user.Status,
+			"to":     status,
+			"reason": reason,
+		})
+	})
+}
+
+func GetRedPacketForAdmin(redPacketID uint64) (*AdminRedPacketDetail, error) {
+	rp, err := repository.GetRedPacketByID(database.DB, redPacketID)
+	if err != nil {
+		return nil, errors.New("red packet not found")
+	}
+	senderName, err := packetSenderName(rp)
+	if err != nil {
+		return nil, err
+	}
+	records, err := repository.GetAllRedPacketRecords(database.DB, redPacketID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := toRecordItems(records)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminRedPacketDetail{RedPacket: rp, SenderName: senderName, Records: items}, nil
+}
+
+// ForceRefundRedPacket 强制退回红包剩余金额给发送者，红包置为已退款
+func ForceRefundRedPacket(admin AdminContext, redPacketID uint64, reason string) (uint64, error) {
+	var refunded uint64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		rp, err := repository.GetRedPacketForUpdate(tx, redPacketID)
+		if err != nil {
+			return errors.New("red packet not found")
+		}
+		if rp.Status == model.RedPacketStatusRefunded || rp.RemainingAmount == 0 {
+			return errors.New("nothing to refund")
+		}
+
+		refunded = rp.RemainingAmount
+		if err := refundRedPacket(tx, rp, model.RedPacketStatusRefunded, "管理员退款"); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionRefundPacket, "red_packet", redPacketID, map[string]interface{}{
+			"amount":   refunded,
+			"currency": rp.Currency,
+			"reason":   reason,
+		})
+	})
+	return refunded, err
+}
+
+// refundRedPacket 把红包剩余金额退回发送者并写退款流水和过期/退款事件，调用方需已持有红包行锁
+func refundRedPacket(tx *gorm.DB, rp *model.RedPacket, status int8, remark string) error {
+	amount := rp.RemainingAmount
+	rp.RemainingAmount = 0
+	rp.RemainingCount = 0
+	rp.Status = status
+	if err := repository.UpdateRedPacket(tx, rp); err != nil {
+		return err
+	}
+	if rp.Type == model.RedPacketTypeAssigned {
+		if err := repository.RefundPendingAllocations(tx, rp.ID); err != nil {
+			return err
+		}
+	}
+
+	eventType := event.RedPacketRefunded
+	if status == model.RedPacketStatusExpired {
+		eventType = event.RedPacketExpired
+	}
+	data := redPacketEventData(rp)
+	data.RefundAmount = amount
+	if err := recordEvent(tx, eventType, data); err != nil {
+		return err
+	}
+	return refundToSender(tx, rp, amount, remark)
+}
+
+// refundToSender 退回金额到发送者余额并写退款流水，金额为 0 时不做任何事；活动红包退回活动预算
+func refundToSender(tx *gorm.DB, rp *model.RedPacket, amount uint64, remark string) error {
+	if amount == 0 {
+		return nil
+	}
+	if rp.CampaignID != nil {
+		return repository.ReturnCampaignBudget(tx, *rp.CampaignID, amount)
+	}
+	redPacketID := rp.ID
+	_, err := credit(tx, rp.SenderID, rp.Currency, amount, model.TransactionTypeRefund, &redPacketID, remark)
+	return err
+}
+
+// AdjustBalance 人工调账，生成 adjust 类型流水并记审计日志
+func AdjustBalance(admin AdminContext, params AdjustBalanceParams) (*model.Transaction, error) {
+	cur, err := currency.Normalize(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if params.Amount == 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if params.Direction != model.TransactionDirectionIn && params.Direction != model.TransactionDirectionOut {
+		return nil, errors.New("invalid direction")
+	}
+
+	var txRecord *model.Transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := repository.GetUserForUpdate(tx, params.UserID); err != nil {
+			return errors.New("user not found")
+		}
+
+		if params.Direction == model.TransactionDirectionIn {
+			err = repository.AddUserBalance(tx, params.UserID, cur, params.Amount)
+		} else {
+			err = repository.DeductUserBalance(tx, params.UserID, cur, params.Amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		balanceAfter, err := repository.GetUserBalance(tx, params.UserID, cur)
+		if err != nil {
+			return err
+		}
+		txRecord = &model.Transaction{
+			UserID:       params.UserID,
+			Type:         model.TransactionTypeAdjust,
+			Direction:    params.Direction,
+			Currency:     cur,
+			Amount:       params.Amount,
+			BalanceAfter: balanceAfter,
+			Remark:       params.Remark,
+		}
+		if err := repository.CreateTransaction(tx, txRecord); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionAdjustBalance, "user", params.UserID, map[string]interface{}{
+			"transaction_id": txRecord.ID,
+			"currency":       cur,
+			"direction":      params.Direction,
+			"amount":         params.Amount,
+			"remark":         params.Remark,
+		})
+	})
+	return txRecord, err
+}
+
+func ListAuditLogs(filter repository.AuditLogFilter, p pagination.Params) ([]model.AdminAuditLog, pagination.Result, error) {
+	list, total, err := repository.ListAuditLogs(database.DB, filter, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(l model.AdminAuditLog) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: l.CreatedAt, ID: l.ID}
+	})
+	return list, result, nil
+}
+
+func writeAuditLog(tx *gorm.DB, admin AdminContext, action, targetType string, targetID uint64, detail map[string]interface{}) error {
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	return repository.CreateAuditLog(tx, &model.AdminAuditLog{
+		AdminID:    admin.AdminID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Detail:     string(b),
+		IP:         admin.IP,
+	})
+}
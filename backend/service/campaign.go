@@ -0,0 +1,278 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/pkg/event"
+	"red-packet/pkg/fairsplit"
+	"red-packet/pkg/pagination"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+const maxCampaignBatchPackets = 500 // 单个批次最多发出的红包个数
+
+type CampaignParams struct {
+	Name         string
+	Currency     string
+	Budget       uint64 // 初始预算，可为 0 之后再注资
+	PerUserLimit uint32
+	StartAt      time.Time
+	EndAt        time.Time
+}
+
+// CampaignBatchParams 一个批次发出 Packets 个相同规格的红包
+type CampaignBatchParams struct {
+	Type         int8   // 1=普通，2=拼手气
+	PacketAmount uint64 // 每个红包的金额
+	PacketCount  uint32 // 每个红包的个数
+	Packets      int
+}
+
+// CampaignReport 活动花费与参与情况。Returned 为过期或退款退回预算的金额
+type CampaignReport struct {
+	PacketCount  int64
+	Issued       uint64
+	Claimed      uint64
+	Outstanding  uint64
+	Returned     uint64
+	ClaimCount   int64
+	Participants int64
+}
+
+func CreateCampaign(admin AdminContext, params CampaignParams) (*model.Campaign, error) {
+	cur, err := currency.Normalize(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if !params.EndAt.After(params.StartAt) {
+		return nil, errors.New("end_at must be after start_at")
+	}
+	if !params.EndAt.After(time.Now()) {
+		return nil, errors.New("end_at must be in the future")
+	}
+
+	c := &model.Campaign{
+		Name:         params.Name,
+		Currency:     cur,
+		Budget:       params.Budget,
+		Balance:      params.Budget,
+		PerUserLimit: params.PerUserLimit,
+		StartAt:      params.StartAt,
+		EndAt:        params.EndAt,
+		CreatedBy:    admin.AdminID,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.CreateCampaign(tx, c); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionCreateCampaign, "campaign", c.ID, map[string]interface{}{
+			"name":     c.Name,
+			"currency": c.Currency,
+			"budget":   c.Budget,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// FundCampaign 向活动预算账户注资
+func FundCampaign(admin AdminContext, campaignID, amount uint64, remark string) (*model.Campaign, error) {
+	if amount == 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	var campaign *model.Campaign
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		c, err := repository.GetCampaignForUpdate(tx, campaignID)
+		if err != nil {
+			return errors.New("campaign not found")
+		}
+		if !time.Now().Before(c.EndAt) {
+			return errors.New("campaign has ended")
+		}
+		c.Budget += amount
+		c.Balance += amount
+		if err := repository.UpdateCampaign(tx, c); err != nil {
+			return err
+		}
+		campaign = c
+		return writeAuditLog(tx, admin, model.AuditActionFundCampaign, "campaign", c.ID, map[string]interface{}{
+			"amount":   amount,
+			"currency": c.Currency,
+			"remark":   remark,
+		})
+	})
+	return campaign, err
+}
+
+// IssueCampaignBatch 从活动预算发出一批红包：锁住活动行后一次性扣减整批金额，
+// 余额不足时整批失败，不会出现部分发出或预算透支
+func IssueCampaignBatch(admin AdminContext, campaignID uint64, params CampaignBatchParams) ([]model.RedPacket, error) {
+	if params.Type != model.RedPacketTypeNormal && params.Type != model.RedPacketTypeLucky {
+		return nil, errors.New("campaign packets must be normal or lucky")
+	}
+	if params.Packets < 1 || params.Packets > maxCampaignBatchPackets {
+		return nil, errors.New("packets must be between 1 and 500")
+	}
+	if params.PacketCount == 0 || params.PacketAmount < uint64(params.PacketCount) {
+		return nil, errors.New("total amount must be >= total count (min 1 fen per person)")
+	}
+	if params.PacketAmount > math.MaxUint64/uint64(params.Packets) {
+		return nil, errors.New("batch amount is too large")
+	}
+	batchAmount := params.PacketAmount * uint64(params.Packets)
+
+	var packets []model.RedPacket
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		c, err := repository.GetCampaignForUpdate(tx, campaignID)
+		if err != nil {
+			return errors.New("campaign not found")
+		}
+		now := time.Now()
+		if !now.Before(c.EndAt) {
+			return errors.New("campaign has ended")
+		}
+		if c.Balance < batchAmount {
+			return errors.New("campaign budget exhausted")
+		}
+		c.Balance -= batchAmount
+		if err := repository.UpdateCampaign(tx, c); err != nil {
+			return err
+		}
+
+		// 活动未开始时红包先置为未开启，到开始时间由定时任务开启；活动结束即过期，剩余退回预算
+		openAt, status := now, int8(model.RedPacketStatusActive)
+		if now.Before(c.StartAt) {
+			openAt, status = c.StartAt, model.RedPacketStatusPending
+		}
+		ids := make([]uint64, 0, params.Packets)
+		for i := 0; i < params.Packets; i++ {
+			rp := &model.RedPacket{
+				CampaignID:      &c.ID,
+				Type:            params.Type,
+				Currency:        c.Currency,
+				TotalAmount:     params.PacketAmount,
+				TotalCount:      params.PacketCount,
+				RemainingAmount: params.PacketAmount,
+				RemainingCount:  params.PacketCount,
+				Status:          status,
+				OpenAt:          openAt,
+				ExpiredAt:       c.EndAt,
+			}
+			if params.Type == model.RedPacketTypeLucky {
+				if rp.Seed, err = fairsplit.NewSeed(); err != nil {
+					return err
+				}
+				if rp.SeedHash, err = fairsplit.Commitment(rp.Seed); err != nil {
+					return err
+				}
+			}
+			if err := repository.CreateRedPacket(tx, rp); err != nil {
+				return err
+			}
+			if err := recordEvent(tx, event.RedPacketSent, redPacketEventData(rp)); err != nil {
+				return err
+			}
+			packets = append(packets, *rp)
+			ids = append(ids, rp.ID)
+		}
+
+		return writeAuditLog(tx, admin, model.AuditActionIssueCampaign, "campaign", c.ID, map[string]interface{}{
+			"type":          params.Type,
+			"packet_amount": params.PacketAmount,
+			"packet_count":  params.PacketCount,
+			"amount":        batchAmount,
+			"currency":      c.Currency,
+			"red_packets":   ids,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return packets, nil
+}
+
+func ListCampaigns(p pagination.Params) ([]model.Campaign, pagination.Result, error) {
+	list, total, err := repository.ListCampaigns(database.DB, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(c model.Campaign) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	return list, result, nil
+}
+
+// GetCampaignReport 活动详情及花费、参与人数统计
+func GetCampaignReport(campaignID uint64) (*model.Campaign, *CampaignReport, error) {
+	c, err := repository.GetCampaignByID(database.DB, campaignID)
+	if err != nil {
+		return nil, nil, errors.New("campaign not found")
+	}
+	packets, err := repository.GetCampaignPacketStats(database.DB, campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	claims, err := repository.GetCampaignClaimStats(database.DB, campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &CampaignReport{
+		PacketCount:  packets.PacketCount,
+		Issued:       packets.Issued,
+		Claimed:      claims.Claimed,
+		Outstanding:  packets.Outstanding,
+		ClaimCount:   claims.ClaimCount,
+		Participants: claims.Participants,
+	}
+	if packets.Issued > claims.Claimed+packets.Outstanding {
+		report.Returned = packets.Issued - claims.Claimed - packets.Outstanding
+	}
+	return c, report, nil
+}
+
+// checkCampaignClaim 活动红包只能在活动期内领取，且受每人领取个数限制。
+// 调用方已通过 receiveUsage 锁住领取者的用户行（未配置领取限额时同样加锁），同一用户并发领取同一活动的不同红包时计数不会超限
+func checkCampaignClaim(tx *gorm.DB, rp *model.RedPacket, userID uint64) error {
+	if rp.CampaignID == nil {
+		return nil
+	}
+	c, err := repository.GetCampaignByID(tx, *rp.CampaignID)
+	if err != nil {
+		return err
+	}
+	if !c.Active(time.Now()) {
+		return errors.New("campaign is not active")
+	}
+	if c.PerUserLimit == 0 {
+		return nil
+	}
+	claimed, err := repository.CountCampaignClaims(tx, c.ID, userID)
+	if err != nil {
+		return err
+	}
+	if claimed >= int64(c.PerUserLimit) {
+		return errors.New("campaign claim limit reached")
+	}
+	return nil
+}
+
+// packetSenderName 红包详情中展示的发送者：用户红包为用户名，活动红包为活动名称
+func packetSenderName(rp *model.RedPacket) (string, error) {
+	if rp.CampaignID == nil {
+		return getUsername(rp.SenderID)
+	}
+	c, err := repository.GetCampaignByID(database.DB, *rp.CampaignID)
+	if err != nil {
+		return "", err
+	}
+	return c.Name, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/repository"
+)
+
+// newTestCampaign 创建进行中的活动并发出 packets 个 100 分、count 份的普通红包
+func newTestCampaign(tb testing.TB, perUserLimit uint32, packets int, count uint32) (*model.Campaign, []model.RedPacket) {
+	tb.Helper()
+	admin := AdminContext{AdminID: newTestUser(tb, 0).ID}
+	now := time.Now()
+	c, err := CreateCampaign(admin, CampaignParams{
+		Name:         "test campaign",
+		Budget:       uint64(packets) * 100,
+		PerUserLimit: perUserLimit,
+		StartAt:      now.Add(-time.Minute),
+		EndAt:        now.Add(time.Hour),
+	})
+	if err != nil {
+		tb.Fatalf("create campaign: %v", err)
+	}
+	list, err := IssueCampaignBatch(admin, c.ID, CampaignBatchParams{
+		Type:         model.RedPacketTypeNormal,
+		PacketAmount: 100,
+		PacketCount:  count,
+		Packets:      packets,
+	})
+	if err != nil {
+		tb.Fatalf("issue batch: %v", err)
+	}
+	return c, list
+}
+
+func campaignBalance(tb testing.TB, id uint64) uint64 {
+	tb.Helper()
+	c, err := repository.GetCampaignByID(database.DB, id)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c.Balance
+}
+
+// 同一用户并发领取同一活动的多个红包，成功个数不超过每人上限
+func TestCampaignPerUserLimitUnderConcurrentClaims(t *testing.T) {
+	requireTestDB(t)
+	const perUserLimit, packets = 2, 6
+	c, list := newTestCampaign(t, perUserLimit, packets, 1)
+	user := newTestUser(t, 0)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for _, rp := range list {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+			_, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: id, ReceiverID: user.ID})
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case err.Error() != "campaign claim limit reached":
+				t.Errorf("claim %d: %v", id, err)
+			}
+		}(rp.ID)
+	}
+	wg.Wait()
+
+	if succeeded != perUserLimit {
+		t.Errorf("%d claims succeeded, want %d", succeeded, perUserLimit)
+	}
+	claimed, err := repository.CountCampaignClaims(database.DB, c.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed != perUserLimit {
+		t.Errorf("%d campaign records, want %d", claimed, perUserLimit)
+	}
+	if b := balanceOf(t, user.ID); b != perUserLimit*100 {
+		t.Errorf("balance = %d, want %d", b, perUserLimit*100)
+	}
+}
+
+// 活动红包过期时剩余金额退回活动预算，而不是记到某个用户的钱包
+func TestExpiredCampaignPacketReturnsToBudget(t *testing.T) {
+	requireTestDB(t)
+	c, list := newTestCampaign(t, 0, 1, 4)
+	rp := list[0]
+	if b := campaignBalance(t, c.ID); b != 0 {
+		t.Fatalf("balance after issue = %d, want 0", b)
+	}
+	if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: newTestUser(t, 0).ID}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	if err := database.DB.Model(&model.RedPacket{}).Where("id = ?", rp.ID).
+		Update("expired_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	for {
+		n, err := ExpireRedPackets()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	got, err := repository.GetRedPacketByID(database.DB, rp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.RedPacketStatusExpired {
+		t.Errorf("status = %d, want expired", got.Status)
+	}
+	if b := campaignBalance(t, c.ID); b != 75 {
+		t.Errorf("campaign balance = %d, want 75", b)
+	}
+	if n := countRows(t, &model.Transaction{}, "related_id = ? AND type = ?", rp.ID, model.TransactionTypeRefund); n != 0 {
+		t.Errorf("%d refund transactions written for a campaign packet", n)
+	}
+	_, report, err := GetCampaignReport(c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Claimed != 25 || report.Returned != 75 || report.Outstanding != 0 {
+		t.Errorf("report claimed %d returned %d outstanding %d, want 25/75/0", report.Claimed, report.Returned, report.Outstanding)
+	}
+}
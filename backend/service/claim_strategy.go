@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+// 领取红包时的并发控制策略
+const (
+	// ClaimPessimistic 事务开始即 SELECT ... FOR UPDATE 锁住红包行，并发领取全部排队等锁
+	ClaimPessimistic = "pessimistic"
+	// ClaimOptimistic 不加锁读取，写回时比对版本号，冲突时整个事务重试
+	ClaimOptimistic = "optimistic"
+	// ClaimConditional 不加锁读取，用 UPDATE ... WHERE remaining_count > 0 原子扣减，
+	// 不依赖读到的剩余值；拼手气红包的金额取决于领取顺序，仍按 optimistic 处理
+	ClaimConditional = "conditional"
+)
+
+var claimStrategy = ClaimPessimistic
+
+// InitClaimStrategy 为空时使用 pessimistic
+func InitClaimStrategy(name string) error {
+	switch name {
+	case "":
+		claimStrategy = ClaimPessimistic
+	case ClaimPessimistic, ClaimOptimistic, ClaimConditional:
+		claimStrategy = name
+	default:
+		return fmt.Errorf("unsupported claim strategy: %s", name)
+	}
+	return nil
+}
+
+// loadRedPacketForClaim 按策略读取待领取的红包，只有 pessimistic 加行锁。
+// 其他策略的普通读会建立事务快照，先锁领取者的用户行，之后的重复领取检查和活动计数
+// 才能看到同一用户刚提交的领取
+func loadRedPacketForClaim(tx *gorm.DB, id, receiverID uint64) (*model.RedPacket, error) {
+	if claimStrategy == ClaimPessimistic {
+		return repository.GetRedPacketForUpdate(tx, id)
+	}
+	if _, err := repository.GetUserForUpdate(tx, receiverID); err != nil {
+		return nil, err
+	}
+	return repository.GetRedPacketByID(tx, id)
+}
+
+// takeShare 按策略扣减红包剩余并写回，返回实际的领取顺序和金额。
+// 非加锁策略下读到的可能是旧值，写回未命中时返回 database.ErrConflict 由外层重试
+func takeShare(tx *gorm.DB, rp *model.RedPacket, amount uint64) (uint32, uint64, error) {
+	if claimStrategy == ClaimConditional && rp.Type != model.RedPacketTypeLucky {
+		updated, err := repository.TakeRedPacketShare(tx, rp.ID, amount)
+		if err != nil {
+			return 0, 0, err
+		}
+		if updated == nil {
+			return 0, 0, database.ErrConflict
+		}
+		*rp = *updated
+		// 实际拿到最后一份时，普通红包的金额为总额减去前面各份
+		if rp.RemainingCount == 0 && rp.Type == model.RedPacketTypeNormal {
+			amount = rp.TotalAmount - rp.TotalAmount/uint64(rp.TotalCount)*uint64(rp.TotalCount-1)
+		}
+		return rp.TotalCount - rp.RemainingCount - 1, amount, nil
+	}
+
+	seq := rp.TotalCount - rp.RemainingCount
+	rp.RemainingAmount -= amount
+	rp.RemainingCount--
+	if rp.RemainingCount == 0 {
+		rp.Status = model.RedPacketStatusEmpty
+	}
+	if claimStrategy == ClaimPessimistic {
+		return seq, amount, repository.UpdateRedPacket(tx, rp)
+	}
+	ok, err := repository.CompareAndSwapRedPacket(tx, rp)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return 0, 0, database.ErrConflict
+	}
+	return seq, amount, nil
+}
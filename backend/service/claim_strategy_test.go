@@ -0,0 +1,70 @@
+package service
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"red-packet/database"
+	"red-packet/model"
+
+	"gorm.io/gorm"
+)
+
+// BenchmarkClaimStrategies 同一个红包被并发领取时三种策略的吞吐，需要 MySQL：
+//
+//	RED_PACKET_TEST_DSN=... go test ./service -run '^$' -bench ClaimStrategies -cpu 8,32
+//
+// 每次迭代是一次完整的领取事务（含冲突重试），只计领取部分，不含风控和口令校验
+func BenchmarkClaimStrategies(b *testing.B) {
+	requireTestDB(b)
+	for _, strategy := range []string{ClaimPessimistic, ClaimOptimistic, ClaimConditional} {
+		for _, typ := range []int8{model.RedPacketTypeNormal, model.RedPacketTypeLucky} {
+			name := strategy + "/normal"
+			if typ == model.RedPacketTypeLucky {
+				name = strategy + "/lucky"
+			}
+			b.Run(name, func(b *testing.B) {
+				benchmarkClaim(b, strategy, typ)
+			})
+		}
+	}
+}
+
+func benchmarkClaim(b *testing.B, strategy string, typ int8) {
+	prev := claimStrategy
+	claimStrategy = strategy
+	defer func() { claimStrategy = prev }()
+
+	sender := newTestUser(b, uint64(b.N)*100)
+	receivers := make([]uint64, b.N)
+	for i := range receivers {
+		receivers[i] = newTestUser(b, 0).ID
+	}
+	rp, err := SendRedPacket(SendRedPacketParams{
+		SenderID:    sender.ID,
+		Type:        typ,
+		TotalAmount: uint64(b.N) * 100,
+		TotalCount:  uint32(b.N),
+	})
+	if err != nil {
+		b.Fatalf("send: %v", err)
+	}
+
+	var next, failed atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			params := ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: receivers[next.Add(1)-1]}
+			err := database.Transaction(func(tx *gorm.DB) error {
+				_, _, err := claimRedPacket(tx, params)
+				return err
+			})
+			if err != nil {
+				failed.Add(1)
+			}
+		}
+	})
+	b.StopTimer()
+	// 重试用尽的领取记为失败，失败率反映该策略在这一并发度下的冲突程度
+	b.ReportMetric(float64(failed.Load())/float64(b.N), "failed/op")
+}
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/event"
+	"red-packet/repository"
+	"red-packet/risk"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxClaimQueue         = 1000 // 单个红包排队请求上限，超出直接拒绝
+	claimTicketBatch      = 50
+	maxClaimTicketWait    = 30 * time.Second
+	claimTicketRecheck    = 2 * time.Second // 长轮询期间查库的间隔，本进程处理完成的请求会立即唤醒
+	claimLeaseTTL         = 30 * time.Second
+	resumeClaimQueueLimit = 100
+	maxClaimReasonLen     = 100 // 与 claim_tickets.reason 列宽一致
+)
+
+var (
+	// claimWorkers 本进程内正在处理队列的红包，避免同一进程重复启动；跨实例由 claim_queues 租约保证只有一个 worker
+	claimWorkers sync.Map
+	// claimWaiters 长轮询中的请求ID → 处理完成时关闭的通道
+	claimWaiters sync.Map
+	// claimWorkerID 本进程的租约持有者标识
+	claimWorkerID = newClaimWorkerID()
+)
+
+func newClaimWorkerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// ClaimTicketResult 排队领取请求及红包币种
+type ClaimTicketResult struct {
+	*model.ClaimTicket
+	Currency string
+}
+
+// EnqueueClaim 排队领取：只做不加锁的预检查后入队并立即返回，
+// 由该红包的 worker 按入队顺序逐个执行与同步领取相同的事务。同一用户重复提交时返回已有的请求
+func EnqueueClaim(params ClaimRedPacketParams) (*ClaimTicketResult, error) {
+	if err := checkUserActive(params.ReceiverID); err != nil {
+		return nil, err
+	}
+	if err := checkRisk(risk.SceneClaim, params.ReceiverID, params.Meta, 0, params.RedPacketID); err != nil {
+		return nil, err
+	}
+
+	rp, err := repository.GetRedPacketByID(database.DB, params.RedPacketID)
+	if err != nil {
+		return nil, errors.New("red packet not found")
+	}
+	// 预检查读的是快照，只用于尽早拒绝明显失败的请求，最终以 worker 加锁后的校验为准
+	switch {
+	case rp.Type == model.RedPacketTypeLottery:
+		return nil, errors.New("lottery red packet requires entry")
+	case rp.Status == model.RedPacketStatusEmpty:
+		return nil, errors.New("red packet is empty")
+	case rp.Status == model.RedPacketStatusPending && time.Now().Before(rp.OpenAt):
+		return nil, errors.New("red packet is not open yet")
+	case rp.Status != model.RedPacketStatusActive && rp.Status != model.RedPacketStatusPending,
+		time.Now().After(rp.ExpiredAt):
+		return nil, errors.New("red packet is expired")
+	}
+	if rp.RequireShare {
+		if err := VerifyShareToken(rp.PublicID, params.ShareToken); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := repository.GetRedPacketRecord(database.DB, rp.ID, params.ReceiverID); err == nil {
+		return nil, errors.New("already claimed")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if t, err := repository.GetPendingClaimTicket(database.DB, rp.ID, params.ReceiverID); err == nil {
+		return &ClaimTicketResult{ClaimTicket: t, Currency: rp.Currency}, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	// 口令在入队时校验（与同步领取相同地占用尝试次数），请求中只记录校验通过，不保存明文
+	if err := verifyClaimSecret(&params); err != nil {
+		return nil, err
+	}
+
+	t := &model.ClaimTicket{
+		RedPacketID:    rp.ID,
+		ReceiverID:     params.ReceiverID,
+		SecretVerified: params.secretVerified,
+		Status:         model.ClaimTicketPending,
+	}
+	if rp.RequireShare {
+		t.ShareToken = params.ShareToken
+	}
+	if err := repository.EnsureClaimQueue(database.DB, rp.ID); err != nil {
+		return nil, err
+	}
+	var existing *model.ClaimTicket
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 排队数以条件更新原子占位，计数行锁同时串行化同一红包的入队，锁定后再查重复请求
+		ok, err := repository.IncrClaimQueue(tx, rp.ID, maxClaimQueue)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("claim queue is full")
+		}
+		if existing, err = repository.GetPendingClaimTicket(tx, rp.ID, params.ReceiverID); err == nil {
+			return errClaimTicketExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return repository.CreateClaimTicket(tx, t)
+	})
+	if errors.Is(err, errClaimTicketExists) {
+		return &ClaimTicketResult{ClaimTicket: existing, Currency: rp.Currency}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	startClaimWorker(rp.ID)
+	return &ClaimTicketResult{ClaimTicket: t, Currency: rp.Currency}, nil
+}
+
+// errClaimTicketExists 并发提交时已有排队中的请求，回滚本次占位并返回已有请求
+var errClaimTicketExists = errors.New("claim ticket exists")
+
+// GetClaimTicket 查询排队领取结果；wait > 0 时在请求仍排队期间等待，最多 30 秒。
+// 本进程处理完成时立即唤醒，其他实例处理的请求按 claimTicketRecheck 间隔查库
+func GetClaimTicket(redPacketID, ticketID, userID uint64, wait time.Duration) (*ClaimTicketResult, error) {
+	rp, err := repository.GetRedPacketByID(database.DB, redPacketID)
+	if err != nil {
+		return nil, errors.New("red packet not found")
+	}
+	if wait > maxClaimTicketWait {
+		wait = maxClaimTicketWait
+	}
+	var done chan struct{}
+	if wait > 0 {
+		// 先登记再查库：之后才完成的请求一定会关闭通道
+		ch, _ := claimWaiters.LoadOrStore(ticketID, make(chan struct{}))
+		done = ch.(chan struct{})
+		defer claimWaiters.CompareAndDelete(ticketID, done)
+	}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	for {
+		t, err := repository.GetClaimTicket(database.DB, redPacketID, ticketID)
+		if err != nil || t.ReceiverID != userID {
+			return nil, errors.New("claim ticket not found")
+		}
+		if t.Status != model.ClaimTicketPending || wait <= 0 {
+			return &ClaimTicketResult{ClaimTicket: t, Currency: rp.Currency}, nil
+		}
+		select {
+		case <-done:
+			done = nil // 已关闭，之后只按间隔查库
+		case <-time.After(claimTicketRecheck):
+		case <-timeout.C:
+			wait = 0 // 超时前再查一次，返回最新状态
+		}
+	}
+}
+
+// notifyClaimWaiters 唤醒等待该请求结果的长轮询
+func notifyClaimWaiters(ticketID uint64) {
+	if ch, ok := claimWaiters.LoadAndDelete(ticketID); ok {
+		close(ch.(chan struct{}))
+	}
+}
+
+// ResumeClaimWorkers 为仍有排队请求但本进程没有 worker 的红包启动 worker（如重启后），返回启动的个数
+func ResumeClaimWorkers() (int, error) {
+	ids, err := repository.GetRedPacketIDsWithPendingTickets(database.DB, resumeClaimQueueLimit)
+	if err != nil {
+		return 0, err
+	}
+	started := 0
+	for _, id := range ids {
+		if startClaimWorker(id) {
+			started++
+		}
+	}
+	return started, nil
+}
+
+// startClaimWorker 红包没有 worker 时启动一个，返回是否新启动
+func startClaimWorker(redPacketID uint64) bool {
+	if _, running := claimWorkers.LoadOrStore(redPacketID, struct{}{}); running {
+		return false
+	}
+	go drainClaimQueue(redPacketID)
+	return true
+}
+
+// drainClaimQueue 持有红包的队列租约时按入队顺序处理排队请求，每批前续期，队列清空后释放租约退出。
+// 租约被其他实例持有时直接退出，由持有者处理
+func drainClaimQueue(redPacketID uint64) {
+	stop := func() {
+		if err := repository.ReleaseClaimQueueLease(database.DB, redPacketID, claimWorkerID); err != nil {
+			log.Printf("claim queue %d: release lease failed: %v", redPacketID, err)
+		}
+		claimWorkers.Delete(redPacketID)
+	}
+	for {
+		held, err := repository.AcquireClaimQueueLease(database.DB, redPacketID, claimWorkerID, time.Now(), claimLeaseTTL)
+		if err != nil {
+			log.Printf("claim queue %d: acquire lease failed: %v", redPacketID, err)
+		}
+		if !held {
+			claimWorkers.Delete(redPacketID)
+			return
+		}
+		ids, err := repository.GetPendingClaimTicketIDs(database.DB, redPacketID, claimTicketBatch)
+		if err != nil {
+			log.Printf("claim queue %d: load tickets failed: %v", redPacketID, err)
+			stop()
+			return
+		}
+		if len(ids) == 0 {
+			stop()
+			// 释放租约前其他实例可能刚有请求入队且因租约被占未能启动 worker，再确认一次
+			if n, err := repository.CountPendingClaimTickets(database.DB, redPacketID); err == nil && n > 0 {
+				startClaimWorker(redPacketID)
+			}
+			return
+		}
+		for _, id := range ids {
+			if err := processClaimTicket(redPacketID, id); err != nil {
+				// 数据库异常时停止，剩余请求由调度任务稍后恢复
+				log.Printf("claim queue %d: process ticket %d failed: %v", redPacketID, id, err)
+				stop()
+				return
+			}
+		}
+	}
+}
+
+// claimFailures 领取的业务失败，排队请求按这些原因记为失败；其他错误（数据库、驱动异常）原样返回，
+// worker 停止处理，请求保持排队，稍后由调度任务恢复后重试
+var claimFailures = map[string]bool{
+	"user not found":                      true,
+	"account is frozen":                   true,
+	"red packet not found":                true,
+	"lottery red packet requires entry":   true,
+	"red packet is not open yet":          true,
+	"red packet is empty":                 true,
+	"red packet is expired":               true,
+	"invalid share token":                 true,
+	"share link expired":                  true,
+	"daily receive amount limit exceeded": true,
+	"daily receive count limit exceeded":  true,
+	"already claimed":                     true,
+	"campaign is not active":              true,
+	"campaign claim limit reached":        true,
+	"wrong secret":                        true,
+	"not a designated receiver":           true,
+	"red packet is busy":                  true,
+}
+
+// processClaimTicket 先锁住请求行再执行领取事务：租约过期交接等情况下两个实例同时处理同一红包时，
+// 已被处理的请求会被跳过。领取失败时单独提交失败状态，原因与同步领取的错误信息相同
+func processClaimTicket(redPacketID, ticketID uint64) error {
+	t, err := repository.GetClaimTicket(database.DB, redPacketID, ticketID)
+	if err != nil {
+		return err
+	}
+	if t.Status != model.ClaimTicketPending {
+		return nil
+	}
+	defer notifyClaimWaiters(ticketID)
+	params := ClaimRedPacketParams{
+		RedPacketID:    t.RedPacketID,
+		ReceiverID:     t.ReceiverID,
+		ShareToken:     t.ShareToken,
+		secretVerified: t.SecretVerified,
+	}
+	claimErr := checkUserActive(t.ReceiverID)
+	if claimErr == nil {
+		opened := false
+		err = database.Transaction(func(tx *gorm.DB) error {
+			claimErr, opened = nil, false
+			t, err := repository.GetClaimTicketForUpdate(tx, ticketID)
+			if err != nil {
+				return err
+			}
+			if t.Status != model.ClaimTicketPending {
+				return nil
+			}
+			amount, ok, err := claimRedPacket(tx, params)
+			if err != nil {
+				if claimFailures[err.Error()] {
+					claimErr = err
+				}
+				return err
+			}
+			opened = ok
+			finishClaimTicket(t, model.ClaimTicketSucceeded, amount, "")
+			if err := repository.UpdateClaimTicket(tx, t); err != nil {
+				return err
+			}
+			return repository.DecrClaimQueue(tx, redPacketID)
+		})
+		if err == nil && opened {
+			event.Publish(event.Event{Type: event.RedPacketOpened, RedPacketID: redPacketID})
+		}
+		if claimErr == nil && database.IsRetryable(err) {
+			// 并发冲突重试用尽，与同步领取一样记为繁忙
+			claimErr = busyIfRetryable(err)
+		}
+	}
+	if claimErr == nil {
+		return err
+	}
+	if !claimFailures[claimErr.Error()] {
+		return claimErr
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := repository.GetClaimTicketForUpdate(tx, ticketID)
+		if err != nil {
+			return err
+		}
+		if t.Status != model.ClaimTicketPending {
+			return nil
+		}
+		finishClaimTicket(t, model.ClaimTicketFailed, 0, claimErr.Error())
+		if err := repository.UpdateClaimTicket(tx, t); err != nil {
+			return err
+		}
+		return repository.DecrClaimQueue(tx, redPacketID)
+	})
+}
+
+// finishClaimTicket 写入处理结果并清空暂存的分享令牌
+func finishClaimTicket(t *model.ClaimTicket, status int8, amount uint64, reason string) {
+	if len(reason) > maxClaimReasonLen {
+		reason = reason[:maxClaimReasonLen]
+	}
+	now := time.Now()
+	t.Status = status
+	t.Amount = amount
+	t.Reason = reason
+	t.ShareToken = ""
+	t.ProcessedAt = &now
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/repository"
+)
+
+// 并发占位不会超过上限，租约同一时刻只归一个持有者
+func TestClaimQueueCounterAndLease(t *testing.T) {
+	requireTestDB(t)
+	id := uint64(time.Now().UnixNano())
+	if err := repository.EnsureClaimQueue(database.DB, id); err != nil {
+		t.Fatal(err)
+	}
+
+	const limit = 5
+	var taken atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repository.IncrClaimQueue(database.DB, id, limit)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := taken.Load(); n != limit {
+		t.Fatalf("%d slots taken, want %d", n, limit)
+	}
+
+	now := time.Now()
+	if held, err := repository.AcquireClaimQueueLease(database.DB, id, "a", now, time.Minute); err != nil || !held {
+		t.Fatalf("first acquire: held=%v err=%v", held, err)
+	}
+	if held, err := repository.AcquireClaimQueueLease(database.DB, id, "a", now, time.Minute); err != nil || !held {
+		t.Fatalf("renew: held=%v err=%v", held, err)
+	}
+	if held, err := repository.AcquireClaimQueueLease(database.DB, id, "b", now, time.Minute); err != nil || held {
+		t.Fatalf("second owner acquired live lease: held=%v err=%v", held, err)
+	}
+	if held, err := repository.AcquireClaimQueueLease(database.DB, id, "b", now.Add(2*time.Minute), time.Minute); err != nil || !held {
+		t.Fatalf("expired lease not taken over: held=%v err=%v", held, err)
+	}
+	if err := repository.ReleaseClaimQueueLease(database.DB, id, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := repository.AcquireClaimQueueLease(database.DB, id, "a", now.Add(2*time.Minute), time.Minute); err != nil || held {
+		t.Fatalf("release by former owner freed the lease: held=%v err=%v", held, err)
+	}
+}
+
+// 领取事务遇到数据库错误时原样返回，请求保持排队且不记录错误文本；恢复后重新处理即可成功。
+// 业务失败（已领取）才记为失败
+func TestProcessClaimTicketKeepsPendingOnDBError(t *testing.T) {
+	requireTestDB(t)
+	sender := newTestUser(t, 300)
+	receiver := newTestUser(t, 0)
+	rp, err := SendRedPacket(SendRedPacketParams{SenderID: sender.ID, Type: model.RedPacketTypeNormal, TotalAmount: 300, TotalCount: 3})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	enqueue := func() *model.ClaimTicket {
+		t.Helper()
+		ticket := &model.ClaimTicket{RedPacketID: rp.ID, ReceiverID: receiver.ID, Status: model.ClaimTicketPending}
+		if _, err := repository.IncrClaimQueue(database.DB, rp.ID, maxClaimQueue); err != nil {
+			t.Fatal(err)
+		}
+		if err := repository.CreateClaimTicket(database.DB, ticket); err != nil {
+			t.Fatal(err)
+		}
+		return ticket
+	}
+	reload := func(id uint64) *model.ClaimTicket {
+		t.Helper()
+		got, err := repository.GetClaimTicket(database.DB, rp.ID, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	if err := repository.EnsureClaimQueue(database.DB, rp.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ticket := enqueue()
+	failCreatesOn(t, "red_packet_records")
+	if err := processClaimTicket(rp.ID, ticket.ID); !errors.Is(err, errInjected) {
+		t.Fatalf("process err = %v, want injected failure", err)
+	}
+	if got := reload(ticket.ID); got.Status != model.ClaimTicketPending || got.Reason != "" {
+		t.Fatalf("ticket after db error: status %d reason %q, want pending", got.Status, got.Reason)
+	}
+
+	failCreateOn.Store("")
+	if err := processClaimTicket(rp.ID, ticket.ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got := reload(ticket.ID); got.Status != model.ClaimTicketSucceeded || got.Amount != 100 {
+		t.Fatalf("ticket after retry: status %d amount %d, want succeeded 100", got.Status, got.Amount)
+	}
+
+	again := enqueue()
+	if err := processClaimTicket(rp.ID, again.ID); err != nil {
+		t.Fatalf("process duplicate: %v", err)
+	}
+	if got := reload(again.ID); got.Status != model.ClaimTicketFailed || got.Reason != "already claimed" {
+		t.Fatalf("duplicate ticket: status %d reason %q, want failed already claimed", got.Status, got.Reason)
+	}
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/pkg/pagination"
+	"red-packet/repository"
+	"red-packet/risk"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxCollectionParticipants = 100
+	defaultCollectionTTL      = 7 * 24 * time.Hour
+	maxCollectionTTL          = 30 * 24 * time.Hour
+	collectionRemindInterval  = time.Hour // 两次催收提醒的最小间隔
+	settleBatchLimit          = 100
+)
+
+type CollectionParams struct {
+	InitiatorID  uint64
+	Title        string
+	Currency     string
+	TotalAmount  uint64   // 总额，按人数均分；与 PerPerson 二选一
+	PerPerson    uint64   // 每人金额
+	Participants []string // 参与人用户名，不含发起人
+	TTL          time.Duration
+}
+
+type CollectionDetail struct {
+	*model.Collection
+	InitiatorName string
+	Shares        []CollectionShareItem
+}
+
+type CollectionShareItem struct {
+	UserID   uint64
+	Username string
+	Amount   uint64
+	Status   int8
+	PaidAt   *time.Time
+}
+
+// CreateCollection 发起收款：总额均分时除不尽的最小单位由排在前面的参与人多付 1
+func CreateCollection(params CollectionParams) (*CollectionDetail, error) {
+	cur, err := currency.Normalize(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if (params.TotalAmount == 0) == (params.PerPerson == 0) {
+		return nil, errors.New("exactly one of total_amount and per_person_amount is required")
+	}
+	if err := checkUserActive(params.InitiatorID); err != nil {
+		return nil, err
+	}
+
+	var participants []*model.User
+	seen := make(map[string]bool, len(params.Participants))
+	for _, name := range params.Participants {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		u, err := repository.GetUserByUsername(database.DB, name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("participant not found")
+			}
+			return nil, err
+		}
+		if u.ID == params.InitiatorID {
+			return nil, errors.New("cannot collect from yourself")
+		}
+		participants = append(participants, u)
+	}
+	n := uint64(len(participants))
+	if n == 0 || n > maxCollectionParticipants {
+		return nil, errors.New("participants must be between 1 and 100")
+	}
+
+	amounts := make([]uint64, n)
+	total := params.TotalAmount
+	if params.PerPerson > 0 {
+		if params.PerPerson > math.MaxUint64/n {
+			return nil, errors.New("total amount is too large")
+		}
+		total = params.PerPerson * n
+		for i := range amounts {
+			amounts[i] = params.PerPerson
+		}
+	} else {
+		if total < n {
+			return nil, errors.New("total amount must be >= participant count")
+		}
+		for i := range amounts {
+			amounts[i] = total / n
+			if uint64(i) < total%n {
+				amounts[i]++
+			}
+		}
+	}
+
+	ttl := params.TTL
+	if ttl <= 0 {
+		ttl = defaultCollectionTTL
+	}
+	if ttl > maxCollectionTTL {
+		return nil, errors.New("collection ttl is too long")
+	}
+
+	c := &model.Collection{
+		InitiatorID:      params.InitiatorID,
+		Title:            params.Title,
+		Currency:         cur,
+		TotalAmount:      total,
+		ParticipantCount: uint32(n),
+		Status:           model.CollectionStatusOpen,
+		ExpiredAt:        time.Now().Add(ttl),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.CreateCollection(tx, c); err != nil {
+			return err
+		}
+		shares := make([]model.CollectionShare, 0, n)
+		for i, u := range participants {
+			shares = append(shares, model.CollectionShare{
+				CollectionID: c.ID,
+				UserID:       u.ID,
+				Amount:       amounts[i],
+				Status:       model.CollectionShareUnpaid,
+			})
+		}
+		return repository.CreateCollectionShares(tx, shares)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetCollection(c.ID, params.InitiatorID)
+}
+
+// GetCollection 收款详情及每个参与人的付款进度，只有发起人和参与人可见
+func GetCollection(collectionID, userID uint64) (*CollectionDetail, error) {
+	c, err := repository.GetCollectionByID(database.DB, collectionID)
+	if err != nil {
+		return nil, errors.New("collection not found")
+	}
+	shares, err := repository.GetCollectionShares(database.DB, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint64{c.InitiatorID}
+	member := c.InitiatorID == userID
+	for _, s := range shares {
+		ids = append(ids, s.UserID)
+		member = member || s.UserID == userID
+	}
+	if !member {
+		return nil, errors.New("collection not found")
+	}
+	names, err := getUsernames(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &CollectionDetail{Collection: c, InitiatorName: names[c.InitiatorID]}
+	for _, s := range shares {
+		detail.Shares = append(detail.Shares, CollectionShareItem{
+			UserID:   s.UserID,
+			Username: names[s.UserID],
+			Amount:   s.Amount,
+			Status:   s.Status,
+			PaidAt:   s.PaidAt,
+		})
+	}
+	return detail, nil
+}
+
+// PayCollection 参与人付款，最后一人付清时同一事务内结算给发起人。
+// 付款与转账一样经过发出场景的风控并计入当日发出限额；结算计入发起人当日领取限额，超出时拒绝最后一笔付款
+func PayCollection(collectionID, userID uint64, meta RequestMeta) (*CollectionDetail, error) {
+	if err := checkUserActive(userID); err != nil {
+		return nil, err
+	}
+	shares, err := repository.GetCollectionShares(database.DB, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	var amount uint64
+	for _, s := range shares {
+		if s.UserID == userID {
+			amount = s.Amount
+		}
+	}
+	if amount == 0 {
+		return nil, errors.New("collection not found")
+	}
+	if err := checkRisk(risk.SceneSend, userID, meta, amount, 0); err != nil {
+		return nil, err
+	}
+
+	err = withOpenCollection(collectionID, func(tx *gorm.DB, c *model.Collection, shares []model.CollectionShare) error {
+		var share *model.CollectionShare
+		for i := range shares {
+			if shares[i].UserID == userID {
+				share = &shares[i]
+			}
+		}
+		if share == nil {
+			return errors.New("collection not found")
+		}
+		if share.Status == model.CollectionSharePaid {
+			return errors.New("already paid")
+		}
+
+		settle := c.PaidCount+1 == c.ParticipantCount
+		if settle {
+			// 付款人和发起人的用户行都要锁，按ID顺序加锁，避免互相支付对方收款时死锁
+			if err := lockUsers(tx, userID, c.InitiatorID); err != nil {
+				return err
+			}
+			ok, err := withinCollectionReceiveLimit(tx, c, c.PaidAmount+share.Amount)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errors.New("initiator daily receive limit exceeded")
+			}
+		}
+		if err := checkDailySendLimit(tx, userID, c.Currency, share.Amount); err != nil {
+			return err
+		}
+
+		if _, err := debit(tx, userID, c.Currency, share.Amount, model.TransactionTypeCollection, &c.ID, "AA 收款付款："+c.Title); err != nil {
+			return err
+		}
+		now := time.Now()
+		share.Status = model.CollectionSharePaid
+		share.PaidAt = &now
+		if err := repository.UpdateCollectionShare(tx, share); err != nil {
+			return err
+		}
+
+		c.PaidAmount += share.Amount
+		c.PaidCount++
+		if settle {
+			return settleCollection(tx, c, shares, model.CollectionStatusSettled)
+		}
+		return repository.UpdateCollection(tx, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetCollection(collectionID, userID)
+}
+
+// CancelCollection 发起人取消收款，已付款的参与人原路退回
+func CancelCollection(collectionID, userID uint64) (*CollectionDetail, error) {
+	err := withOpenCollection(collectionID, func(tx *gorm.DB, c *model.Collection, shares []model.CollectionShare) error {
+		if c.InitiatorID != userID {
+			return errors.New("collection not found")
+		}
+		return refundCollection(tx, c, shares, model.CollectionStatusCancelled, "AA 收款取消退回："+c.Title)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetCollection(collectionID, userID)
+}
+
+// RemindCollection 发起人催收，给每个未付款的参与人累加提醒次数（领域事件通知），返回提醒人数
+func RemindCollection(collectionID, userID uint64) (int, error) {
+	reminded := 0
+	err := withOpenCollection(collectionID, func(tx *gorm.DB, c *model.Collection, shares []model.CollectionShare) error {
+		if c.InitiatorID != userID {
+			return errors.New("collection not found")
+		}
+		now := time.Now()
+		if c.RemindedAt != nil && now.Sub(*c.RemindedAt) < collectionRemindInterval {
+			return errors.New("remind too frequent")
+		}
+		for i := range shares {
+			s := &shares[i]
+			if s.Status != model.CollectionShareUnpaid {
+				continue
+			}
+			s.RemindCount++
+			if err := repository.UpdateCollectionShare(tx, s); err != nil {
+				return err
+			}
+			reminded++
+		}
+		c.RemindedAt = &now
+		return repository.UpdateCollection(tx, c)
+	})
+	return reminded, err
+}
+
+// SettleExpiredCollections 到期未收齐的收款把已收款项结算给发起人，返回处理的数量
+func SettleExpiredCollections() (int, error) {
+	ids, err := repository.GetExpiredOpenCollectionIDs(database.DB, time.Now(), settleBatchLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, id := range ids {
+		done := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			c, err := repository.GetCollectionForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			// 加锁后再确认一次，可能刚被付清或取消
+			if c.Status != model.CollectionStatusOpen {
+				return nil
+			}
+			shares, err := repository.GetCollectionShares(tx, id)
+			if err != nil {
+				return err
+			}
+			done = true
+			// 结算会超出发起人当日领取限额时不入账，已付款项原路退回
+			if c.PaidAmount > 0 {
+				ok, err := withinCollectionReceiveLimit(tx, c, c.PaidAmount)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return refundCollection(tx, c, shares, model.CollectionStatusExpired, "AA 收款到期退回："+c.Title)
+				}
+			}
+			return settleCollection(tx, c, shares, model.CollectionStatusExpired)
+		})
+		if err != nil {
+			return settled, err
+		}
+		if done {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+// settleCollection 把已收款项一次性转给发起人，未付款的份额标记为已结束
+func settleCollection(tx *gorm.DB, c *model.Collection, shares []model.CollectionShare, status int8) error {
+	if c.PaidAmount > 0 {
+		if _, err := credit(tx, c.InitiatorID, c.Currency, c.PaidAmount, model.TransactionTypeCollection, &c.ID, "AA 收款到账："+c.Title); err != nil {
+			return err
+		}
+	}
+	for i := range shares {
+		s := &shares[i]
+		if s.Status != model.CollectionShareUnpaid {
+			continue
+		}
+		s.Status = model.CollectionShareClosed
+		if err := repository.UpdateCollectionShare(tx, s); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	c.Status = status
+	c.SettledAt = &now
+	return repository.UpdateCollection(tx, c)
+}
+
+// refundCollection 已付款的参与人原路退回，未付款的份额标记为已结束
+func refundCollection(tx *gorm.DB, c *model.Collection, shares []model.CollectionShare, status int8, remark string) error {
+	for i := range shares {
+		s := &shares[i]
+		switch s.Status {
+		case model.CollectionSharePaid:
+			if _, err := credit(tx, s.UserID, c.Currency, s.Amount, model.TransactionTypeCollection, &c.ID, remark); err != nil {
+				return err
+			}
+			s.Status = model.CollectionShareRefunded
+		case model.CollectionShareUnpaid:
+			s.Status = model.CollectionShareClosed
+		default:
+			continue
+		}
+		if err := repository.UpdateCollectionShare(tx, s); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	c.PaidAmount = 0
+	c.Status = status
+	c.SettledAt = &now
+	return repository.UpdateCollection(tx, c)
+}
+
+// withinCollectionReceiveLimit 结算金额计入发起人当日领取限额后是否仍在限额内，查询失败时返回 error
+func withinCollectionReceiveLimit(tx *gorm.DB, c *model.Collection, amount uint64) (bool, error) {
+	used, count, err := receiveUsage(tx, c.InitiatorID, c.Currency)
+	if err != nil {
+		return false, err
+	}
+	return checkDailyReceiveLimit(c.Currency, used, count, amount) == nil, nil
+}
+
+// withOpenCollection 锁定收款中的收款并加载全部份额，已结束或已到期的返回 collection is closed
+func withOpenCollection(collectionID uint64, fn func(tx *gorm.DB, c *model.Collection, shares []model.CollectionShare) error) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		c, err := repository.GetCollectionForUpdate(tx, collectionID)
+		if err != nil {
+			return errors.New("collection not found")
+		}
+		if c.Status != model.CollectionStatusOpen || time.Now().After(c.ExpiredAt) {
+			return errors.New("collection is closed")
+		}
+		shares, err := repository.GetCollectionShares(tx, collectionID)
+		if err != nil {
+			return err
+		}
+		return fn(tx, c, shares)
+	})
+}
+
+func GetUserCollections(userID uint64, p pagination.Params) ([]model.Collection, pagination.Result, error) {
+	list, total, err := repository.GetUserCollections(database.DB, userID, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(c model.Collection) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+	})
+	return list, result, nil
+}
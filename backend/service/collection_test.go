@@ -0,0 +1,215 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/repository"
+)
+
+// newTestCollection 发起人向 n 个各有 1000 余额的参与人每人收 100
+func newTestCollection(tb testing.TB, n int) (*CollectionDetail, *model.User, []*model.User) {
+	tb.Helper()
+	initiator := newTestUser(tb, 0)
+	participants := make([]*model.User, n)
+	names := make([]string, n)
+	for i := range participants {
+		participants[i] = newTestUser(tb, 1000)
+		names[i] = participants[i].Username
+	}
+	c, err := CreateCollection(CollectionParams{InitiatorID: initiator.ID, Title: "聚餐", PerPerson: 100, Participants: names})
+	if err != nil {
+		tb.Fatalf("create collection: %v", err)
+	}
+	return c, initiator, participants
+}
+
+// collectionState 收款当前状态及各参与人份额状态
+func collectionState(tb testing.TB, id uint64) (*model.Collection, map[uint64]int8) {
+	tb.Helper()
+	c, err := repository.GetCollectionByID(database.DB, id)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	shares, err := repository.GetCollectionShares(database.DB, id)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	status := make(map[uint64]int8, len(shares))
+	for _, s := range shares {
+		status[s.UserID] = s.Status
+	}
+	return c, status
+}
+
+// withReceiveLimit 测试期间给默认币种设置当日领取金额上限
+func withReceiveLimit(tb testing.TB, amount uint64) {
+	tb.Helper()
+	prev := limits
+	limits = map[string]Limit{currency.Default(): {DailyReceiveAmount: amount}}
+	tb.Cleanup(func() { limits = prev })
+}
+
+// expireCollection 把收款改为已到期并执行到期结算，直到没有可处理的收款
+func expireCollection(tb testing.TB, id uint64) {
+	tb.Helper()
+	if err := database.DB.Model(&model.Collection{}).Where("id = ?", id).
+		Update("expired_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		tb.Fatal(err)
+	}
+	for {
+		n, err := SettleExpiredCollections()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// 付款即扣款，款项暂存平台；最后一人付清时一次性结算给发起人
+func TestPayCollectionSettlesWhenFullyPaid(t *testing.T) {
+	requireTestDB(t)
+	c, initiator, ps := newTestCollection(t, 2)
+
+	if _, err := PayCollection(c.ID, ps[0].ID, RequestMeta{}); err != nil {
+		t.Fatalf("first pay: %v", err)
+	}
+	if _, err := PayCollection(c.ID, ps[0].ID, RequestMeta{}); err == nil || err.Error() != "already paid" {
+		t.Fatalf("second pay by same user err = %v, want already paid", err)
+	}
+	if b := balanceOf(t, ps[0].ID); b != 900 {
+		t.Errorf("payer balance = %d, want 900", b)
+	}
+	if b := balanceOf(t, initiator.ID); b != 0 {
+		t.Errorf("initiator balance before settlement = %d, want 0", b)
+	}
+
+	if _, err := PayCollection(c.ID, ps[1].ID, RequestMeta{}); err != nil {
+		t.Fatalf("last pay: %v", err)
+	}
+	got, shares := collectionState(t, c.ID)
+	if got.Status != model.CollectionStatusSettled || got.PaidAmount != 200 || got.PaidCount != 2 {
+		t.Errorf("collection status %d paid %d/%d, want settled 200/2", got.Status, got.PaidAmount, got.PaidCount)
+	}
+	for _, p := range ps {
+		if shares[p.ID] != model.CollectionSharePaid {
+			t.Errorf("share of %d status %d, want paid", p.ID, shares[p.ID])
+		}
+	}
+	if b := balanceOf(t, initiator.ID); b != 200 {
+		t.Errorf("initiator balance = %d, want 200", b)
+	}
+}
+
+// 取消后已付款的原路退回，未付款的份额结束，发起人不入账
+func TestCancelCollectionRefundsPaidShares(t *testing.T) {
+	requireTestDB(t)
+	c, initiator, ps := newTestCollection(t, 2)
+	if _, err := PayCollection(c.ID, ps[0].ID, RequestMeta{}); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if _, err := CancelCollection(c.ID, ps[0].ID); err == nil || err.Error() != "collection not found" {
+		t.Fatalf("cancel by participant err = %v, want collection not found", err)
+	}
+	if _, err := CancelCollection(c.ID, initiator.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	got, shares := collectionState(t, c.ID)
+	if got.Status != model.CollectionStatusCancelled || got.PaidAmount != 0 {
+		t.Errorf("collection status %d paid %d, want cancelled 0", got.Status, got.PaidAmount)
+	}
+	if shares[ps[0].ID] != model.CollectionShareRefunded || shares[ps[1].ID] != model.CollectionShareClosed {
+		t.Errorf("share statuses %d/%d, want refunded/closed", shares[ps[0].ID], shares[ps[1].ID])
+	}
+	if b := balanceOf(t, ps[0].ID); b != 1000 {
+		t.Errorf("payer balance = %d, want 1000", b)
+	}
+	if b := balanceOf(t, initiator.ID); b != 0 {
+		t.Errorf("initiator balance = %d, want 0", b)
+	}
+	if _, err := PayCollection(c.ID, ps[1].ID, RequestMeta{}); err == nil || err.Error() != "collection is closed" {
+		t.Errorf("pay after cancel err = %v, want collection is closed", err)
+	}
+}
+
+// 到期未收齐：已收款项结算给发起人；结算会超出发起人当日领取限额时改为原路退回
+func TestSettleExpiredCollections(t *testing.T) {
+	requireTestDB(t)
+	t.Run("settle", func(t *testing.T) {
+		c, initiator, ps := newTestCollection(t, 2)
+		if _, err := PayCollection(c.ID, ps[0].ID, RequestMeta{}); err != nil {
+			t.Fatalf("pay: %v", err)
+		}
+		expireCollection(t, c.ID)
+
+		got, shares := collectionState(t, c.ID)
+		if got.Status != model.CollectionStatusExpired || got.PaidAmount != 100 {
+			t.Errorf("collection status %d paid %d, want expired 100", got.Status, got.PaidAmount)
+		}
+		if shares[ps[0].ID] != model.CollectionSharePaid || shares[ps[1].ID] != model.CollectionShareClosed {
+			t.Errorf("share statuses %d/%d, want paid/closed", shares[ps[0].ID], shares[ps[1].ID])
+		}
+		if b := balanceOf(t, initiator.ID); b != 100 {
+			t.Errorf("initiator balance = %d, want 100", b)
+		}
+		if b := balanceOf(t, ps[0].ID); b != 900 {
+			t.Errorf("payer balance = %d, want 900", b)
+		}
+	})
+	t.Run("refund over limit", func(t *testing.T) {
+		c, initiator, ps := newTestCollection(t, 2)
+		if _, err := PayCollection(c.ID, ps[0].ID, RequestMeta{}); err != nil {
+			t.Fatalf("pay: %v", err)
+		}
+		withReceiveLimit(t, 50)
+		expireCollection(t, c.ID)
+
+		got, shares := collectionState(t, c.ID)
+		if got.Status != model.CollectionStatusExpired || got.PaidAmount != 0 {
+			t.Errorf("collection status %d paid %d, want expired 0", got.Status, got.PaidAmount)
+		}
+		if shares[ps[0].ID] != model.CollectionShareRefunded {
+			t.Errorf("payer share status %d, want refunded", shares[ps[0].ID])
+		}
+		if b := balanceOf(t, initiator.ID); b != 0 {
+			t.Errorf("initiator balance = %d, want 0", b)
+		}
+		if b := balanceOf(t, ps[0].ID); b != 1000 {
+			t.Errorf("payer balance = %d, want 1000", b)
+		}
+	})
+}
+
+// 最后一笔付款会让结算超出发起人当日领取限额时拒绝，付款人余额和收款进度不变
+func TestPayCollectionRejectsSettlementOverReceiveLimit(t *testing.T) {
+	requireTestDB(t)
+	c, initiator, ps := newTestCollection(t, 2)
+	withReceiveLimit(t, 150)
+
+	if _, err := PayCollection(c.ID, ps[0].ID, RequestMeta{}); err != nil {
+		t.Fatalf("first pay: %v", err)
+	}
+	if _, err := PayCollection(c.ID, ps[1].ID, RequestMeta{}); err == nil || err.Error() != "initiator daily receive limit exceeded" {
+		t.Fatalf("last pay err = %v, want initiator daily receive limit exceeded", err)
+	}
+
+	got, shares := collectionState(t, c.ID)
+	if got.Status != model.CollectionStatusOpen || got.PaidAmount != 100 || got.PaidCount != 1 {
+		t.Errorf("collection status %d paid %d/%d, want open 100/1", got.Status, got.PaidAmount, got.PaidCount)
+	}
+	if shares[ps[1].ID] != model.CollectionShareUnpaid {
+		t.Errorf("rejected payer share status %d, want unpaid", shares[ps[1].ID])
+	}
+	if b := balanceOf(t, ps[1].ID); b != 1000 {
+		t.Errorf("rejected payer balance = %d, want 1000", b)
+	}
+	if b := balanceOf(t, initiator.ID); b != 0 {
+		t.Errorf("initiator balance = %d, want 0", b)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/broker"
+	"red-packet/pkg/event"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	sequenceBatchLimit = 500
+	relayBatchLimit    = 200
+	relayTimeout       = 10 * time.Second
+	maxEventPullLimit  = 500
+)
+
+var (
+	relayBroker broker.Broker
+	relayTopic  string
+)
+
+// InitEventRelay 开启领域事件转发，未调用时 RelayDomainEvents 不做任何事
+func InitEventRelay(b broker.Broker, topic string) {
+	relayBroker = b
+	relayTopic = topic
+}
+
+// SequenceDomainEvents 给已提交的事件按可见顺序分配连续序号，返回本次分配的数量。
+// 自增 ID 的提交顺序不确定（ID 小的事务可能后提交），直接按 ID 翻页会漏掉晚提交的事件；
+// 这里锁住序号行串行分配，只有已提交的事件才能被读到，保证序号递增的顺序就是可见顺序
+func SequenceDomainEvents() (int, error) {
+	n := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		offset, err := repository.GetDomainEventOffsetForUpdate(tx, repository.DomainEventOffsetSequence)
+		if err != nil {
+			return err
+		}
+		ids, err := repository.GetUnsequencedDomainEventIDs(tx, sequenceBatchLimit)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		seq := offset.Value
+		for _, id := range ids {
+			seq++
+			if err := repository.SetDomainEventSeq(tx, id, seq); err != nil {
+				return err
+			}
+		}
+		n = len(ids)
+		return repository.UpdateDomainEventOffset(tx, repository.DomainEventOffsetSequence, seq)
+	})
+	return n, err
+}
+
+type DomainEventPage struct {
+	List    []event.DomainEvent
+	NextSeq uint64 // 下次拉取时作为 after 传入
+	HasMore bool
+}
+
+// PullDomainEvents 按序号拉取已分配序号的事件，只读不加锁；序号由调度任务分配，
+// 刚提交的事件在下一个调度周期后可见
+func PullDomainEvents(filter repository.DomainEventFilter, limit int) (*DomainEventPage, error) {
+	if limit <= 0 || limit > maxEventPullLimit {
+		limit = maxEventPullLimit
+	}
+	list, err := repository.ListDomainEvents(database.DB, filter, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &DomainEventPage{NextSeq: filter.AfterSeq}
+	if len(list) > limit {
+		list = list[:limit]
+		page.HasMore = true
+	}
+	page.List = toDomainEvents(list)
+	if len(list) > 0 {
+		page.NextSeq = *list[len(list)-1].Seq
+	}
+	return page, nil
+}
+
+// RelayDomainEvents 把已分配序号的新事件转发到消息中间件，成功后才推进转发位置（至少一次投递），返回转发数量
+func RelayDomainEvents() (int, error) {
+	if relayBroker == nil {
+		return 0, nil
+	}
+
+	n := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁住转发位置，多实例部署时同一时刻只有一个实例在转发
+		offset, err := repository.GetDomainEventOffsetForUpdate(tx, repository.DomainEventOffsetRelay)
+		if err != nil {
+			return err
+		}
+		list, err := repository.ListDomainEvents(tx, repository.DomainEventFilter{AfterSeq: offset.Value}, relayBatchLimit)
+		if err != nil || len(list) == 0 {
+			return err
+		}
+
+		msgs := make([]broker.Message, 0, len(list))
+		for _, e := range toDomainEvents(list) {
+			body, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, broker.Message{Key: strconv.FormatUint(e.Seq, 10), Body: body})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+		defer cancel()
+		if err := relayBroker.Publish(ctx, relayTopic, msgs...); err != nil {
+			return err
+		}
+		n = len(list)
+		return repository.UpdateDomainEventOffset(tx, repository.DomainEventOffsetRelay, *list[len(list)-1].Seq)
+	})
+	return n, err
+}
+
+func toDomainEvents(list []model.DomainEvent) []event.DomainEvent {
+	items := make([]event.DomainEvent, 0, len(list))
+	for _, e := range list {
+		items = append(items, event.DomainEvent{
+			Seq:           *e.Seq,
+			Type:          e.EventType,
+			AggregateType: e.AggregateType,
+			AggregateID:   e.AggregateID,
+			UserID:        e.UserID,
+			Data:          json.RawMessage(e.Payload),
+			CreatedAt:     e.CreatedAt,
+		})
+	}
+	return items
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+
+	"red-packet/model"
+	"red-packet/repository"
+)
+
+// 拉取只读已分配序号的事件，不替调度任务分配序号；分配后按序号可见
+func TestPullDomainEventsReadsSequencedOnly(t *testing.T) {
+	requireTestDB(t)
+	user := newTestUser(t, 100)
+	if _, err := SendRedPacket(SendRedPacketParams{SenderID: user.ID, Type: model.RedPacketTypeNormal, TotalAmount: 100, TotalCount: 1}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	filter := repository.DomainEventFilter{UserID: user.ID}
+
+	page, err := PullDomainEvents(filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.List) != 0 {
+		t.Fatalf("pull returned %d events before sequencing", len(page.List))
+	}
+	if n := countRows(t, &model.DomainEvent{}, "user_id = ? AND seq IS NULL", user.ID); n == 0 {
+		t.Fatal("pull assigned sequence numbers")
+	}
+
+	for {
+		n, err := SequenceDomainEvents()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	page, err = PullDomainEvents(filter, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.List) == 0 {
+		t.Fatal("sequenced events not returned")
+	}
+	for _, e := range page.List {
+		if e.UserID != user.ID {
+			t.Fatalf("event for user %d returned", e.UserID)
+		}
+	}
+	if page.NextSeq != page.List[len(page.List)-1].Seq {
+		t.Fatalf("next_seq = %d, want %d", page.NextSeq, page.List[len(page.List)-1].Seq)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/fairsplit"
+	"red-packet/repository"
+)
+
+// Fairness 拼手气红包的公平性公开数据，红包结束前不含种子
+type Fairness struct {
+	RedPacket *model.RedPacket
+	Algorithm string
+	Seed      string // 红包结束后公开
+	Claims    []FairClaim
+	// Entries 抽奖红包按报名顺序排列的报名用户ID，开奖后公开；EntriesHash 为开奖时记录的名单摘要，早期开奖的为空
+	Entries     []uint64
+	EntriesHash string
+	// Verified 用公开的种子重算后每一份都与实际领取金额一致；种子未公开时为 nil
+	Verified *bool
+}
+
+type FairClaim struct {
+	Seq          uint32
+	ReceiverID   uint64
+	ReceiverName string
+	Amount       uint64
+	Expected     *uint64 // 按种子重算的金额，种子未公开时为 nil
+	// 抽奖红包按种子和报名名单重算出的该名次中奖者，开奖前为 nil
+	ExpectedReceiverID *uint64
+}
+
+// GetFairness 返回种子承诺和按领取顺序排列的金额，种子公开后附带服务端重算结果
+func GetFairness(redPacketID uint64) (*Fairness, error) {
+	rp, err := repository.GetRedPacketByID(database.DB, redPacketID)
+	if err != nil {
+		return nil, errors.New("red packet not found")
+	}
+	if (rp.Type != model.RedPacketTypeLucky && rp.Type != model.RedPacketTypeLottery) || rp.SeedHash == "" {
+		return nil, errors.New("red packet is not verifiable")
+	}
+
+	records, err := repository.GetAllRedPacketRecords(database.DB, redPacketID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ReceiverID)
+	}
+	names, err := getUsernames(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fairness{RedPacket: rp, Algorithm: fairsplit.Algorithm}
+	var shares []uint64
+	if rp.SeedRevealed() {
+		f.Seed = rp.Seed
+		if shares, err = fairsplit.Shares(rp.Seed, rp.TotalAmount, rp.TotalCount); err != nil {
+			return nil, err
+		}
+		// 种子与发出时公布的承诺不符，整包都不可信
+		commitment, err := fairsplit.Commitment(rp.Seed)
+		if err != nil {
+			return nil, err
+		}
+		verified := commitment == rp.SeedHash
+		f.Verified = &verified
+	}
+	var winners []uint64
+	if rp.Type == model.RedPacketTypeLottery && rp.Status == model.RedPacketStatusDrawn {
+		if winners, err = lotteryWinners(f); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range records {
+		c := FairClaim{Seq: r.Seq, ReceiverID: r.ReceiverID, ReceiverName: names[r.ReceiverID], Amount: r.Amount}
+		if winners != nil {
+			if int(r.Seq) < len(winners) {
+				c.ExpectedReceiverID = &winners[r.Seq]
+			}
+			if c.ExpectedReceiverID == nil || *c.ExpectedReceiverID != r.ReceiverID {
+				*f.Verified = false
+			}
+		}
+		if shares != nil {
+			if int(r.Seq) < len(shares) {
+				expected := shares[r.Seq]
+				c.Expected = &expected
+			}
+			if c.Expected == nil || *c.Expected != r.Amount {
+				*f.Verified = false
+			}
+		}
+		f.Claims = append(f.Claims, c)
+	}
+	return f, nil
+}
+
+// lotteryWinners 按报名名单重算各名次的中奖者，名单与开奖时记录的摘要不符时标记为未通过校验
+func lotteryWinners(f *Fairness) ([]uint64, error) {
+	rp := f.RedPacket
+	entries, err := repository.GetRedPacketEntries(database.DB, rp.ID)
+	if err != nil {
+		return nil, err
+	}
+	f.Entries = make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		f.Entries = append(f.Entries, e.UserID)
+	}
+	f.EntriesHash = rp.EntriesHash
+
+	// 名单摘要上线前开奖的红包直接用红包种子抽取
+	drawSeed := rp.Seed
+	if rp.EntriesHash != "" {
+		if fairsplit.EntriesHash(f.Entries) != rp.EntriesHash {
+			*f.Verified = false
+		}
+		if drawSeed, err = fairsplit.DrawSeed(rp.Seed, rp.EntriesHash); err != nil {
+			return nil, err
+		}
+	}
+	idx, err := fairsplit.Winners(drawSeed, len(f.Entries), int(rp.TotalCount))
+	if err != nil {
+		return nil, err
+	}
+	winners := make([]uint64, 0, len(idx))
+	for _, i := range idx {
+		winners = append(winners, f.Entries[i])
+	}
+	return winners, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+// Limit 单币种的收发限额，0 表示不限制
+type Limit struct {
+	MaxPacketAmount    uint64 // 单个红包最大金额
+	DailySendAmount    uint64 // 每日转出总额（发红包、转账、AA 付款）
+	DailySendCount     int64  // 每日转出笔数
+	DailyReceiveAmount uint64 // 每日领取总额（领红包、红包雨、收到转账、AA 收款到账）
+	DailyReceiveCount  int64  // 每日领取次数
+}
+
+var limits = map[string]Limit{}
+
+// sendUsageTypes 计入当日发出额度的支出流水类型：发红包之外的转出方式也要受同一限额约束
+var sendUsageTypes = []string{model.TransactionTypeSend, model.TransactionTypeTransfer, model.TransactionTypeCollection}
+
+// receiveUsageTypes 计入当日领取额度的收入流水类型；转账和 AA 收款的收入流水还包含退回，
+// 收到的转账和收款结算部分单独统计
+var receiveUsageTypes = []string{model.TransactionTypeReceive, model.TransactionTypeRain}
+
+func InitLimitService(l map[string]Limit) {
+	limits = l
+}
+
+type QuotaItem struct {
+	Limit     uint64
+	Used      uint64
+	Remaining uint64
+	Unlimited bool
+}
+
+type Quota struct {
+	Currency        string
+	MaxPacketAmount uint64
+	SendAmount      QuotaItem
+	SendCount       QuotaItem
+	ReceiveAmount   QuotaItem
+	ReceiveCount    QuotaItem
+	ResetAt         time.Time
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// checkPacketAmount 单个红包金额上限，在事务外校验即可
+func checkPacketAmount(cur string, amount uint64) error {
+	l := limits[cur]
+	if l.MaxPacketAmount > 0 && amount > l.MaxPacketAmount {
+		return errors.New("red packet amount exceeds limit")
+	}
+	return nil
+}
+
+// checkDailySendLimit 先锁用户行串行化同一用户的并发请求，再按当日流水聚合校验
+func checkDailySendLimit(tx *gorm.DB, userID uint64, cur string, amount uint64) error {
+	l := limits[cur]
+	if l.DailySendAmount == 0 && l.DailySendCount == 0 {
+		return nil
+	}
+	if _, err := repository.GetUserForUpdate(tx, userID); err != nil {
+		return err
+	}
+	sum, count, err := repository.SumUserTransactions(tx, userID, sendUsageTypes, model.TransactionDirectionOut, cur, startOfDay(time.Now()))
+	if err != nil {
+		return err
+	}
+	if l.DailySendCount > 0 && count+1 > l.DailySendCount {
+		return errors.New("daily send count limit exceeded")
+	}
+	if l.DailySendAmount > 0 && sum+amount > l.DailySendAmount {
+		return errors.New("daily send amount limit exceeded")
+	}
+	return nil
+}
+
+// lockUsers 按用户ID从小到大锁定用户行，同时涉及两个用户限额的事务按相同顺序加锁，避免死锁
+func lockUsers(tx *gorm.DB, ids ...uint64) error {
+	slices.Sort(ids)
+	for _, id := range ids {
+		if _, err := repository.GetUserForUpdate(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveUsage 锁用户行后查询当日已领取的金额和次数。未配置领取限额时也加锁：
+// 活动每人领取上限等按用户计数的校验依赖这把锁串行化同一用户的并发领取。
+// 调用前事务可能已有普通读建立了快照，用量用锁定读统计，才能看到刚提交的领取
+func receiveUsage(tx *gorm.DB, userID uint64, cur string) (uint64, int64, error) {
+	if _, err := repository.GetUserForUpdate(tx, userID); err != nil {
+		return 0, 0, err
+	}
+	l := limits[cur]
+	if l.DailyReceiveAmount == 0 && l.DailyReceiveCount == 0 {
+		return 0, 0, nil
+	}
+	return receivedToday(repository.ForShare(tx), userID, cur, startOfDay(time.Now()))
+}
+
+// receivedToday 当日领取用量：领红包和红包雨流水，加上作为收款人收到的转账和作为发起人收到的 AA 收款结算
+func receivedToday(db *gorm.DB, userID uint64, cur string, since time.Time) (uint64, int64, error) {
+	sum, count, err := repository.SumUserTransactions(db, userID, receiveUsageTypes, model.TransactionDirectionIn, cur, since)
+	if err != nil {
+		return 0, 0, err
+	}
+	transferSum, transferCount, err := repository.SumTransferReceipts(db, userID, cur, since)
+	if err != nil {
+		return 0, 0, err
+	}
+	settledSum, settledCount, err := repository.SumCollectionSettlements(db, userID, cur, since)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sum + transferSum + settledSum, count + transferCount + settledCount, nil
+}
+
+func checkDailyReceiveLimit(cur string, usedAmount uint64, usedCount int64, amount uint64) error {
+	l := limits[cur]
+	if l.DailyReceiveCount > 0 && usedCount+1 > l.DailyReceiveCount {
+		return errors.New("daily receive count limit exceeded")
+	}
+	if l.DailyReceiveAmount > 0 && usedAmount+amount > l.DailyReceiveAmount {
+		return errors.New("daily receive amount limit exceeded")
+	}
+	return nil
+}
+
+// GetQuota 查询用户当日剩余额度
+func GetQuota(userID uint64, code string) (*Quota, error) {
+	cur, err := currency.Normalize(code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	since := startOfDay(now)
+	sendSum, sendCount, err := repository.SumUserTransactions(database.DB, userID, sendUsageTypes, model.TransactionDirectionOut, cur, since)
+	if err != nil {
+		return nil, err
+	}
+	recvSum, recvCount, err := receivedToday(database.DB, userID, cur, since)
+	if err != nil {
+		return nil, err
+	}
+
+	l := limits[cur]
+	return &Quota{
+		Currency:        cur,
+		MaxPacketAmount: l.MaxPacketAmount,
+		SendAmount:      newQuotaItem(l.DailySendAmount, sendSum),
+		SendCount:       newQuotaItem(uint64(l.DailySendCount), uint64(sendCount)),
+		ReceiveAmount:   newQuotaItem(l.DailyReceiveAmount, recvSum),
+		ReceiveCount:    newQuotaItem(uint64(l.DailyReceiveCount), uint64(recvCount)),
+		ResetAt:         since.AddDate(0, 0, 1),
+	}, nil
+}
+
+func newQuotaItem(limit, used uint64) QuotaItem {
+	if limit == 0 {
+		return QuotaItem{Used: used, Unlimited: true}
+	}
+	item := QuotaItem{Limit: limit, Used: used}
+	if used < limit {
+		item.Remaining = limit - used
+	}
+	return item
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/cache"
+	"red-packet/pkg/pagination"
+
+	"gorm.io/gorm"
+)
+
+var (
+	queryCounting    atomic.Bool
+	queryCount       atomic.Int64
+	queryCounterOnce sync.Once
+)
+
+// countQueries 统计 fn 执行的 SQL 语句数。用户名缓存先清空，每次都按未命中计算
+func countQueries(tb testing.TB, fn func() error) int64 {
+	tb.Helper()
+	queryCounterOnce.Do(func() {
+		count := func(*gorm.DB) {
+			if queryCounting.Load() {
+				queryCount.Add(1)
+			}
+		}
+		cb := database.DB.Callback()
+		for _, err := range []error{
+			cb.Query().After("gorm:query").Register("test:count_query", count),
+			cb.Row().After("gorm:row").Register("test:count_row", count),
+			cb.Raw().After("gorm:raw").Register("test:count_raw", count),
+		} {
+			if err != nil {
+				tb.Fatalf("register callback: %v", err)
+			}
+		}
+	})
+
+	prev := usernameCache
+	usernameCache = cache.NewTTL[uint64, string](time.Minute, 1000)
+	defer func() { usernameCache = prev }()
+
+	queryCount.Store(0)
+	queryCounting.Store(true)
+	err := fn()
+	queryCounting.Store(false)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return queryCount.Load()
+}
+
+// listFixture 一个被 10 人领取的红包，以及一个领取了 10 个不同发送者红包的用户
+type listFixture struct {
+	redPacketID uint64
+	receiverID  uint64
+}
+
+func newListFixture(tb testing.TB) listFixture {
+	tb.Helper()
+	const n = 10
+	sender := newTestUser(tb, n*100)
+	rp, err := SendRedPacket(SendRedPacketParams{SenderID: sender.ID, Type: model.RedPacketTypeNormal, TotalAmount: n * 100, TotalCount: n})
+	if err != nil {
+		tb.Fatalf("send: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: newTestUser(tb, 0).ID}); err != nil {
+			tb.Fatalf("claim: %v", err)
+		}
+	}
+
+	receiver := newTestUser(tb, 0)
+	for i := 0; i < n; i++ {
+		s := newTestUser(tb, 100)
+		p, err := SendRedPacket(SendRedPacketParams{SenderID: s.ID, Type: model.RedPacketTypeNormal, TotalAmount: 100, TotalCount: 1})
+		if err != nil {
+			tb.Fatalf("send: %v", err)
+		}
+		if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: p.ID, ReceiverID: receiver.ID}); err != nil {
+			tb.Fatalf("claim: %v", err)
+		}
+	}
+	return listFixture{redPacketID: rp.ID, receiverID: receiver.ID}
+}
+
+func (f listFixture) lists() map[string]func(pagination.Params) error {
+	return map[string]func(pagination.Params) error{
+		"records": func(p pagination.Params) error {
+			_, _, err := GetRedPacketRecords(f.redPacketID, p)
+			return err
+		},
+		"received": func(p pagination.Params) error {
+			_, _, err := GetReceivedRedPackets(f.receiverID, p)
+			return err
+		},
+	}
+}
+
+// 领取记录和我收到的红包列表补齐用户名是批量查询，每页的语句数与页大小无关
+func TestListQueryCountIndependentOfPageSize(t *testing.T) {
+	requireTestDB(t)
+	f := newListFixture(t)
+	for name, list := range f.lists() {
+		t.Run(name, func(t *testing.T) {
+			small := countQueries(t, func() error { return list(pagination.Params{Page: 1, PageSize: 2}) })
+			large := countQueries(t, func() error { return list(pagination.Params{Page: 1, PageSize: 10}) })
+			if small != large {
+				t.Fatalf("page size 2 ran %d queries, page size 10 ran %d", small, large)
+			}
+		})
+	}
+}
+
+// BenchmarkListQueries 按页大小报告每页执行的语句数（queries/op），需要 MySQL：
+//
+//	RED_PACKET_TEST_DSN=... go test ./service -run '^$' -bench ListQueries
+func BenchmarkListQueries(b *testing.B) {
+	requireTestDB(b)
+	f := newListFixture(b)
+	for name, list := range f.lists() {
+		for _, size := range []int{2, 10} {
+			b.Run(fmt.Sprintf("%s/page_size=%d", name, size), func(b *testing.B) {
+				var total int64
+				for i := 0; i < b.N; i++ {
+					total += countQueries(b, func() error { return list(pagination.Params{Page: 1, PageSize: size}) })
+				}
+				b.ReportMetric(float64(total)/float64(b.N), "queries/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/event"
+	"red-packet/pkg/fairsplit"
+	"red-packet/repository"
+	"red-packet/risk"
+
+	"gorm.io/gorm"
+)
+
+// EnterLottery 报名抽奖红包，返回当前报名人数。口令、分享令牌的要求与普通领取相同
+func EnterLottery(params ClaimRedPacketParams) (int64, error) {
+	redPacketID, userID := params.RedPacketID, params.ReceiverID
+	var entryCount int64
+
+	if err := checkUserActive(userID); err != nil {
+		return 0, err
+	}
+	if err := checkRisk(risk.SceneClaim, userID, params.Meta, 0, redPacketID); err != nil {
+		return 0, err
+	}
+	if err := verifyClaimSecret(&params); err != nil {
+		return 0, err
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 与开奖任务共用红包行锁，开奖时不会有新的报名插入
+		rp, err := repository.GetRedPacketForUpdate(tx, redPacketID)
+		if err != nil {
+			return errors.New("red packet not found")
+		}
+		if rp.Type != model.RedPacketTypeLottery {
+			return errors.New("not a lottery red packet")
+		}
+
+		now := time.Now()
+		switch {
+		case rp.Status == model.RedPacketStatusPending && now.Before(rp.OpenAt):
+			return errors.New("red packet is not open yet")
+		case rp.Status == model.RedPacketStatusPending, rp.Status == model.RedPacketStatusActive:
+			if rp.DrawAt == nil || !now.Before(*rp.DrawAt) {
+				return errors.New("lottery is closed")
+			}
+		case rp.Status == model.RedPacketStatusDrawn:
+			return errors.New("lottery is closed")
+		default:
+			return errors.New("red packet is expired")
+		}
+
+		if rp.RequireShare {
+			if err := VerifyShareToken(rp.PublicID, params.ShareToken); err != nil {
+				return err
+			}
+		}
+		if _, err := repository.GetRedPacketEntry(tx, redPacketID, userID); err == nil {
+			return errors.New("already entered")
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if rp.HasSecret && !params.secretVerified {
+			return errors.New("wrong secret")
+		}
+
+		if err := repository.CreateRedPacketEntry(tx, &model.RedPacketEntry{
+			RedPacketID: redPacketID,
+			UserID:      userID,
+			Status:      model.EntryStatusPending,
+		}); err != nil {
+			return err
+		}
+		entryCount, err = repository.CountRedPacketEntries(tx, redPacketID)
+		return err
+	})
+	return entryCount, err
+}
+
+// DrawLotteries 为报名已截止的抽奖红包开奖，返回开奖的红包数
+func DrawLotteries() (int, error) {
+	ids, err := repository.GetDueLotteryIDs(database.DB, time.Now(), activateBatchLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	drawn := 0
+	for _, id := range ids {
+		done := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			rp, err := repository.GetRedPacketForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			// 加锁后再确认一次，可能已被其他实例开奖或被管理员退款
+			if rp.Status != model.RedPacketStatusActive || rp.DrawAt == nil || time.Now().Before(*rp.DrawAt) {
+				return nil
+			}
+			done = true
+			return drawLottery(tx, rp)
+		})
+		if err != nil {
+			return drawn, err
+		}
+		if done {
+			drawn++
+		}
+	}
+	return drawn, nil
+}
+
+// drawLottery 用红包种子和截止时的报名名单抽出中奖者并按拼手气规则分配金额，
+// 中奖者入账、写领取记录和流水，所有报名更新结果，未发出的金额退回发送者，全部在同一事务内完成
+func drawLottery(tx *gorm.DB, rp *model.RedPacket) error {
+	entries, err := repository.GetRedPacketEntries(tx, rp.ID)
+	if err != nil {
+		return err
+	}
+	shares, err := fairsplit.Shares(rp.Seed, rp.TotalAmount, rp.TotalCount)
+	if err != nil {
+		return err
+	}
+	// 只用红包种子抽取时，知道种子就能在报名期间算出哪些报名位置中奖；混入截止后才确定的名单摘要
+	userIDs := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		userIDs = append(userIDs, e.UserID)
+	}
+	rp.EntriesHash = fairsplit.EntriesHash(userIDs)
+	drawSeed, err := fairsplit.DrawSeed(rp.Seed, rp.EntriesHash)
+	if err != nil {
+		return err
+	}
+	winners, err := fairsplit.Winners(drawSeed, len(entries), int(rp.TotalCount))
+	if err != nil {
+		return err
+	}
+	rank := make(map[int]uint32, len(winners))
+	for r, i := range winners {
+		rank[i] = uint32(r)
+	}
+
+	var winnerCount uint32
+	for i := range entries {
+		e := &entries[i]
+		seq, won := rank[i]
+		if !won {
+			e.Status = model.EntryStatusLost
+			if err := repository.UpdateRedPacketEntry(tx, e); err != nil {
+				return err
+			}
+			continue
+		}
+
+		amount := shares[seq]
+		eligible, err := winnerEligible(tx, rp, e.UserID, amount)
+		if err != nil {
+			return err
+		}
+		if !eligible {
+			// 冻结或超出当日领取限额的中奖者放弃本份，金额随剩余一起退回
+			e.Status = model.EntryStatusForfeited
+			if err := repository.UpdateRedPacketEntry(tx, e); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := repository.CreateRedPacketRecord(tx, &model.RedPacketRecord{
+			RedPacketID: rp.ID,
+			ReceiverID:  e.UserID,
+			Amount:      amount,
+			Seq:         seq,
+		}); err != nil {
+			return err
+		}
+		if err := creditReceiver(tx, rp, e.UserID, amount, "抽奖红包中奖"); err != nil {
+			return err
+		}
+		e.Status, e.Amount = model.EntryStatusWon, amount
+		if err := repository.UpdateRedPacketEntry(tx, e); err != nil {
+			return err
+		}
+
+		rp.RemainingAmount -= amount
+		rp.RemainingCount--
+		winnerCount++
+		data := redPacketEventData(rp)
+		data.ReceiverID, data.Amount = e.UserID, amount
+		if err := recordEvent(tx, event.RedPacketClaimed, data); err != nil {
+			return err
+		}
+	}
+
+	refund := rp.RemainingAmount
+	rp.RemainingAmount = 0
+	rp.RemainingCount = 0
+	rp.Status = model.RedPacketStatusDrawn
+	if err := repository.UpdateRedPacket(tx, rp); err != nil {
+		return err
+	}
+	data := redPacketEventData(rp)
+	data.RefundAmount = refund
+	data.EntryCount = uint32(len(entries))
+	data.WinnerCount = winnerCount
+	data.EntriesHash = rp.EntriesHash
+	if err := recordEvent(tx, event.RedPacketDrawn, data); err != nil {
+		return err
+	}
+	return refundToSender(tx, rp, refund, "抽奖红包未发出部分退款")
+}
+
+// winnerEligible 开奖时按领取的规则检查中奖者账户状态和当日领取限额，查询失败时返回 error
+func winnerEligible(tx *gorm.DB, rp *model.RedPacket, userID, amount uint64) (bool, error) {
+	if err := checkUserActive(userID); err != nil {
+		return false, nil
+	}
+	usedAmount, usedCount, err := receiveUsage(tx, userID, rp.Currency)
+	if err != nil {
+		return false, err
+	}
+	return checkDailyReceiveLimit(rp.Currency, usedAmount, usedCount, amount) == nil, nil
+}
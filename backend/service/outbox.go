@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+
+	"red-packet/model"
+	"red-packet/pkg/event"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+func redPacketEventData(rp *model.RedPacket) event.RedPacketData {
+	data := event.RedPacketData{
+		RedPacketID:     rp.ID,
+		PublicID:        rp.PublicID,
+		SenderID:        rp.SenderID,
+		PacketType:      rp.Type,
+		Currency:        rp.Currency,
+		TotalAmount:     rp.TotalAmount,
+		TotalCount:      rp.TotalCount,
+		RemainingAmount: rp.RemainingAmount,
+		RemainingCount:  rp.RemainingCount,
+		Status:          rp.Status,
+		SeedHash:        rp.SeedHash,
+	}
+	if rp.SeedRevealed() {
+		data.Seed = rp.Seed
+	}
+	if rp.CampaignID != nil {
+		data.CampaignID = *rp.CampaignID
+	}
+	return data
+}
+
+// recordEvent 在业务事务内写入发件箱，事务回滚时事件一并消失，提交后由调度任务投递
+func recordEvent(tx *gorm.DB, eventType string, data event.RedPacketData) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return repository.CreateOutboxEvent(tx, &model.OutboxEvent{
+		EventType:   eventType,
+		RedPacketID: data.RedPacketID,
+		Payload:     string(b),
+	})
+}
@@ -0,0 +1,506 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/fairsplit"
+	"red-packet/repository"
+	"red-packet/risk"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxRainDrops     = 100000 // 单场红包雨最多份数
+	maxRainQueue     = 20000  // 单场红包雨排队请求上限，超出直接拒绝
+	maxRainClaimWait = 30 * time.Second
+	rainPollInterval = 200 * time.Millisecond
+	rainScanLimit    = 50 // 每轮最多扫描的红包雨个数
+)
+
+var (
+	rainSecret    []byte
+	rainTicketTTL = 30 * time.Second
+	rainBatchSize = 200
+)
+
+var (
+	errInvalidRainTicket = errors.New("invalid rain ticket")
+	errRainTicketExpired = errors.New("rain ticket expired")
+)
+
+// InitRainService secret 用于签发领取券；batchSize 为 worker 每次锁定红包雨后处理的请求数
+func InitRainService(secret string, ticketTTL time.Duration, batchSize int) {
+	rainSecret = []byte(secret)
+	rainTicketTTL = ticketTTL
+	rainBatchSize = batchSize
+}
+
+type RainParams struct {
+	TotalAmount uint64
+	DropCount   uint32
+	PerUserCap  uint32 // 每人最多抢到的份数，0 时为 1
+	StartAt     time.Time
+	EndAt       time.Time
+}
+
+// RainClaimResult 领取请求及所属红包雨的币种
+type RainClaimResult struct {
+	*model.RainClaim
+	Currency string
+}
+
+// RainTicket 领取券，NotBefore 之前提交无效，ExpiresAt 后作废
+type RainTicket struct {
+	Ticket    string
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// CreateRain 从活动预算划出一场红包雨，时间窗口需落在活动期内；金额按种子拆分，结束后公开种子
+func CreateRain(admin AdminContext, campaignID uint64, params RainParams) (*model.Rain, error) {
+	if params.DropCount == 0 || params.DropCount > maxRainDrops {
+		return nil, errors.New("drop count must be between 1 and 100000")
+	}
+	if params.TotalAmount < uint64(params.DropCount) {
+		return nil, errors.New("total amount must be >= drop count (min 1 fen per drop)")
+	}
+	if !params.EndAt.After(params.StartAt) {
+		return nil, errors.New("end_at must be after start_at")
+	}
+	if !params.EndAt.After(time.Now()) {
+		return nil, errors.New("end_at must be in the future")
+	}
+	perUserCap := params.PerUserCap
+	if perUserCap == 0 {
+		perUserCap = 1
+	}
+
+	seed, err := fairsplit.NewSeed()
+	if err != nil {
+		return nil, err
+	}
+	seedHash, err := fairsplit.Commitment(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	var rain *model.Rain
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		c, err := repository.GetCampaignForUpdate(tx, campaignID)
+		if err != nil {
+			return errors.New("campaign not found")
+		}
+		if !time.Now().Before(c.EndAt) {
+			return errors.New("campaign has ended")
+		}
+		if params.StartAt.Before(c.StartAt) || params.EndAt.After(c.EndAt) {
+			return errors.New("rain must be within the campaign period")
+		}
+		if c.Balance < params.TotalAmount {
+			return errors.New("campaign budget exhausted")
+		}
+		c.Balance -= params.TotalAmount
+		if err := repository.UpdateCampaign(tx, c); err != nil {
+			return err
+		}
+
+		rain = &model.Rain{
+			CampaignID:      c.ID,
+			Currency:        c.Currency,
+			TotalAmount:     params.TotalAmount,
+			DropCount:       params.DropCount,
+			RemainingAmount: params.TotalAmount,
+			RemainingDrops:  params.DropCount,
+			PerUserCap:      perUserCap,
+			Seed:            seed,
+			SeedHash:        seedHash,
+			Status:          model.RainStatusActive,
+			StartAt:         params.StartAt,
+			EndAt:           params.EndAt,
+		}
+		if err := repository.CreateRain(tx, rain); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionCreateRain, "rain", rain.ID, map[string]interface{}{
+			"campaign_id":  c.ID,
+			"amount":       params.TotalAmount,
+			"currency":     c.Currency,
+			"drop_count":   params.DropCount,
+			"per_user_cap": perUserCap,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rain, nil
+}
+
+func GetRain(rainID uint64) (*model.Rain, error) {
+	rain, err := repository.GetRainByID(database.DB, rainID)
+	if err != nil {
+		return nil, errors.New("rain not found")
+	}
+	return rain, nil
+}
+
+// IssueRainTicket 签发领取券。风控在签券时检查，开抢后提交请求不再访问风控，
+// 开始前可预领，券从开始时间起生效
+func IssueRainTicket(rainID, userID uint64, meta RequestMeta) (*RainTicket, error) {
+	rain, err := GetRain(rainID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if rain.Status != model.RainStatusActive || rain.RemainingDrops == 0 || !now.Before(rain.EndAt) {
+		return nil, errors.New("rain is over")
+	}
+	if err := checkUserActive(userID); err != nil {
+		return nil, err
+	}
+	if err := checkRisk(risk.SceneClaim, userID, meta, 0, 0); err != nil {
+		return nil, err
+	}
+	// 已抢到和排队中的份数达到上限后不再签券，提交时还会在用户行锁内再检查一次
+	claimed, err := repository.CountUserRainClaims(database.DB, rainID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if claimed >= int64(rain.PerUserCap) {
+		return nil, errors.New("rain claim limit reached")
+	}
+
+	nbf := rain.StartAt
+	if now.After(nbf) {
+		nbf = now
+	}
+	exp := nbf.Add(rainTicketTTL)
+	if exp.After(rain.EndAt) {
+		exp = rain.EndAt
+	}
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &RainTicket{
+		Ticket:    encodeRainTicket(rainID, userID, rain.StartAt, exp, nonce),
+		NotBefore: time.Unix(rain.StartAt.Unix(), 0),
+		ExpiresAt: time.Unix(exp.Unix(), 0),
+	}, nil
+}
+
+// SubmitRainClaim 凭券入队，只做签名、限额和队列长度检查，不锁红包雨行，只锁提交者的用户行；结果由 worker 异步写入
+func SubmitRainClaim(rainID, userID uint64, ticket string) (*RainClaimResult, error) {
+	nonce, err := verifyRainTicket(rainID, userID, ticket)
+	if err != nil {
+		return nil, err
+	}
+	rain, err := GetRain(rainID)
+	if err != nil {
+		return nil, err
+	}
+	if rain.Status != model.RainStatusActive || rain.RemainingDrops == 0 {
+		return nil, errors.New("rain is over")
+	}
+	if used, err := repository.RainNonceUsed(database.DB, rainID, nonce); err != nil {
+		return nil, err
+	} else if used {
+		return nil, errors.New("ticket already used")
+	}
+	claim := &model.RainClaim{
+		RainID: rainID,
+		Nonce:  nonce,
+		UserID: userID,
+		Status: model.RainClaimQueued,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 券可以重复签发，锁用户行串行化同一用户的并发提交，排队中和已抢到的份数不会超过上限，
+		// 单个用户无法用多张券占满队列；最终以 worker 处理时的计数为准
+		if _, err := repository.GetUserForUpdate(tx, userID); err != nil {
+			return err
+		}
+		claimed, err := repository.CountUserRainClaims(tx, rainID, userID)
+		if err != nil {
+			return err
+		}
+		if claimed >= int64(rain.PerUserCap) {
+			return errors.New("rain claim limit reached")
+		}
+		queued, err := repository.CountQueuedRainClaims(tx, rainID)
+		if err != nil {
+			return err
+		}
+		if queued >= maxRainQueue {
+			return errors.New("rain queue is full")
+		}
+		return repository.CreateRainClaim(tx, claim)
+	})
+	if err != nil {
+		// 同一张券并发提交时由唯一索引兜底
+		if used, _ := repository.RainNonceUsed(database.DB, rainID, nonce); used {
+			return nil, errors.New("ticket already used")
+		}
+		return nil, err
+	}
+	return &RainClaimResult{RainClaim: claim, Currency: rain.Currency}, nil
+}
+
+// GetRainClaim 查询领取结果；wait > 0 时在请求仍排队期间轮询等待，最多 30 秒
+func GetRainClaim(rainID, claimID, userID uint64, wait time.Duration) (*RainClaimResult, error) {
+	rain, err := GetRain(rainID)
+	if err != nil {
+		return nil, err
+	}
+	if wait > maxRainClaimWait {
+		wait = maxRainClaimWait
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		claim, err := repository.GetRainClaim(database.DB, rainID, claimID)
+		if err != nil || claim.UserID != userID {
+			return nil, errors.New("rain claim not found")
+		}
+		if claim.Status != model.RainClaimQueued || !time.Now().Before(deadline) {
+			return &RainClaimResult{RainClaim: claim, Currency: rain.Currency}, nil
+		}
+		time.Sleep(rainPollInterval)
+	}
+}
+
+// ProcessRainClaims 处理各场红包雨排队中的请求，返回处理的请求数。
+// 多个 worker 并发调用时通过 SKIP LOCKED 各自认领不同的红包雨，同一场内严格按入队顺序分配
+func ProcessRainClaims() (int, error) {
+	ids, err := repository.GetRainIDsWithQueuedClaims(database.DB, rainScanLimit)
+	if err != nil {
+		return 0, err
+	}
+	processed := 0
+	for _, id := range ids {
+		n, err := processRainBatch(id)
+		if err != nil {
+			return processed, err
+		}
+		processed += n
+	}
+	return processed, nil
+}
+
+// processRainBatch 一个事务内处理一批请求：整批只锁一次红包雨行，
+// 同一用户本批抢到的金额合并入账，并按用户ID顺序加锁钱包，避免与其他事务死锁
+func processRainBatch(rainID uint64) (int, error) {
+	processed := 0
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		rain, err := repository.TryLockRain(tx, rainID)
+		if err != nil || rain == nil {
+			return err
+		}
+		claims, err := repository.GetQueuedRainClaims(tx, rainID, rainBatchSize)
+		if err != nil || len(claims) == 0 {
+			return err
+		}
+		userIDs := make([]uint64, 0, len(claims))
+		for _, c := range claims {
+			userIDs = append(userIDs, c.UserID)
+		}
+		wins, err := repository.CountRainWins(tx, rainID, userIDs)
+		if err != nil {
+			return err
+		}
+		usage, err := rainReceiveUsage(tx, userIDs, rain.Currency)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		payouts := make(map[uint64]uint64)
+		frozen := make(map[uint64]error)
+		for i := range claims {
+			c := &claims[i]
+			c.ProcessedAt = &now
+			c.Status = model.RainClaimFailed
+			if _, checked := frozen[c.UserID]; !checked {
+				frozen[c.UserID] = checkUserActive(c.UserID)
+			}
+			switch {
+			case rain.Status != model.RainStatusActive || rain.RemainingDrops == 0:
+				// 结算与入队不互斥，结算后才入队的请求在这里失败
+				c.Reason = "rain is over"
+			case wins[c.UserID] >= rain.PerUserCap:
+				c.Reason = "rain claim limit reached"
+			case frozen[c.UserID] != nil:
+				c.Reason = frozen[c.UserID].Error()
+			default:
+				seq := rain.DropCount - rain.RemainingDrops
+				amount, err := fairsplit.Share(rain.Seed, seq, rain.RemainingAmount, rain.RemainingDrops)
+				if err != nil {
+					return err
+				}
+				// 与领红包相同受当日领取限额约束，超出的请求不占用这一份，下一个请求仍从这一份开始
+				u := usage[c.UserID]
+				usedCount := u.count
+				if payouts[c.UserID] > 0 {
+					usedCount-- // 同一用户本批合并为一条流水，只计一次
+				}
+				if err := checkDailyReceiveLimit(rain.Currency, u.amount, usedCount, amount); err != nil {
+					c.Reason = err.Error()
+					break
+				}
+				u.amount += amount
+				if payouts[c.UserID] == 0 {
+					u.count++
+				}
+				c.Status = model.RainClaimSucceeded
+				c.Amount = amount
+				c.Seq = seq
+				rain.RemainingAmount -= amount
+				rain.RemainingDrops--
+				wins[c.UserID]++
+				payouts[c.UserID] += amount
+			}
+			if err := repository.UpdateRainClaim(tx, c); err != nil {
+				return err
+			}
+		}
+
+		winners := make([]uint64, 0, len(payouts))
+		for userID := range payouts {
+			winners = append(winners, userID)
+		}
+		sort.Slice(winners, func(i, j int) bool { return winners[i] < winners[j] })
+		for _, userID := range winners {
+			if _, err := credit(tx, userID, rain.Currency, payouts[userID], model.TransactionTypeRain, &rain.ID, "红包雨"); err != nil {
+				return err
+			}
+		}
+		processed = len(claims)
+		return repository.UpdateRain(tx, rain)
+	})
+	return processed, err
+}
+
+type rainUsage struct {
+	amount uint64
+	count  int64
+}
+
+// rainReceiveUsage 按用户ID顺序锁定本批领取者的用户行并查询当日领取用量，与其他领取事务的加锁顺序一致
+func rainReceiveUsage(tx *gorm.DB, userIDs []uint64, cur string) (map[uint64]*rainUsage, error) {
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	usage := make(map[uint64]*rainUsage, len(ids))
+	for _, id := range ids {
+		amount, count, err := receiveUsage(tx, id, cur)
+		if err != nil {
+			return nil, err
+		}
+		usage[id] = &rainUsage{amount: amount, count: count}
+	}
+	return usage, nil
+}
+
+// SettleRains 结算已结束或已抢完的红包雨：排队请求处理完后把剩余金额退回活动预算，返回结算的场数
+func SettleRains() (int, error) {
+	ids, err := repository.GetSettleableRainIDs(database.DB, time.Now(), rainScanLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, id := range ids {
+		queued, err := repository.CountQueuedRainClaims(database.DB, id)
+		if err != nil {
+			return settled, err
+		}
+		if queued > 0 {
+			continue
+		}
+		done := false
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			rain, err := repository.GetRainForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			if rain.Status != model.RainStatusActive {
+				return nil
+			}
+			// 未到结束时间的红包雨仍可能有新请求入队，加锁后再确认一次
+			if queued, err := repository.CountQueuedRainClaims(tx, id); err != nil || queued > 0 {
+				return err
+			}
+			if rain.RemainingAmount > 0 {
+				if err := repository.ReturnCampaignBudget(tx, rain.CampaignID, rain.RemainingAmount); err != nil {
+					return err
+				}
+			}
+			rain.Status = model.RainStatusFinished
+			done = true
+			return repository.UpdateRain(tx, rain)
+		})
+		if err != nil {
+			return settled, err
+		}
+		if done {
+			settled++
+		}
+	}
+	return settled, nil
+}
+
+// verifyRainTicket 券格式为 "用户ID.生效时间戳.过期时间戳.nonce.签名"，返回 nonce
+func verifyRainTicket(rainID, userID uint64, ticket string) (string, error) {
+	i := strings.LastIndex(ticket, ".")
+	if i < 0 {
+		return "", errInvalidRainTicket
+	}
+	payload, sig := ticket[:i], ticket[i+1:]
+	if !hmac.Equal([]byte(signRainTicket(rainID, payload)), []byte(sig)) {
+		return "", errInvalidRainTicket
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 || parts[0] != strconv.FormatUint(userID, 10) {
+		return "", errInvalidRainTicket
+	}
+	nbf, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", errInvalidRainTicket
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", errInvalidRainTicket
+	}
+	now := time.Now().Unix()
+	if now < nbf {
+		return "", errors.New("rain has not started")
+	}
+	if now > exp {
+		return "", errRainTicketExpired
+	}
+	return parts[3], nil
+}
+
+// encodeRainTicket 按 verifyRainTicket 的格式拼接并签名领取券
+func encodeRainTicket(rainID, userID uint64, nbf, exp time.Time, nonce []byte) string {
+	payload := strconv.FormatUint(userID, 10) + "." +
+		strconv.FormatInt(nbf.Unix(), 10) + "." +
+		strconv.FormatInt(exp.Unix(), 10) + "." +
+		base64.RawURLEncoding.EncodeToString(nonce)
+	return payload + "." + signRainTicket(rainID, payload)
+}
+
+func signRainTicket(rainID uint64, payload string) string {
+	mac := hmac.New(sha256.New, rainSecret)
+	mac.Write([]byte("rain:" + strconv.FormatUint(rainID, 10) + "." + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:shareMACBytes])
+}
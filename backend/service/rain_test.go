@@ -0,0 +1,237 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/repository"
+)
+
+// 领取券绑定红包雨和用户，签名覆盖生效和过期时间，任何一处被改动都校验失败
+func TestRainTicketSignAndVerify(t *testing.T) {
+	prev := rainSecret
+	rainSecret = []byte("test-rain-secret")
+	defer func() { rainSecret = prev }()
+
+	const rainID, userID = 7, 42
+	now := time.Now()
+	nonce := []byte("0123456789ab")
+	wantNonce := base64.RawURLEncoding.EncodeToString(nonce)
+	valid := encodeRainTicket(rainID, userID, now.Add(-time.Second), now.Add(time.Minute), nonce)
+
+	got, err := verifyRainTicket(rainID, userID, valid)
+	if err != nil || got != wantNonce {
+		t.Fatalf("valid ticket: nonce %q err %v, want %q", got, err, wantNonce)
+	}
+
+	// 改动载荷中的过期时间，签名不变
+	parts := strings.Split(valid, ".")
+	parts[2] = fmt.Sprint(now.Add(time.Hour).Unix())
+	extended := strings.Join(parts, ".")
+
+	sig := valid[strings.LastIndex(valid, ".")+1:]
+	flipped := "A"
+	if sig[0] == 'A' {
+		flipped = "B"
+	}
+	badSig := valid[:len(valid)-len(sig)] + flipped + sig[1:]
+
+	rainSecret = []byte("other-secret")
+	otherSecret := encodeRainTicket(rainID, userID, now.Add(-time.Second), now.Add(time.Minute), nonce)
+	rainSecret = []byte("test-rain-secret")
+
+	cases := []struct {
+		name    string
+		rainID  uint64
+		userID  uint64
+		ticket  string
+		wantErr string
+	}{
+		{"other rain", rainID + 1, userID, valid, errInvalidRainTicket.Error()},
+		{"other user", rainID, userID + 1, valid, errInvalidRainTicket.Error()},
+		{"tampered payload", rainID, userID, extended, errInvalidRainTicket.Error()},
+		{"tampered signature", rainID, userID, badSig, errInvalidRainTicket.Error()},
+		{"other secret", rainID, userID, otherSecret, errInvalidRainTicket.Error()},
+		{"no signature", rainID, userID, "garbage", errInvalidRainTicket.Error()},
+		{"expired", rainID, userID, encodeRainTicket(rainID, userID, now.Add(-time.Minute), now.Add(-time.Second), nonce), errRainTicketExpired.Error()},
+		{"not started", rainID, userID, encodeRainTicket(rainID, userID, now.Add(time.Minute), now.Add(2*time.Minute), nonce), "rain has not started"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := verifyRainTicket(tc.rainID, tc.userID, tc.ticket)
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("err = %v, want %s", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// newTestRain 创建进行中的活动并从预算划出一场红包雨，活动预算恰好用完
+func newTestRain(tb testing.TB, amount uint64, drops, perUserCap uint32) *model.Rain {
+	tb.Helper()
+	admin := AdminContext{AdminID: newTestUser(tb, 0).ID}
+	now := time.Now()
+	c, err := CreateCampaign(admin, CampaignParams{
+		Name:    "test rain",
+		Budget:  amount,
+		StartAt: now.Add(-time.Minute),
+		EndAt:   now.Add(time.Hour),
+	})
+	if err != nil {
+		tb.Fatalf("create campaign: %v", err)
+	}
+	rain, err := CreateRain(admin, c.ID, RainParams{
+		TotalAmount: amount,
+		DropCount:   drops,
+		PerUserCap:  perUserCap,
+		StartAt:     now.Add(-time.Minute),
+		EndAt:       now.Add(time.Hour),
+	})
+	if err != nil {
+		tb.Fatalf("create rain: %v", err)
+	}
+	return rain
+}
+
+// queueRainClaim 跳过领取券直接入队一个请求
+func queueRainClaim(tb testing.TB, rainID, userID uint64) *model.RainClaim {
+	tb.Helper()
+	c := &model.RainClaim{
+		RainID: rainID,
+		Nonce:  fmt.Sprintf("%x_%d", time.Now().UnixNano(), testUserSeq.Add(1)),
+		UserID: userID,
+		Status: model.RainClaimQueued,
+	}
+	if err := repository.CreateRainClaim(database.DB, c); err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// 一批请求按入队顺序分配：同一用户抢到的多份合并为一条流水且只计一次领取次数，
+// 超出当日领取限额的请求不占用份额，下一个请求从同一份开始；结算后剩余金额退回活动预算，之后入队的请求失败
+func TestProcessRainBatchAndSettle(t *testing.T) {
+	requireTestDB(t)
+	rain := newTestRain(t, 1000, 10, 2)
+	alice, bob, carol := newTestUser(t, 0), newTestUser(t, 0), newTestUser(t, 0)
+
+	// carol 今天已领过一个红包，领取次数上限为 1 时红包雨的请求被拒
+	sender := newTestUser(t, 100)
+	rp, err := SendRedPacket(SendRedPacketParams{SenderID: sender.ID, Type: model.RedPacketTypeNormal, TotalAmount: 100, TotalCount: 1})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: carol.ID}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	prev := limits
+	limits = map[string]Limit{rain.Currency: {DailyReceiveCount: 1}}
+	defer func() { limits = prev }()
+
+	claims := []*model.RainClaim{
+		queueRainClaim(t, rain.ID, alice.ID),
+		queueRainClaim(t, rain.ID, carol.ID),
+		queueRainClaim(t, rain.ID, bob.ID),
+		queueRainClaim(t, rain.ID, alice.ID),
+		queueRainClaim(t, rain.ID, alice.ID),
+	}
+	n, err := processRainBatch(rain.ID)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if n != len(claims) {
+		t.Fatalf("processed %d claims, want %d", n, len(claims))
+	}
+
+	got := make([]*model.RainClaim, len(claims))
+	for i, c := range claims {
+		if got[i], err = repository.GetRainClaim(database.DB, rain.ID, c.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []struct {
+		status int8
+		seq    uint32
+		reason string
+	}{
+		{model.RainClaimSucceeded, 0, ""},
+		{model.RainClaimFailed, 0, "daily receive count limit exceeded"},
+		{model.RainClaimSucceeded, 1, ""},
+		{model.RainClaimSucceeded, 2, ""},
+		{model.RainClaimFailed, 0, "rain claim limit reached"},
+	}
+	var paid uint64
+	for i, w := range want {
+		c := got[i]
+		if c.Status != w.status || c.Seq != w.seq || c.Reason != w.reason {
+			t.Errorf("claim %d: status %d seq %d reason %q, want %d %d %q", i, c.Status, c.Seq, c.Reason, w.status, w.seq, w.reason)
+		}
+		if (c.Status == model.RainClaimSucceeded) != (c.Amount > 0) {
+			t.Errorf("claim %d: status %d with amount %d", i, c.Status, c.Amount)
+		}
+		paid += c.Amount
+	}
+
+	aliceWon := got[0].Amount + got[3].Amount
+	if b := balanceOf(t, alice.ID); b != aliceWon {
+		t.Errorf("alice balance = %d, want %d", b, aliceWon)
+	}
+	if n := countRows(t, &model.Transaction{}, "user_id = ? AND type = ?", alice.ID, model.TransactionTypeRain); n != 1 {
+		t.Errorf("alice has %d rain transactions, want 1 merged", n)
+	}
+	if b := balanceOf(t, bob.ID); b != got[2].Amount {
+		t.Errorf("bob balance = %d, want %d", b, got[2].Amount)
+	}
+	if b := balanceOf(t, carol.ID); b != 100 {
+		t.Errorf("carol balance = %d, want 100 from the red packet only", b)
+	}
+
+	after, err := repository.GetRainByID(database.DB, rain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.RemainingDrops != 7 || after.RemainingAmount != 1000-paid {
+		t.Fatalf("rain remaining %d/%d, want 7/%d", after.RemainingDrops, after.RemainingAmount, 1000-paid)
+	}
+
+	if err := database.DB.Model(&model.Rain{}).Where("id = ?", rain.ID).
+		Update("end_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	for {
+		n, err := SettleRains()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	after, err = repository.GetRainByID(database.DB, rain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Status != model.RainStatusFinished {
+		t.Errorf("rain status = %d, want finished", after.Status)
+	}
+	if b := campaignBalance(t, rain.CampaignID); b != 1000-paid {
+		t.Errorf("campaign balance = %d, want %d returned", b, 1000-paid)
+	}
+
+	late := queueRainClaim(t, rain.ID, bob.ID)
+	if _, err := processRainBatch(rain.ID); err != nil {
+		t.Fatal(err)
+	}
+	c, err := repository.GetRainClaim(database.DB, rain.ID, late.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status != model.RainClaimFailed || c.Reason != "rain is over" {
+		t.Errorf("claim after settlement: status %d reason %q, want failed rain is over", c.Status, c.Reason)
+	}
+}
@@ -1,42 +1,95 @@
 package service
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"math/rand"
 	"time"
 
 	"red-packet/database"
 	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/pkg/event"
+	"red-packet/pkg/fairsplit"
+	"red-packet/pkg/pagination"
 	"red-packet/repository"
+	"red-packet/risk"
 
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type SendRedPacketParams struct {
-	SenderID    uint64
-	Type        int8
-	TotalAmount uint64
-	TotalCount  uint32
+	SenderID     uint64
+	Type         int8
+	TotalAmount  uint64
+	TotalCount   uint32
+	Currency     string       // 币种，为空取默认币种
+	OpenAt       *time.Time   // 定时开启时间，为空表示立即开启
+	Secret       string       // 口令，为空表示普通红包
+	RequireShare bool         // 只允许通过分享链接领取
+	DrawAt       *time.Time   // 抽奖红包的开奖时间，其他类型忽略
+	Allocations  []Allocation // 指定金额红包的领取人及金额，其他类型必须为空
+	Meta         RequestMeta
 }
 
+// Allocation 指定金额红包中一个领取人的金额
+type Allocation struct {
+	ReceiverID uint64
+	Amount     uint64
+}
+
+type ClaimRedPacketParams struct {
+	RedPacketID uint64
+	ReceiverID  uint64
+	Secret      string // 口令红包需提交的口令
+	ShareToken  string // 分享链接中的令牌，要求分享链接的红包必填
+	Meta        RequestMeta
+
+	secretVerified bool // 口令已在事务外校验通过
+}
+
+const (
+	redPacketTTL       = 24 * time.Hour      // 红包有效期，从开启时间算起
+	maxScheduleAhead   = 30 * 24 * time.Hour // 定时红包最多提前 30 天设置
+	activateBatchLimit = 100
+	maxSecretAttempts  = 5                  // 每人每个口令红包最多可猜错次数
+	maxLotteryWindow   = 7 * 24 * time.Hour // 抽奖红包报名时长上限，从开启时间算起
+)
+
 type RedPacketDetail struct {
 	*model.RedPacket
 	SenderName   string
 	ClaimedCount int64
 	MyClaim      *MyClaim
+	EntryCount   int64                      // 抽奖红包报名人数
+	MyEntry      *model.RedPacketEntry      // 抽奖红包中当前用户的报名，未报名为 nil
+	MyAllocation *model.RedPacketAllocation // 指定金额红包中分配给当前用户的份额，不是指定领取人为 nil
 }
 
 type MyClaim struct {
-	Claimed   bool      `json:"claimed"`
-	Amount    uint64    `json:"amount,omitempty"`
-	ClaimedAt time.Time `json:"claimed_at,omitempty"`
+	Claimed   bool
+	Amount    uint64
+	ClaimedAt time.Time
 }
 
 type RecordItem struct {
-	ReceiverID   uint64    `json:"receiver_id"`
-	ReceiverName string    `json:"receiver_name"`
-	Amount       uint64    `json:"amount"`
-	ClaimedAt    time.Time `json:"claimed_at"`
+	ReceiverID   uint64
+	ReceiverName string
+	Amount       uint64
+	ClaimedAt    time.Time
+}
+
+type ReceivedItem struct {
+	RedPacketID uint64
+	PublicID    string
+	SenderName  string
+	Currency    string
+	Amount      uint64
+	ClaimedAt   time.Time
 }
 
 func SendRedPacket(params SendRedPacketParams) (*model.RedPacket, error) {
@@ -44,23 +97,82 @@ func SendRedPacket(params SendRedPacketParams) (*model.RedPacket, error) {
 		return nil, errors.New("total amount must be >= total count (min 1 fen per person)")
 	}
 
+	cur, err := currency.Normalize(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPacketAmount(cur, params.TotalAmount); err != nil {
+		return nil, err
+	}
+
+	if err := checkUserActive(params.SenderID); err != nil {
+		return nil, err
+	}
+	if err := checkAllocations(params); err != nil {
+		return nil, err
+	}
+	if err := checkRisk(risk.SceneSend, params.SenderID, params.Meta, params.TotalAmount, 0); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	openAt := now
+	status := int8(model.RedPacketStatusActive)
+	if params.OpenAt != nil && params.OpenAt.After(now) {
+		if params.OpenAt.Sub(now) > maxScheduleAhead {
+			return nil, errors.New("open_at is too far in the future")
+		}
+		openAt = *params.OpenAt
+		status = model.RedPacketStatusPending
+	}
+
+	// 抽奖红包在开奖前一直可报名，过期时间顺延到开奖之后，只在开奖任务长时间未执行时兜底退款
+	expiredAt := openAt.Add(redPacketTTL)
+	var drawAt *time.Time
+	if params.Type == model.RedPacketTypeLottery {
+		if params.DrawAt == nil || !params.DrawAt.After(openAt) {
+			return nil, errors.New("draw_at must be after open time")
+		}
+		if params.DrawAt.Sub(openAt) > maxLotteryWindow {
+			return nil, errors.New("draw_at is too far after open time")
+		}
+		drawAt = params.DrawAt
+		expiredAt = drawAt.Add(redPacketTTL)
+	}
+
+	var secretHash string
+	if params.Secret != "" {
+		if secretHash, err = hashSecret(params.Secret); err != nil {
+			return nil, err
+		}
+	}
+
+	// 拼手气、抽奖红包发出时生成种子，只公开其哈希
+	var seed, seedHash string
+	if params.Type == model.RedPacketTypeLucky || params.Type == model.RedPacketTypeLottery {
+		if seed, err = fairsplit.NewSeed(); err != nil {
+			return nil, err
+		}
+		if seedHash, err = fairsplit.Commitment(seed); err != nil {
+			return nil, err
+		}
+	}
+
 	var redPacket *model.RedPacket
 
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// 扣减发送者余额
-		if err := repository.DeductUserBalance(tx, params.SenderID, params.TotalAmount); err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkDailySendLimit(tx, params.SenderID, cur, params.TotalAmount); err != nil {
 			return err
 		}
 
-		// 验证余额是否足够（受影响行数为 0 说明余额不足）
-		senderBalance, err := repository.GetUserBalanceInTx(tx, params.SenderID)
-		if err != nil {
+		// 扣减发送者余额（受影响行数为 0 说明余额不足）
+		if err := repository.DeductUserBalance(tx, params.SenderID, cur, params.TotalAmount); err != nil {
 			return err
 		}
-		_ = senderBalance
 
 		// 写流水：支出
-		balanceAfter, err := repository.GetUserBalanceInTx(tx, params.SenderID)
+		balanceAfter, err := repository.GetUserBalance(tx, params.SenderID, cur)
 		if err != nil {
 			return err
 		}
@@ -68,6 +180,7 @@ func SendRedPacket(params SendRedPacketParams) (*model.RedPacket, error) {
 			UserID:       params.SenderID,
 			Type:         model.TransactionTypeSend,
 			Direction:    model.TransactionDirectionOut,
+			Currency:     cur,
 			Amount:       params.TotalAmount,
 			BalanceAfter: balanceAfter,
 			Remark:       "发红包",
@@ -76,21 +189,47 @@ func SendRedPacket(params SendRedPacketParams) (*model.RedPacket, error) {
 		rp := &model.RedPacket{
 			SenderID:        params.SenderID,
 			Type:            params.Type,
+			Currency:        cur,
 			TotalAmount:     params.TotalAmount,
 			TotalCount:      params.TotalCount,
 			RemainingAmount: params.TotalAmount,
 			RemainingCount:  params.TotalCount,
-			Status:          model.RedPacketStatusActive,
-			ExpiredAt:       time.Now().Add(24 * time.Hour),
+			Status:          status,
+			HasSecret:       params.Secret != "",
+			RequireShare:    params.RequireShare,
+			Seed:            seed,
+			SeedHash:        seedHash,
+			SecretHash:      secretHash,
+			OpenAt:          openAt,
+			DrawAt:          drawAt,
+			ExpiredAt:       expiredAt,
 		}
-		if err := repository.CreateRedPacket(rp); err != nil {
+		if err := repository.CreateRedPacket(tx, rp); err != nil {
 			return err
 		}
 
+		if len(params.Allocations) > 0 {
+			list := make([]model.RedPacketAllocation, 0, len(params.Allocations))
+			for _, a := range params.Allocations {
+				list = append(list, model.RedPacketAllocation{
+					RedPacketID: rp.ID,
+					ReceiverID:  a.ReceiverID,
+					Amount:      a.Amount,
+					Status:      model.AllocationStatusPending,
+				})
+			}
+			if err := repository.CreateRedPacketAllocations(tx, list); err != nil {
+				return err
+			}
+		}
+
 		txRecord.RelatedID = &rp.ID
 		if err := repository.CreateTransaction(tx, txRecord); err != nil {
 			return err
 		}
+		if err := recordEvent(tx, event.RedPacketSent, redPacketEventData(rp)); err != nil {
+			return err
+		}
 
 		redPacket = rp
 		return nil
@@ -99,123 +238,399 @@ func SendRedPacket(params SendRedPacketParams) (*model.RedPacket, error) {
 	return redPacket, err
 }
 
-func ClaimRedPacket(redPacketID, receiverID uint64) (uint64, error) {
+func ClaimRedPacket(params ClaimRedPacketParams) (uint64, error) {
+	if err := checkUserActive(params.ReceiverID); err != nil {
+		return 0, err
+	}
+	if err := checkRisk(risk.SceneClaim, params.ReceiverID, params.Meta, 0, params.RedPacketID); err != nil {
+		return 0, err
+	}
+
+	if err := verifyClaimSecret(&params); err != nil {
+		return 0, err
+	}
+
 	var claimedAmount uint64
+	var opened bool
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		claimedAmount, opened, err = claimRedPacket(tx, params)
+		return err
+	})
+	if err != nil {
+		return 0, busyIfRetryable(err)
+	}
+	if opened {
+		event.Publish(event.Event{Type: event.RedPacketOpened, RedPacketID: params.RedPacketID})
+	}
+	return claimedAmount, nil
+}
 
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		// 加行锁，防止并发超发
-		rp, err := repository.GetRedPacketForUpdate(tx, redPacketID)
-		if err != nil {
-			return errors.New("red packet not found")
-		}
+// busyIfRetryable 重试用尽仍冲突时换成业务错误，不把数据库错误原样返回给客户端
+func busyIfRetryable(err error) error {
+	if database.IsRetryable(err) {
+		return errors.New("red packet is busy")
+	}
+	return err
+}
 
-		// 状态校验
-		if rp.Status != model.RedPacketStatusActive {
-			if rp.Status == model.RedPacketStatusEmpty {
-				return errors.New("red packet is empty")
-			}
-			return errors.New("red packet is expired")
+// claimRedPacket 领取的事务部分：锁红包行、校验、分配金额并入账，返回领取金额，
+// 以及定时红包是否由本次领取开启（调用方在提交后发布开启事件）。风控和账户状态由调用方在事务外检查
+func claimRedPacket(tx *gorm.DB, params ClaimRedPacketParams) (uint64, bool, error) {
+	redPacketID, receiverID := params.RedPacketID, params.ReceiverID
+
+	// 悲观策略在这里加行锁，其他策略在写回时检测并发冲突，防止超发
+	rp, err := loadRedPacketForClaim(tx, redPacketID, receiverID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, errors.New("red packet not found")
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if rp.Type == model.RedPacketTypeLottery {
+		return 0, false, errors.New("lottery red packet requires entry")
+	}
+
+	// 定时红包：未到开启时间不可领取；已到时间但调度尚未处理的，由本次领取开启，并与调度任务一样记录开启事件
+	opened := false
+	if rp.Status == model.RedPacketStatusPending {
+		now := time.Now()
+		if now.Before(rp.OpenAt) {
+			return 0, false, errors.New("red packet is not open yet")
 		}
-		if time.Now().After(rp.ExpiredAt) {
-			return errors.New("red packet is expired")
+		if opened, err = repository.OpenRedPacket(tx, rp.ID, now); err != nil {
+			return 0, false, err
 		}
+		if !opened {
+			// 读到的是旧快照，已被调度任务或其他领取开启，重试时重新读取
+			return 0, false, database.ErrConflict
+		}
+		rp.Status = model.RedPacketStatusActive
+		if err := recordEvent(tx, event.RedPacketOpened, redPacketEventData(rp)); err != nil {
+			return 0, false, err
+		}
+	}
 
-		// 检查是否已领取
-		_, err = repository.GetRedPacketRecord(redPacketID, receiverID)
-		if err == nil {
-			return errors.New("already claimed")
+	// 状态校验
+	if rp.Status != model.RedPacketStatusActive {
+		if rp.Status == model.RedPacketStatusEmpty {
+			return 0, false, errors.New("red packet is empty")
 		}
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return err
+		return 0, false, errors.New("red packet is expired")
+	}
+	if time.Now().After(rp.ExpiredAt) {
+		return 0, false, errors.New("red packet is expired")
+	}
+	if rp.RequireShare {
+		if err := VerifyShareToken(rp.PublicID, params.ShareToken); err != nil {
+			return 0, false, err
 		}
+	}
+
+	// 锁用户行后以锁定读统计当日已领取额度，不受此前普通读建立的快照影响
+	usedAmount, usedCount, err := receiveUsage(tx, receiverID, rp.Currency)
+	if err != nil {
+		return 0, false, err
+	}
+	// 检查是否已领取：与其他读取一样走事务连接，悲观策略下看到的是红包加锁后的快照
+	_, err = repository.GetRedPacketRecord(tx, redPacketID, receiverID)
+	if err == nil {
+		return 0, false, errors.New("already claimed")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, err
+	}
+	if err := checkCampaignClaim(tx, rp, receiverID); err != nil {
+		return 0, false, err
+	}
 
-		// 计算本次领取金额
-		amount := calcClaimAmount(rp)
-		claimedAmount = amount
+	if rp.HasSecret && !params.secretVerified {
+		return 0, false, errors.New("wrong secret")
+	}
 
-		// 更新红包剩余
-		rp.RemainingAmount -= amount
-		rp.RemainingCount--
-		if rp.RemainingCount == 0 {
-			rp.Status = model.RedPacketStatusEmpty
+	// 计算本次领取金额：指定金额红包取发送者预先分配的份额
+	var allocation *model.RedPacketAllocation
+	var amount uint64
+	if rp.Type == model.RedPacketTypeAssigned {
+		allocation, err = repository.GetRedPacketAllocation(tx, redPacketID, receiverID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, errors.New("not a designated receiver")
 		}
-		if err := repository.UpdateRedPacket(tx, rp); err != nil {
-			return err
+		if err != nil {
+			return 0, false, err
 		}
+		amount = allocation.Amount
+	} else if amount, err = calcClaimAmount(rp); err != nil {
+		return 0, false, err
+	}
+	if err := checkDailyReceiveLimit(rp.Currency, usedAmount, usedCount, amount); err != nil {
+		return 0, false, err
+	}
 
-		// 写领取记录
-		record := &model.RedPacketRecord{
-			RedPacketID: redPacketID,
-			ReceiverID:  receiverID,
-			Amount:      amount,
+	// 更新红包剩余
+	checked := amount
+	seq, amount, err := takeShare(tx, rp, amount)
+	if err != nil {
+		return 0, false, err
+	}
+	// conditional 策略实际拿到最后一份时金额换成尾差，比校验时多，按最终金额再校验一次；超出时事务回滚，扣减一并撤销
+	if amount != checked {
+		if err := checkDailyReceiveLimit(rp.Currency, usedAmount, usedCount, amount); err != nil {
+			return 0, false, err
 		}
-		if err := repository.CreateRedPacketRecord(tx, record); err != nil {
-			return err
+	}
+
+	// 写领取记录
+	record := &model.RedPacketRecord{
+		RedPacketID: redPacketID,
+		ReceiverID:  receiverID,
+		Amount:      amount,
+		Seq:         seq,
+	}
+	if err := repository.CreateRedPacketRecord(tx, record); err != nil {
+		return 0, false, err
+	}
+	if allocation != nil {
+		now := time.Now()
+		allocation.Status = model.AllocationStatusClaimed
+		allocation.ClaimedAt = &now
+		if err := repository.UpdateRedPacketAllocation(tx, allocation); err != nil {
+			return 0, false, err
 		}
+	}
 
-		// 增加领取者余额
-		if err := repository.AddUserBalance(tx, receiverID, amount); err != nil {
-			return err
+	if err := creditReceiver(tx, rp, receiverID, amount, "领红包"); err != nil {
+		return 0, false, err
+	}
+
+	data := redPacketEventData(rp)
+	data.ReceiverID, data.Amount = receiverID, amount
+	if err := recordEvent(tx, event.RedPacketClaimed, data); err != nil {
+		return 0, false, err
+	}
+	if rp.Status == model.RedPacketStatusEmpty {
+		if err := recordEvent(tx, event.RedPacketEmptied, data); err != nil {
+			return 0, false, err
+		}
+	}
+	return amount, opened, nil
+}
+
+// checkAllocations 指定金额红包的份额数须等于红包个数、金额之和等于总额，领取人不重复、存在且不是发送者
+func checkAllocations(params SendRedPacketParams) error {
+	if params.Type != model.RedPacketTypeAssigned {
+		if len(params.Allocations) > 0 {
+			return errors.New("allocations are only allowed for assigned red packets")
+		}
+		return nil
+	}
+	if len(params.Allocations) != int(params.TotalCount) {
+		return errors.New("allocation count must equal total count")
+	}
+
+	var sum uint64
+	ids := make([]uint64, 0, len(params.Allocations))
+	seen := make(map[uint64]bool, len(params.Allocations))
+	for _, a := range params.Allocations {
+		if a.Amount == 0 {
+			return errors.New("allocation amount must be positive")
+		}
+		if a.ReceiverID == params.SenderID {
+			return errors.New("cannot allocate to yourself")
 		}
+		if seen[a.ReceiverID] {
+			return errors.New("duplicate receiver in allocations")
+		}
+		seen[a.ReceiverID] = true
+		ids = append(ids, a.ReceiverID)
+		if sum+a.Amount < sum {
+			return errors.New("allocation amounts must sum to total amount")
+		}
+		sum += a.Amount
+	}
+	if sum != params.TotalAmount {
+		return errors.New("allocation amounts must sum to total amount")
+	}
+
+	names, err := repository.GetUsernamesByIDs(database.DB, ids)
+	if err != nil {
+		return err
+	}
+	if len(names) != len(ids) {
+		return errors.New("receiver not found")
+	}
+	return nil
+}
 
-		// 写流水：收入
-		balanceAfter, err := repository.GetUserBalanceInTx(tx, receiverID)
+// creditReceiver 增加领取者余额并写收入流水，入账币种与红包币种一致
+func creditReceiver(tx *gorm.DB, rp *model.RedPacket, receiverID, amount uint64, remark string) error {
+	redPacketID := rp.ID
+	_, err := credit(tx, receiverID, rp.Currency, amount, model.TransactionTypeReceive, &redPacketID, remark)
+	return err
+}
+
+// verifyClaimSecret 口令红包在进入领取事务前校验口令，通过后标记到 params 上
+func verifyClaimSecret(params *ClaimRedPacketParams) error {
+	rp, err := repository.GetRedPacketByID(database.DB, params.RedPacketID)
+	if err != nil {
+		return errors.New("red packet not found")
+	}
+	if err := verifySecret(rp, params.ReceiverID, params.Secret); err != nil {
+		return err
+	}
+	params.secretVerified = true
+	return nil
+}
+
+// verifySecret 口令校验：先原子地占用一次尝试次数并提交，超过上限直接拒绝，口令正确时退回这次次数。
+// 并发猜测各自占用次数，同时进行的尝试也不会超过上限
+func verifySecret(rp *model.RedPacket, userID uint64, secret string) error {
+	if !rp.HasSecret {
+		return nil
+	}
+	attempts, err := repository.TakeSecretAttempt(database.DB, rp.ID, userID)
+	if err != nil {
+		return err
+	}
+	if attempts > maxSecretAttempts {
+		return errors.New("too many wrong secret attempts")
+	}
+	if !matchSecret(rp, secret) {
+		return errors.New("wrong secret")
+	}
+	return repository.ReturnSecretAttempt(database.DB, rp.ID, userID)
+}
+
+// hashSecret 口令用 bcrypt 存储。口令最长 32 个字符，UTF-8 下可能超过 bcrypt 的 72 字节上限，先取 SHA-256 摘要
+func hashSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(secretDigest(secret), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// matchSecret 比对口令；secret_salt 非空的是旧版加盐 SHA-256 存储的红包
+func matchSecret(rp *model.RedPacket, secret string) bool {
+	if rp.SecretSalt != "" {
+		sum := sha256.Sum256([]byte(rp.SecretSalt + secret))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(rp.SecretHash)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(rp.SecretHash), secretDigest(secret)) == nil
+}
+
+func secretDigest(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return []byte(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+// ActivateDueRedPackets 将已到开启时间的定时红包置为可领取，返回本次开启的数量
+func ActivateDueRedPackets() (int, error) {
+	ids, err := repository.GetDuePendingRedPacketIDs(database.DB, time.Now(), activateBatchLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	activated := 0
+	for _, id := range ids {
+		opened := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			rp, err := repository.GetRedPacketForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			// 加锁后再确认一次，可能已被领取流程置为可领取
+			if rp.Status != model.RedPacketStatusPending {
+				return nil
+			}
+			rp.Status = model.RedPacketStatusActive
+			opened = true
+			if err := repository.UpdateRedPacket(tx, rp); err != nil {
+				return err
+			}
+			return recordEvent(tx, event.RedPacketOpened, redPacketEventData(rp))
+		})
 		if err != nil {
-			return err
+			return activated, err
 		}
-		txRecord := &model.Transaction{
-			UserID:       receiverID,
-			Type:         model.TransactionTypeReceive,
-			Direction:    model.TransactionDirectionIn,
-			Amount:       amount,
-			BalanceAfter: balanceAfter,
-			RelatedID:    &redPacketID,
-			Remark:       "领红包",
+		if opened {
+			activated++
+			event.Publish(event.Event{Type: event.RedPacketOpened, RedPacketID: id})
 		}
-		return repository.CreateTransaction(tx, txRecord)
-	})
+	}
+	return activated, nil
+}
+
+// ExpireRedPackets 结算已过期的红包：置为已过期并把剩余金额退回发送者，返回本次处理的数量
+func ExpireRedPackets() (int, error) {
+	ids, err := repository.GetExpiredRedPacketIDs(database.DB, time.Now(), activateBatchLimit)
+	if err != nil {
+		return 0, err
+	}
 
-	return claimedAmount, err
+	expired := 0
+	for _, id := range ids {
+		refunded := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			rp, err := repository.GetRedPacketForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			// 加锁后再确认一次，最后一个名额可能刚被领走
+			if rp.Status != model.RedPacketStatusActive && rp.Status != model.RedPacketStatusPending {
+				return nil
+			}
+			refunded = true
+			return refundRedPacket(tx, rp, model.RedPacketStatusExpired, "红包过期退款")
+		})
+		if err != nil {
+			return expired, err
+		}
+		if refunded {
+			expired++
+		}
+	}
+	return expired, nil
 }
 
 // calcClaimAmount 计算本次领取金额
-// 普通红包：等额分配；拼手气红包：随机分配（保证最后一人也有得拿）
-func calcClaimAmount(rp *model.RedPacket) uint64 {
+// 普通红包：等额分配；拼手气红包：按种子和领取顺序确定性拆分（保证最后一人也有得拿）
+func calcClaimAmount(rp *model.RedPacket) (uint64, error) {
 	if rp.RemainingCount == 1 {
-		return rp.RemainingAmount
+		return rp.RemainingAmount, nil
 	}
 	if rp.Type == model.RedPacketTypeNormal {
-		return rp.TotalAmount / uint64(rp.TotalCount)
+		return rp.TotalAmount / uint64(rp.TotalCount), nil
+	}
+	if rp.Seed != "" {
+		return fairsplit.Share(rp.Seed, rp.TotalCount-rp.RemainingCount, rp.RemainingAmount, rp.RemainingCount)
 	}
-	// 拼手气：随机金额，范围 [1, 剩余均值*2-1]
+	// 上线前发出、没有种子的拼手气红包：随机金额，范围 [1, 剩余均值*2-1]
 	maxAmount := rp.RemainingAmount/uint64(rp.RemainingCount)*2 - 1
 	if maxAmount < 1 {
 		maxAmount = 1
 	}
-	return uint64(rand.Int63n(int64(maxAmount))) + 1
+	return uint64(rand.Int63n(int64(maxAmount))) + 1, nil
 }
 
 func GetRedPacketDetail(redPacketID, currentUserID uint64) (*RedPacketDetail, error) {
-	rp, err := repository.GetRedPacketByID(redPacketID)
+	rp, err := repository.GetRedPacketByID(database.DB, redPacketID)
 	if err != nil {
 		return nil, errors.New("red packet not found")
 	}
 
-	sender, err := repository.GetUserByID(rp.SenderID)
+	senderName, err := packetSenderName(rp)
 	if err != nil {
 		return nil, err
 	}
 
-	claimedCount, _ := repository.CountRedPacketClaimed(redPacketID)
+	claimedCount, _ := repository.CountRedPacketClaimed(database.DB, redPacketID)
 
 	detail := &RedPacketDetail{
 		RedPacket:    rp,
-		SenderName:   sender.Username,
+		SenderName:   senderName,
 		ClaimedCount: claimedCount,
 	}
 
 	// 查询当前用户的领取情况
-	record, err := repository.GetRedPacketRecord(redPacketID, currentUserID)
+	record, err := repository.GetRedPacketRecord(database.DB, redPacketID, currentUserID)
 	if err == nil {
 		detail.MyClaim = &MyClaim{
 			Claimed:   true,
@@ -226,59 +641,99 @@ func GetRedPacketDetail(redPacketID, currentUserID uint64) (*RedPacketDetail, er
 		detail.MyClaim = &MyClaim{Claimed: false}
 	}
 
+	if rp.Type == model.RedPacketTypeLottery {
+		detail.EntryCount, _ = repository.CountRedPacketEntries(database.DB, redPacketID)
+		if entry, err := repository.GetRedPacketEntry(database.DB, redPacketID, currentUserID); err == nil {
+			detail.MyEntry = entry
+		}
+	}
+	if rp.Type == model.RedPacketTypeAssigned {
+		if a, err := repository.GetRedPacketAllocation(database.DB, redPacketID, currentUserID); err == nil {
+			detail.MyAllocation = a
+		}
+	}
+
 	return detail, nil
 }
 
-func GetRedPacketRecords(redPacketID uint64, page, pageSize int) ([]RecordItem, int64, error) {
-	offset := (page - 1) * pageSize
-	records, total, err := repository.GetRedPacketRecords(redPacketID, offset, pageSize)
+func GetRedPacketRecords(redPacketID uint64, p pagination.Params) ([]RecordItem, pagination.Result, error) {
+	records, total, err := repository.GetRedPacketRecords(database.DB, redPacketID, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	records, result := pagination.Trim(records, p, total, func(r model.RedPacketRecord) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+	})
+
+	items, err := toRecordItems(records)
 	if err != nil {
-		return nil, 0, err
+		return nil, pagination.Result{}, err
+	}
+	return items, result, nil
+}
+
+// toRecordItems 批量补齐领取者名称，查询次数与记录条数无关
+func toRecordItems(records []model.RedPacketRecord) ([]RecordItem, error) {
+	ids := make([]uint64, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ReceiverID)
+	}
+	names, err := getUsernames(ids)
+	if err != nil {
+		return nil, err
 	}
 
 	items := make([]RecordItem, 0, len(records))
 	for _, r := range records {
-		user, _ := repository.GetUserByID(r.ReceiverID)
-		name := ""
-		if user != nil {
-			name = user.Username
-		}
 		items = append(items, RecordItem{
 			ReceiverID:   r.ReceiverID,
-			ReceiverName: name,
+			ReceiverName: names[r.ReceiverID],
 			Amount:       r.Amount,
 			ClaimedAt:    r.CreatedAt,
 		})
 	}
-	return items, total, nil
+	return items, nil
 }
 
-func GetSentRedPackets(senderID uint64, page, pageSize int) ([]model.RedPacket, int64, error) {
-	offset := (page - 1) * pageSize
-	return repository.GetSentRedPackets(senderID, offset, pageSize)
+func GetSentRedPackets(senderID uint64, p pagination.Params) ([]model.RedPacket, pagination.Result, error) {
+	list, total, err := repository.GetSentRedPackets(database.DB, senderID, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(rp model.RedPacket) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: rp.CreatedAt, ID: rp.ID}
+	})
+	return list, result, nil
 }
 
-func GetReceivedRedPackets(receiverID uint64, page, pageSize int) ([]map[string]interface{}, int64, error) {
-	offset := (page - 1) * pageSize
-	records, total, err := repository.GetReceivedRedPackets(receiverID, offset, pageSize)
+func GetReceivedRedPackets(receiverID uint64, p pagination.Params) ([]ReceivedItem, pagination.Result, error) {
+	records, total, err := repository.GetReceivedRedPackets(database.DB, receiverID, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	records, result := pagination.Trim(records, p, total, func(r repository.ReceivedRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+	})
+
+	ids := make([]uint64, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.SenderID)
+	}
+	names, err := getUsernames(ids)
 	if err != nil {
-		return nil, 0, err
+		return nil, pagination.Result{}, err
 	}
 
-	result := make([]map[string]interface{}, 0, len(records))
+	items := make([]ReceivedItem, 0, len(records))
 	for _, r := range records {
-		rp, _ := repository.GetRedPacketByID(r.RedPacketID)
-		sender, _ := repository.GetUserByID(rp.SenderID)
-		senderName := ""
-		if sender != nil {
-			senderName = sender.Username
-		}
-		result = append(result, map[string]interface{}{
-			"red_packet_id": r.RedPacketID,
-			"sender_name":   senderName,
-			"amount":        r.Amount,
-			"claimed_at":    r.CreatedAt,
+		items = append(items, ReceivedItem{
+			RedPacketID: r.RedPacketID,
+			PublicID:    r.PublicID,
+			SenderName:  names[r.SenderID],
+			Currency:    r.Currency,
+			Amount:      r.Amount,
+			ClaimedAt:   r.CreatedAt,
 		})
 	}
-	return result, total, nil
+	return items, result, nil
 }
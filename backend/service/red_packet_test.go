@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/event"
+	"red-packet/repository"
+)
+
+func TestMatchSecret(t *testing.T) {
+	// 32 个汉字的 UTF-8 编码超过 bcrypt 的 72 字节上限
+	long := strings.Repeat("恭", 32)
+	for _, secret := range []string{"恭喜发财", long} {
+		hash, err := hashSecret(secret)
+		if err != nil {
+			t.Fatalf("hashSecret(%q): %v", secret, err)
+		}
+		rp := &model.RedPacket{HasSecret: true, SecretHash: hash}
+		if !matchSecret(rp, secret) {
+			t.Errorf("matchSecret(%q) = false, want true", secret)
+		}
+		if matchSecret(rp, secret+"x") {
+			t.Errorf("matchSecret(%q+x) = true, want false", secret)
+		}
+	}
+}
+
+func TestMatchSecretLegacy(t *testing.T) {
+	sum := sha256.Sum256([]byte("salt" + "恭喜发财"))
+	rp := &model.RedPacket{HasSecret: true, SecretSalt: "salt", SecretHash: hex.EncodeToString(sum[:])}
+	if !matchSecret(rp, "恭喜发财") {
+		t.Error("legacy secret did not match")
+	}
+	if matchSecret(rp, "恭喜") {
+		t.Error("wrong legacy secret matched")
+	}
+}
+
+// 调度任务之前被领取开启的定时红包同样记录一次开启事件，调度任务随后不再重复开启
+func TestClaimOpensDuePendingRedPacket(t *testing.T) {
+	requireTestDB(t)
+	for _, strategy := range []string{ClaimPessimistic, ClaimOptimistic, ClaimConditional} {
+		t.Run(strategy, func(t *testing.T) {
+			prev := claimStrategy
+			claimStrategy = strategy
+			defer func() { claimStrategy = prev }()
+
+			sender := newTestUser(t, 300)
+			receiver := newTestUser(t, 0)
+			openAt := time.Now().Add(time.Hour)
+			rp, err := SendRedPacket(SendRedPacketParams{
+				SenderID:    sender.ID,
+				Type:        model.RedPacketTypeNormal,
+				TotalAmount: 300,
+				TotalCount:  3,
+				OpenAt:      &openAt,
+			})
+			if err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if rp.Status != model.RedPacketStatusPending {
+				t.Fatalf("status = %d, want pending", rp.Status)
+			}
+			if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: receiver.ID}); err == nil {
+				t.Fatal("claimed a packet that is not open yet")
+			}
+			// 模拟开启时间已过但调度任务尚未执行
+			if err := database.DB.Model(&model.RedPacket{}).Where("id = ?", rp.ID).
+				UpdateColumn("open_at", time.Now().Add(-time.Second)).Error; err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: receiver.ID}); err != nil {
+				t.Fatalf("claim: %v", err)
+			}
+			if _, err := ActivateDueRedPackets(); err != nil {
+				t.Fatal(err)
+			}
+			if n := countRows(t, &model.OutboxEvent{}, "red_packet_id = ? AND event_type = ?", rp.ID, event.RedPacketOpened); n != 1 {
+				t.Fatalf("%d opened events, want 1", n)
+			}
+		})
+	}
+}
+
+// 指定金额红包只有指定的领取人能领，每人领到的正好是发送者为其分配的金额
+func TestAssignedRedPacketPaysAllocations(t *testing.T) {
+	requireTestDB(t)
+	for _, strategy := range []string{ClaimPessimistic, ClaimOptimistic, ClaimConditional} {
+		t.Run(strategy, func(t *testing.T) {
+			prev := claimStrategy
+			claimStrategy = strategy
+			defer func() { claimStrategy = prev }()
+
+			sender := newTestUser(t, 1000)
+			alice, bob, stranger := newTestUser(t, 0), newTestUser(t, 0), newTestUser(t, 0)
+			allocations := []Allocation{{ReceiverID: alice.ID, Amount: 701}, {ReceiverID: bob.ID, Amount: 299}}
+			rp, err := SendRedPacket(SendRedPacketParams{
+				SenderID:    sender.ID,
+				Type:        model.RedPacketTypeAssigned,
+				TotalAmount: 1000,
+				TotalCount:  2,
+				Allocations: allocations,
+			})
+			if err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			if _, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: stranger.ID}); err == nil || err.Error() != "not a designated receiver" {
+				t.Fatalf("stranger claim err = %v, want not a designated receiver", err)
+			}
+			if b := balanceOf(t, stranger.ID); b != 0 {
+				t.Errorf("stranger balance = %d, want 0", b)
+			}
+
+			for _, a := range allocations {
+				amount, err := ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: a.ReceiverID})
+				if err != nil {
+					t.Fatalf("claim by %d: %v", a.ReceiverID, err)
+				}
+				if amount != a.Amount {
+					t.Errorf("receiver %d got %d, want %d", a.ReceiverID, amount, a.Amount)
+				}
+				if b := balanceOf(t, a.ReceiverID); b != a.Amount {
+					t.Errorf("receiver %d balance = %d, want %d", a.ReceiverID, b, a.Amount)
+				}
+			}
+			if n := countRows(t, &model.RedPacketAllocation{}, "red_packet_id = ? AND status = ?", rp.ID, model.AllocationStatusClaimed); n != 2 {
+				t.Errorf("%d allocations claimed, want 2", n)
+			}
+			got, err := repository.GetRedPacketByID(database.DB, rp.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != model.RedPacketStatusEmpty || got.RemainingAmount != 0 || got.RemainingCount != 0 {
+				t.Errorf("packet status %d remaining %d/%d, want empty 0/0", got.Status, got.RemainingCount, got.RemainingAmount)
+			}
+		})
+	}
+}
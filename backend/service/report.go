@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/cache"
+	"red-packet/pkg/currency"
+	"red-packet/repository"
+)
+
+// reportTopN 排行榜展示的人数
+const reportTopN = 5
+
+// reportMaxSpan 自定义统计区间的最大跨度
+const reportMaxSpan = 366 * 24 * time.Hour
+
+// reportCacheSize 每个报告缓存的条目上限。自定义区间由用户任意指定，不设上限时单个用户即可无限制地写入缓存
+const reportCacheSize = 10000
+
+// reportCache 报告缓存：已结束的区间数据不再变化，缓存一天；包含当前时间的区间缓存 10 分钟
+var (
+	reportCache     = cache.NewTTL[reportKey, *Report](24*time.Hour, reportCacheSize)
+	liveReportCache = cache.NewTTL[reportKey, *Report](10*time.Minute, reportCacheSize)
+)
+
+type reportKey struct {
+	UserID   uint64
+	Currency string
+	Start    int64
+	End      int64
+}
+
+type ReportPeriod struct {
+	Start time.Time // 含
+	End   time.Time // 不含
+}
+
+type ReportCounterpart struct {
+	UserID   uint64
+	Username string
+	Amount   uint64
+	Count    int64
+}
+
+type ReportMonth struct {
+	Month          string
+	SentAmount     uint64
+	SentCount      int64
+	ReceivedAmount uint64
+	ReceivedCount  int64
+}
+
+type Report struct {
+	Currency       string
+	Period         ReportPeriod
+	SentAmount     uint64
+	SentCount      int64
+	RefundAmount   uint64
+	ReceivedAmount uint64
+	ReceivedCount  int64
+	BiggestClaim   *repository.BiggestClaim
+	BiggestSender  string
+	BestLuckCount  int64
+	TopSenders     []ReportCounterpart
+	TopReceivers   []ReportCounterpart
+	Months         []ReportMonth
+	GeneratedAt    time.Time
+}
+
+// YearPeriod 自然年统计区间
+func YearPeriod(year int) ReportPeriod {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	return ReportPeriod{Start: start, End: start.AddDate(1, 0, 0)}
+}
+
+// NewReportPeriod 自定义区间，end 为包含在内的最后一天
+func NewReportPeriod(start, end time.Time) (ReportPeriod, error) {
+	end = end.AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return ReportPeriod{}, errors.New("start must not be after end")
+	}
+	if end.Sub(start) > reportMaxSpan {
+		return ReportPeriod{}, errors.New("report period must not exceed one year")
+	}
+	return ReportPeriod{Start: start, End: end}, nil
+}
+
+// GetReport 汇总用户在区间内的收发红包数据，全部在数据库侧聚合
+func GetReport(userID uint64, code string, period ReportPeriod) (*Report, error) {
+	cur, err := currency.Normalize(code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key := reportKey{UserID: userID, Currency: cur, Start: period.Start.Unix(), End: period.End.Unix()}
+	c := reportCacheFor(period, now)
+	if r, ok := c.Get(key); ok {
+		return r, nil
+	}
+
+	r, err := buildReport(userID, cur, period)
+	if err != nil {
+		return nil, err
+	}
+	r.GeneratedAt = now
+	c.Set(key, r)
+	return r, nil
+}
+
+// reportCacheFor 区间包含当前时间时数据仍在变化，使用短期缓存
+func reportCacheFor(period ReportPeriod, now time.Time) *cache.TTL[reportKey, *Report] {
+	if period.End.After(now) {
+		return liveReportCache
+	}
+	return reportCache
+}
+
+func buildReport(userID uint64, cur string, period ReportPeriod) (*Report, error) {
+	start, end := period.Start, period.End
+	r := &Report{Currency: cur, Period: period}
+
+	sent, err := repository.SumUserTransactionsBetween(database.DB, userID, model.TransactionTypeSend, cur, start, end)
+	if err != nil {
+		return nil, err
+	}
+	refund, err := repository.SumUserTransactionsBetween(database.DB, userID, model.TransactionTypeRefund, cur, start, end)
+	if err != nil {
+		return nil, err
+	}
+	received, err := repository.SumUserTransactionsBetween(database.DB, userID, model.TransactionTypeReceive, cur, start, end)
+	if err != nil {
+		return nil, err
+	}
+	r.SentAmount, r.SentCount = sent.Total, sent.Count
+	r.RefundAmount = refund.Total
+	r.ReceivedAmount, r.ReceivedCount = received.Total, received.Count
+
+	if r.BiggestClaim, err = repository.GetBiggestClaim(database.DB, userID, cur, start, end); err != nil {
+		return nil, err
+	}
+	if r.BestLuckCount, err = repository.CountBestLuck(database.DB, userID, cur, start, end); err != nil {
+		return nil, err
+	}
+
+	topSenders, err := repository.GetTopSendersToUser(database.DB, userID, cur, start, end, reportTopN)
+	if err != nil {
+		return nil, err
+	}
+	topReceivers, err := repository.GetTopReceiversFromUser(database.DB, userID, cur, start, end, reportTopN)
+	if err != nil {
+		return nil, err
+	}
+
+	// 排行榜和最大一笔的用户名一次批量查询
+	ids := make([]uint64, 0, len(topSenders)+len(topReceivers)+1)
+	for _, t := range topSenders {
+		ids = append(ids, t.UserID)
+	}
+	for _, t := range topReceivers {
+		ids = append(ids, t.UserID)
+	}
+	if r.BiggestClaim != nil {
+		ids = append(ids, r.BiggestClaim.SenderID)
+	}
+	names, err := getUsernames(ids)
+	if err != nil {
+		return nil, err
+	}
+	if r.BiggestClaim != nil {
+		r.BiggestSender = names[r.BiggestClaim.SenderID]
+	}
+	r.TopSenders = toReportCounterparts(topSenders, names)
+	r.TopReceivers = toReportCounterparts(topReceivers, names)
+
+	if r.Months, err = reportMonths(userID, cur, period); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func toReportCounterparts(list []repository.CounterpartAmount, names map[uint64]string) []ReportCounterpart {
+	result := make([]ReportCounterpart, 0, len(list))
+	for _, t := range list {
+		result = append(result, ReportCounterpart{
+			UserID:   t.UserID,
+			Username: names[t.UserID],
+			Amount:   t.Total,
+			Count:    t.Count,
+		})
+	}
+	return result
+}
+
+// reportMonths 按月合并收发数据，区间内没有数据的月份补零
+func reportMonths(userID uint64, cur string, period ReportPeriod) ([]ReportMonth, error) {
+	sent, err := repository.SumUserTransactionsByMonth(database.DB, userID, model.TransactionTypeSend, cur, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+	received, err := repository.SumUserTransactionsByMonth(database.DB, userID, model.TransactionTypeReceive, cur, period.Start, period.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var months []ReportMonth
+	index := map[string]int{}
+	first := time.Date(period.Start.Year(), period.Start.Month(), 1, 0, 0, 0, 0, period.Start.Location())
+	for m := first; m.Before(period.End); m = m.AddDate(0, 1, 0) {
+		label := fmt.Sprintf("%04d-%02d", m.Year(), int(m.Month()))
+		index[label] = len(months)
+		months = append(months, ReportMonth{Month: label})
+	}
+	for _, s := range sent {
+		if i, ok := index[s.Month]; ok {
+			months[i].SentAmount, months[i].SentCount = s.Total, s.Count
+		}
+	}
+	for _, s := range received {
+		if i, ok := index[s.Month]; ok {
+			months[i].ReceivedAmount, months[i].ReceivedCount = s.Total, s.Count
+		}
+	}
+	return months, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"red-packet/pkg/cache"
+)
+
+// 已结束的区间进长期缓存，包含当前时间的区间进短期缓存
+func TestReportCacheFor(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	if c := reportCacheFor(YearPeriod(2025), now); c != reportCache {
+		t.Error("closed period not cached in reportCache")
+	}
+	if c := reportCacheFor(YearPeriod(2026), now); c != liveReportCache {
+		t.Error("current period not cached in liveReportCache")
+	}
+	// 结束时间正好是当前时间的区间已结束
+	if c := reportCacheFor(ReportPeriod{Start: now.AddDate(0, -1, 0), End: now}, now); c != reportCache {
+		t.Error("period ending now not cached in reportCache")
+	}
+}
+
+// 任意自定义区间都会写缓存，两个报告缓存写满后淘汰旧条目，条目数不超过上限，刚写入的仍可读到
+func TestReportCachesAreCapped(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cache *cache.TTL[reportKey, *Report]
+	}{
+		{"closed", reportCache},
+		{"live", liveReportCache},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// 用不会与真实用户冲突的用户ID，避免影响同一进程内的其他测试
+			var last reportKey
+			for i := 0; i < reportCacheSize+500; i++ {
+				last = reportKey{UserID: math.MaxUint64 - uint64(i), Currency: "CNY", Start: int64(i), End: int64(i) + 86400}
+				tc.cache.Set(last, &Report{})
+				if n := tc.cache.Len(); n > reportCacheSize {
+					t.Fatalf("Len = %d, exceeds %d", n, reportCacheSize)
+				}
+			}
+			if n := tc.cache.Len(); n != reportCacheSize {
+				t.Fatalf("Len = %d, want %d", n, reportCacheSize)
+			}
+			if _, ok := tc.cache.Get(last); !ok {
+				t.Fatal("latest report evicted")
+			}
+		})
+	}
+}
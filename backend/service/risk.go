@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/repository"
+	"red-packet/risk"
+)
+
+var riskEngine risk.Engine = risk.AllowAll{}
+
+func InitRiskService(engine risk.Engine) {
+	riskEngine = engine
+}
+
+// RequestMeta 请求来源信息，由 handler 从 HTTP 请求中提取
+type RequestMeta struct {
+	IP       string
+	DeviceID string
+}
+
+// checkRisk 组装风控上下文并记录决策日志，拒绝或需验证时返回错误
+func checkRisk(scene string, userID uint64, meta RequestMeta, amount, redPacketID uint64) error {
+	user, err := repository.GetUserByID(database.DB, userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	now := time.Now()
+	req := risk.Request{
+		Scene:       scene,
+		UserID:      userID,
+		AccountAge:  now.Sub(user.CreatedAt),
+		IP:          meta.IP,
+		DeviceID:    meta.DeviceID,
+		Amount:      amount,
+		RedPacketID: redPacketID,
+	}
+
+	// 计数包含本次请求
+	actions, err := repository.CountUserRiskDecisions(database.DB, userID, scene, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	req.UserActions1h = actions + 1
+	if meta.DeviceID != "" {
+		others, err := repository.CountDeviceOtherAccounts(database.DB, meta.DeviceID, userID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		req.DeviceAccounts24h = others + 1
+	}
+	if meta.IP != "" {
+		others, err := repository.CountIPOtherAccounts(database.DB, meta.IP, userID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		req.IPAccounts24h = others + 1
+	}
+
+	decision := riskEngine.Evaluate(req)
+	if err := repository.CreateRiskDecision(database.DB, &model.RiskDecision{
+		UserID:      userID,
+		Scene:       scene,
+		IP:          meta.IP,
+		DeviceID:    meta.DeviceID,
+		RedPacketID: redPacketID,
+		Amount:      amount,
+		Action:      decision.Action,
+		Rule:        decision.Rule,
+		Reason:      decision.Reason,
+	}); err != nil {
+		// 日志写失败不影响业务判断
+		log.Printf("risk: save decision failed: %v", err)
+	}
+
+	switch decision.Action {
+	case risk.ActionDeny:
+		return errors.New("risk denied")
+	case risk.ActionChallenge:
+		return errors.New("risk challenge required")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errInjected    = errors.New("injected failure")
+	failCreateOn   atomic.Value // 要注入失败的表名，空串表示不注入
+	injectHookOnce sync.Once
+)
+
+// failCreatesOn 之后向 table 插入时返回 errInjected，测试结束后恢复
+func failCreatesOn(tb testing.TB, table string) {
+	tb.Helper()
+	injectHookOnce.Do(func() {
+		err := database.DB.Callback().Create().Before("gorm:create").Register("test:inject_failure", func(db *gorm.DB) {
+			if t, _ := failCreateOn.Load().(string); t != "" && db.Statement.Table == t {
+				db.AddError(errInjected)
+			}
+		})
+		if err != nil {
+			tb.Fatalf("register callback: %v", err)
+		}
+	})
+	failCreateOn.Store(table)
+	tb.Cleanup(func() { failCreateOn.Store("") })
+}
+
+func countRows(tb testing.TB, m interface{}, query string, args ...interface{}) int64 {
+	tb.Helper()
+	var n int64
+	if err := database.DB.Model(m).Where(query, args...).Count(&n).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return n
+}
+
+func balanceOf(tb testing.TB, userID uint64) uint64 {
+	tb.Helper()
+	b, err := repository.GetUserBalance(database.DB, userID, currency.Default())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+// 红包行已插入后写流水失败：整个事务回滚，不留下红包、流水和事件，余额不变
+func TestSendRedPacketRollsBackAfterCreate(t *testing.T) {
+	requireTestDB(t)
+	sender := newTestUser(t, 1000)
+	failCreatesOn(t, "transactions")
+
+	_, err := SendRedPacket(SendRedPacketParams{
+		SenderID:    sender.ID,
+		Type:        model.RedPacketTypeLucky,
+		TotalAmount: 500,
+		TotalCount:  5,
+	})
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("SendRedPacket err = %v, want injected failure", err)
+	}
+	if n := countRows(t, &model.RedPacket{}, "sender_id = ?", sender.ID); n != 0 {
+		t.Errorf("%d red packets left behind", n)
+	}
+	if n := countRows(t, &model.Transaction{}, "user_id = ?", sender.ID); n != 0 {
+		t.Errorf("%d transactions left behind", n)
+	}
+	if b := balanceOf(t, sender.ID); b != 1000 {
+		t.Errorf("balance = %d, want 1000", b)
+	}
+}
+
+// 扣减红包剩余后写领取记录失败：各策略下红包剩余、领取记录、流水和余额都回到领取前
+func TestClaimRedPacketRollsBackAfterTakeShare(t *testing.T) {
+	requireTestDB(t)
+	for _, strategy := range []string{ClaimPessimistic, ClaimOptimistic, ClaimConditional} {
+		t.Run(strategy, func(t *testing.T) {
+			prev := claimStrategy
+			claimStrategy = strategy
+			defer func() { claimStrategy = prev }()
+
+			sender := newTestUser(t, 1000)
+			receiver := newTestUser(t, 0)
+			rp, err := SendRedPacket(SendRedPacketParams{
+				SenderID:    sender.ID,
+				Type:        model.RedPacketTypeNormal,
+				TotalAmount: 300,
+				TotalCount:  3,
+			})
+			if err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			failCreatesOn(t, "red_packet_records")
+			_, err = ClaimRedPacket(ClaimRedPacketParams{RedPacketID: rp.ID, ReceiverID: receiver.ID})
+			if !errors.Is(err, errInjected) {
+				t.Fatalf("ClaimRedPacket err = %v, want injected failure", err)
+			}
+
+			got, err := repository.GetRedPacketByID(database.DB, rp.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.RemainingCount != 3 || got.RemainingAmount != 300 || got.Version != rp.Version {
+				t.Errorf("red packet changed: remaining %d/%d version %d", got.RemainingCount, got.RemainingAmount, got.Version)
+			}
+			if n := countRows(t, &model.RedPacketRecord{}, "red_packet_id = ?", rp.ID); n != 0 {
+				t.Errorf("%d records left behind", n)
+			}
+			if n := countRows(t, &model.Transaction{}, "user_id = ?", receiver.ID); n != 0 {
+				t.Errorf("%d receiver transactions left behind", n)
+			}
+			if b := balanceOf(t, receiver.ID); b != 0 {
+				t.Errorf("receiver balance = %d, want 0", b)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/qrcode"
+	"red-packet/repository"
+)
+
+// shareQRScale 二维码每个模块的像素数
+const shareQRScale = 8
+
+// shareMACBytes 签名截取的字节数，128 位足以防止伪造，也让链接和二维码更短
+const shareMACBytes = 16
+
+var (
+	shareSecret  []byte
+	shareBaseURL string
+	shareTTL     time.Duration
+)
+
+var (
+	errInvalidShareToken = errors.New("invalid share token")
+	errShareLinkExpired  = errors.New("share link expired")
+)
+
+// InitShareService baseURL 为前端地址，分享链接指向其红包详情页
+func InitShareService(secret, baseURL string, ttl time.Duration) {
+	shareSecret = []byte(secret)
+	shareBaseURL = strings.TrimRight(baseURL, "/")
+	shareTTL = ttl
+}
+
+type ShareLink struct {
+	PublicID  string
+	Token     string
+	URL       string
+	ExpiresAt time.Time
+}
+
+// ResolveRedPacketID 把对外公开ID换成内部ID
+func ResolveRedPacketID(publicID string) (uint64, error) {
+	rp, err := repository.GetRedPacketByPublicID(database.DB, publicID)
+	if err != nil {
+		return 0, errors.New("red packet not found")
+	}
+	return rp.ID, nil
+}
+
+// CreateShareLink 发送者生成带签名的分享链接，有效期不超过红包过期时间
+func CreateShareLink(userID uint64, publicID string) (*ShareLink, error) {
+	rp, err := repository.GetRedPacketByPublicID(database.DB, publicID)
+	if err != nil {
+		return nil, errors.New("red packet not found")
+	}
+	if rp.SenderID != userID {
+		return nil, errors.New("only the sender can share")
+	}
+	switch rp.Status {
+	case model.RedPacketStatusEmpty:
+		return nil, errors.New("red packet is empty")
+	case model.RedPacketStatusExpired, model.RedPacketStatusRefunded:
+		return nil, errors.New("red packet is expired")
+	case model.RedPacketStatusDrawn:
+		return nil, errors.New("lottery is closed")
+	}
+
+	expiresAt := time.Now().Add(shareTTL)
+	if rp.ExpiredAt.Before(expiresAt) {
+		expiresAt = rp.ExpiredAt
+	}
+	// 抽奖红包开奖后链接已无用
+	if rp.DrawAt != nil && rp.DrawAt.Before(expiresAt) {
+		expiresAt = *rp.DrawAt
+	}
+	token := signShareToken(rp.PublicID, expiresAt.Unix())
+	return &ShareLink{
+		PublicID:  rp.PublicID,
+		Token:     token,
+		URL:       ShareURL(rp.PublicID, token),
+		ExpiresAt: time.Unix(expiresAt.Unix(), 0),
+	}, nil
+}
+
+// ShareURL 前端详情页地址，令牌放在查询参数 t 中
+func ShareURL(publicID, token string) string {
+	return shareBaseURL + "/red-packets/" + url.PathEscape(publicID) + "?t=" + url.QueryEscape(token)
+}
+
+// ShareQRCode 校验令牌后把分享链接编码为二维码 PNG
+func ShareQRCode(publicID, token string) ([]byte, error) {
+	if err := VerifyShareToken(publicID, token); err != nil {
+		return nil, err
+	}
+	code, err := qrcode.Encode(ShareURL(publicID, token))
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(shareQRScale)
+}
+
+// VerifyShareToken 令牌格式为 "过期时间戳.签名"，签名覆盖公开ID和过期时间
+func VerifyShareToken(publicID, token string) error {
+	expPart, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return errInvalidShareToken
+	}
+	exp, err := strconv.ParseInt(expPart, 10, 64)
+	if err != nil {
+		return errInvalidShareToken
+	}
+	if !hmac.Equal([]byte(signShareToken(publicID, exp)), []byte(token)) {
+		return errInvalidShareToken
+	}
+	if time.Now().Unix() > exp {
+		return errShareLinkExpired
+	}
+	return nil
+}
+
+func signShareToken(publicID string, exp int64) string {
+	expPart := strconv.FormatInt(exp, 10)
+	mac := hmac.New(sha256.New, shareSecret)
+	mac.Write([]byte("share:" + publicID + "." + expPart))
+	return expPart + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:shareMACBytes])
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/pkg/xlsx"
+	"red-packet/repository"
+)
+
+// 对账单导出格式
+const (
+	StatementFormatCSV  = "csv"
+	StatementFormatXLSX = "xlsx"
+)
+
+// 金额、变动后余额在表头中的列下标，XLSX 中写为数字
+const (
+	statementColAmount  = 5
+	statementColBalance = 6
+)
+
+// statementFlushRows 每写多少行主动刷新一次输出，让客户端尽早收到数据
+const statementFlushRows = 500
+
+type StatementParams struct {
+	UserID   uint64
+	Currency string // 为空表示全部币种
+	Start    time.Time
+	End      time.Time // 不含
+	Format   string
+	Lang     string // zh / en
+}
+
+type statementLabels struct {
+	headers    []string
+	types      map[string]string
+	in, out    string
+	sheetName  string
+	timeLayout string
+}
+
+var statementI18n = map[string]statementLabels{
+	"zh": {
+		headers: []string{"时间", "流水号", "类型", "收支", "币种", "金额", "变动后余额", "关联红包ID", "关联转账ID", "备注"},
+		types: map[string]string{
+			model.TransactionTypeRecharge:   "充值",
+			model.TransactionTypeSend:       "发红包",
+			model.TransactionTypeReceive:    "领红包",
+			model.TransactionTypeRefund:     "红包退款",
+			model.TransactionTypeAdjust:     "人工调账",
+			model.TransactionTypeTransfer:   "转账",
+			model.TransactionTypeCollection: "AA 收款",
+			model.TransactionTypeRain:       "红包雨",
+		},
+		in: "收入", out: "支出",
+		sheetName:  "账单",
+		timeLayout: time.DateTime,
+	},
+	"en": {
+		headers: []string{"Time", "Transaction ID", "Type", "Direction", "Currency", "Amount", "Balance After", "Red Packet ID", "Transfer ID", "Remark"},
+		types: map[string]string{
+			model.TransactionTypeRecharge:   "Recharge",
+			model.TransactionTypeSend:       "Send red packet",
+			model.TransactionTypeReceive:    "Claim red packet",
+			model.TransactionTypeRefund:     "Red packet refund",
+			model.TransactionTypeAdjust:     "Adjustment",
+			model.TransactionTypeTransfer:   "Transfer",
+			model.TransactionTypeCollection: "Collection",
+			model.TransactionTypeRain:       "Red packet rain",
+		},
+		in: "In", out: "Out",
+		sheetName:  "Statement",
+		timeLayout: time.DateTime,
+	},
+}
+
+// NewStatementParams 校验导出参数，需在开始写响应之前调用，出错时还能返回普通的错误响应
+func NewStatementParams(userID uint64, code, format, lang string, start, end time.Time) (StatementParams, error) {
+	p := StatementParams{UserID: userID, Format: format, Lang: lang}
+	if code != "" {
+		cur, err := currency.Normalize(code)
+		if err != nil {
+			return p, err
+		}
+		p.Currency = cur
+	}
+	if p.Format == "" {
+		p.Format = StatementFormatCSV
+	}
+	if p.Format != StatementFormatCSV && p.Format != StatementFormatXLSX {
+		return p, errors.New("format must be csv or xlsx")
+	}
+	if _, ok := statementI18n[p.Lang]; !ok {
+		p.Lang = "zh"
+	}
+	p.Start, p.End = start, end.AddDate(0, 0, 1)
+	if !p.Start.Before(p.End) {
+		return p, errors.New("start must not be after end")
+	}
+	return p, nil
+}
+
+// statementWriter CSV 和 XLSX 共用的逐行写出接口
+type statementWriter interface {
+	header(cols []string) error
+	row(cols []string) error
+	flush() error
+	close() error
+}
+
+// WriteStatement 逐行读取流水并写出对账单，全程不缓存整份数据
+func WriteStatement(w io.Writer, p StatementParams) error {
+	labels := statementI18n[p.Lang]
+
+	var sw statementWriter
+	if p.Format == StatementFormatXLSX {
+		xw, err := xlsx.NewWriter(w, labels.sheetName)
+		if err != nil {
+			return err
+		}
+		sw = &xlsxStatement{w: xw}
+	} else {
+		// 写入 UTF-8 BOM，Excel 直接打开 CSV 时中文不乱码
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+		sw = &csvStatement{w: csv.NewWriter(w)}
+	}
+
+	if err := sw.header(labels.headers); err != nil {
+		return err
+	}
+	n := 0
+	err := repository.EachUserTransaction(database.DB, p.UserID, p.Currency, p.Start, p.End, func(t *model.Transaction) error {
+		if err := sw.row(statementColumns(t, labels)); err != nil {
+			return err
+		}
+		n++
+		if n%statementFlushRows == 0 {
+			return sw.flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return sw.close()
+}
+
+// statementColumns 单行的文本列，金额按币种精度换算为元等主单位
+func statementColumns(t *model.Transaction, labels statementLabels) []string {
+	direction := labels.in
+	if t.Direction == model.TransactionDirectionOut {
+		direction = labels.out
+	}
+	typeName, ok := labels.types[t.Type]
+	if !ok {
+		typeName = t.Type
+	}
+	var packetID, transferID string
+	if t.RelatedID != nil {
+		related := strconv.FormatUint(*t.RelatedID, 10)
+		switch t.Type {
+		case model.TransactionTypeSend, model.TransactionTypeReceive, model.TransactionTypeRefund:
+			packetID = related
+		case model.TransactionTypeTransfer:
+			transferID = related
+		}
+	}
+	return []string{
+		t.CreatedAt.Local().Format(labels.timeLayout),
+		strconv.FormatUint(t.ID, 10),
+		escapeCell(typeName),
+		direction,
+		t.Currency,
+		signedAmount(t),
+		currency.Format(t.Currency, t.BalanceAfter),
+		packetID,
+		transferID,
+		escapeCell(t.Remark),
+	}
+}
+
+// escapeCell 防止公式注入：备注中包含用户名、收款标题等用户输入，以 = + - @ 或制表符、回车开头的文本
+// 会被表格软件当作公式执行，前面加单引号按文本显示。金额列是程序生成的数字，不做处理
+func escapeCell(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + s
+	}
+	return s
+}
+
+// signedAmount 支出金额带负号，便于在表格中直接求和
+func signedAmount(t *model.Transaction) string {
+	s := currency.Format(t.Currency, t.Amount)
+	if t.Direction == model.TransactionDirectionOut {
+		return "-" + s
+	}
+	return s
+}
+
+type csvStatement struct {
+	w *csv.Writer
+}
+
+func (s *csvStatement) header(cols []string) error {
+	return s.w.Write(cols)
+}
+
+func (s *csvStatement) row(cols []string) error {
+	return s.w.Write(cols)
+}
+
+func (s *csvStatement) flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *csvStatement) close() error {
+	return s.flush()
+}
+
+type xlsxStatement struct {
+	w *xlsx.Writer
+}
+
+func (s *xlsxStatement) header(cols []string) error {
+	cells := make([]xlsx.Cell, 0, len(cols))
+	for _, c := range cols {
+		cells = append(cells, xlsx.String(c))
+	}
+	return s.w.WriteRow(cells...)
+}
+
+// row 金额和余额写成数字单元格，流水号、关联ID 仍按文本写入避免被科学计数法显示
+func (s *xlsxStatement) row(cols []string) error {
+	cells := make([]xlsx.Cell, 0, len(cols))
+	for i, c := range cols {
+		if i == statementColAmount || i == statementColBalance {
+			cells = append(cells, xlsx.Number(c))
+			continue
+		}
+		cells = append(cells, xlsx.String(c))
+	}
+	return s.w.WriteRow(cells...)
+}
+
+func (s *xlsxStatement) flush() error {
+	return s.w.Flush()
+}
+
+func (s *xlsxStatement) close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"red-packet/model"
+)
+
+func TestEscapeCell(t *testing.T) {
+	tests := map[string]string{
+		"":                               "",
+		"转账给 alice":                      "转账给 alice",
+		`=HYPERLINK("http://x","click")`: `'=HYPERLINK("http://x","click")`,
+		"+1+1":                           "'+1+1",
+		"-2+3":                           "'-2+3",
+		"@SUM(A1)":                       "'@SUM(A1)",
+		"\t=1":                           "'\t=1",
+		"\r=1":                           "'\r=1",
+	}
+	for in, want := range tests {
+		if got := escapeCell(in); got != want {
+			t.Errorf("escapeCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStatementColumnsEscapeRemark(t *testing.T) {
+	tx := &model.Transaction{
+		ID:           1,
+		Type:         model.TransactionTypeAdjust,
+		Direction:    model.TransactionDirectionOut,
+		Currency:     "CNY",
+		Amount:       150,
+		BalanceAfter: 50,
+		Remark:       "=cmd|' /C calc'!A0",
+		CreatedAt:    time.Now(),
+	}
+	cols := statementColumns(tx, statementI18n["zh"])
+	if got := cols[len(cols)-1]; got != "'=cmd|' /C calc'!A0" {
+		t.Errorf("remark = %q, want escaped", got)
+	}
+	// 金额列保持数字，支出的负号不能被转义
+	if got := cols[statementColAmount]; got != "-1.50" {
+		t.Errorf("amount = %q, want -1.50", got)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"red-packet/config"
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/repository"
+)
+
+// testDSNEnv 依赖 MySQL 的测试使用的连接串，未设置时跳过；测试数据不会清理，请使用专用的测试库
+const testDSNEnv = "RED_PACKET_TEST_DSN"
+
+var (
+	testDBOnce sync.Once
+	testDBErr  error
+)
+
+func requireTestDB(tb testing.TB) {
+	tb.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		tb.Skip(testDSNEnv + " not set")
+	}
+	testDBOnce.Do(func() {
+		testDBErr = database.Init(&config.Config{Database: config.DatabaseConfig{DSN: dsn}})
+	})
+	if testDBErr != nil {
+		tb.Fatalf("init test database: %v", testDBErr)
+	}
+}
+
+var testUserSeq atomic.Int64
+
+// newTestUser 创建用户并向默认币种钱包充值 balance，用户名按时间和序号生成，重复运行不冲突
+func newTestUser(tb testing.TB, balance uint64) *model.User {
+	tb.Helper()
+	user := &model.User{
+		Username:     fmt.Sprintf("t%x_%d", time.Now().UnixNano(), testUserSeq.Add(1)),
+		PasswordHash: "-",
+	}
+	if err := repository.CreateUser(database.DB, user); err != nil {
+		tb.Fatalf("create user: %v", err)
+	}
+	if balance > 0 {
+		if err := repository.AddUserBalance(database.DB, user.ID, currency.Default(), balance); err != nil {
+			tb.Fatalf("add balance: %v", err)
+		}
+	}
+	return user
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/currency"
+	"red-packet/pkg/pagination"
+	"red-packet/repository"
+	"red-packet/risk"
+
+	"gorm.io/gorm"
+)
+
+const (
+	transferAcceptTTL = 24 * time.Hour // 待收款转账的有效期
+	returnBatchLimit  = 100
+)
+
+type TransferParams struct {
+	FromUserID    uint64
+	ToUsername    string
+	Currency      string
+	Amount        uint64
+	Note          string
+	RequireAccept bool
+	Meta          RequestMeta
+}
+
+// CreateTransfer 发起转账：立即到账模式直接入账；需确认模式先扣款，等待对方收款。
+// 转账与发红包一样经过 send 场景的风控，并计入当日发出额度；到账时计入收款人当日领取额度
+func CreateTransfer(params TransferParams) (*model.Transfer, error) {
+	cur, err := currency.Normalize(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if params.Amount == 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	receiver, err := repository.GetUserByUsername(database.DB, params.ToUsername)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recipient not found")
+		}
+		return nil, err
+	}
+	if receiver.ID == params.FromUserID {
+		return nil, errors.New("cannot transfer to yourself")
+	}
+	if err := checkUserActive(params.FromUserID); err != nil {
+		return nil, err
+	}
+	if receiver.Status == model.UserStatusFrozen {
+		return nil, errors.New("recipient account is frozen")
+	}
+	if err := checkRisk(risk.SceneSend, params.FromUserID, params.Meta, params.Amount, 0); err != nil {
+		return nil, err
+	}
+
+	transfer := &model.Transfer{
+		FromUserID:    params.FromUserID,
+		ToUserID:      receiver.ID,
+		Currency:      cur,
+		Amount:        params.Amount,
+		Note:          params.Note,
+		RequireAccept: params.RequireAccept,
+		Status:        model.TransferStatusAccepted,
+	}
+	now := time.Now()
+	if params.RequireAccept {
+		expiredAt := now.Add(transferAcceptTTL)
+		transfer.Status = model.TransferStatusPending
+		transfer.ExpiredAt = &expiredAt
+	} else {
+		transfer.FinishedAt = &now
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if !params.RequireAccept {
+			// 同时涉及双方限额，按用户ID顺序锁定，避免互相转账的两个事务死锁
+			if err := lockUsers(tx, params.FromUserID, receiver.ID); err != nil {
+				return err
+			}
+			used, count, err := receiveUsage(tx, receiver.ID, cur)
+			if err != nil {
+				return err
+			}
+			if checkDailyReceiveLimit(cur, used, count, params.Amount) != nil {
+				return errors.New("recipient daily receive limit exceeded")
+			}
+		}
+		if err := checkDailySendLimit(tx, params.FromUserID, cur, params.Amount); err != nil {
+			return err
+		}
+		if err := repository.CreateTransfer(tx, transfer); err != nil {
+			return err
+		}
+		if _, err := debit(tx, params.FromUserID, cur, params.Amount, model.TransactionTypeTransfer, &transfer.ID, "转账给 "+receiver.Username); err != nil {
+			return err
+		}
+		if params.RequireAccept {
+			return nil
+		}
+		_, err := credit(tx, receiver.ID, cur, params.Amount, model.TransactionTypeTransfer, &transfer.ID, "收到转账")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// AcceptTransfer 收款人确认收款
+func AcceptTransfer(transferID, userID uint64) (*model.Transfer, error) {
+	return finishTransfer(transferID, func(tx *gorm.DB, t *model.Transfer) error {
+		if t.ToUserID != userID {
+			return errors.New("transfer not found")
+		}
+		if err := checkUserActive(userID); err != nil {
+			return err
+		}
+		if time.Now().After(*t.ExpiredAt) {
+			return errors.New("transfer is expired")
+		}
+		used, count, err := receiveUsage(tx, userID, t.Currency)
+		if err != nil {
+			return err
+		}
+		if err := checkDailyReceiveLimit(t.Currency, used, count, t.Amount); err != nil {
+			return err
+		}
+		t.Status = model.TransferStatusAccepted
+		_, err = credit(tx, t.ToUserID, t.Currency, t.Amount, model.TransactionTypeTransfer, &t.ID, "收到转账")
+		return err
+	})
+}
+
+// RejectTransfer 收款人拒收，款项退回转出方
+func RejectTransfer(transferID, userID uint64) (*model.Transfer, error) {
+	return finishTransfer(transferID, func(tx *gorm.DB, t *model.Transfer) error {
+		if t.ToUserID != userID {
+			return errors.New("transfer not found")
+		}
+		t.Status = model.TransferStatusRejected
+		_, err := credit(tx, t.FromUserID, t.Currency, t.Amount, model.TransactionTypeTransfer, &t.ID, "转账被拒收退回")
+		return err
+	})
+}
+
+// ReturnExpiredTransfers 退回超时未收款的转账，返回本次退回的数量
+func ReturnExpiredTransfers() (int, error) {
+	ids, err := repository.GetExpiredPendingTransferIDs(database.DB, time.Now(), returnBatchLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	returned := 0
+	for _, id := range ids {
+		_, err := finishTransfer(id, func(tx *gorm.DB, t *model.Transfer) error {
+			t.Status = model.TransferStatusReturned
+			_, err := credit(tx, t.FromUserID, t.Currency, t.Amount, model.TransactionTypeTransfer, &t.ID, "转账超时退回")
+			return err
+		})
+		if errors.Is(err, errTransferFinished) {
+			continue
+		}
+		if err != nil {
+			return returned, err
+		}
+		returned++
+	}
+	return returned, nil
+}
+
+var errTransferFinished = errors.New("transfer is already finished")
+
+// finishTransfer 锁定待收款转账并执行状态流转，fn 负责设置终态和资金入账
+func finishTransfer(transferID uint64, fn func(tx *gorm.DB, t *model.Transfer) error) (*model.Transfer, error) {
+	var transfer *model.Transfer
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		t, err := repository.GetTransferForUpdate(tx, transferID)
+		if err != nil {
+			return errors.New("transfer not found")
+		}
+		if t.Status != model.TransferStatusPending {
+			return errTransferFinished
+		}
+		if err := fn(tx, t); err != nil {
+			return err
+		}
+		now := time.Now()
+		t.FinishedAt = &now
+		transfer = t
+		return repository.UpdateTransfer(tx, t)
+	})
+	return transfer, err
+}
+
+func GetUserTransfers(userID uint64, p pagination.Params) ([]model.Transfer, pagination.Result, error) {
+	list, total, err := repository.GetUserTransfers(database.DB, userID, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(t model.Transfer) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: t.CreatedAt, ID: t.ID}
+	})
+	return list, result, nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+)
+
+func getTransfer(tb testing.TB, id uint64) *model.Transfer {
+	tb.Helper()
+	var t model.Transfer
+	if err := database.DB.First(&t, id).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return &t
+}
+
+// 立即到账：转出方扣款、收款方入账，转账直接为已到账
+func TestCreateTransferImmediate(t *testing.T) {
+	requireTestDB(t)
+	from := newTestUser(t, 1000)
+	to := newTestUser(t, 0)
+
+	tr, err := CreateTransfer(TransferParams{FromUserID: from.ID, ToUsername: to.Username, Amount: 300})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if got := getTransfer(t, tr.ID); got.Status != model.TransferStatusAccepted || got.FinishedAt == nil {
+		t.Errorf("status = %d finished_at = %v, want accepted", got.Status, got.FinishedAt)
+	}
+	if b := balanceOf(t, from.ID); b != 700 {
+		t.Errorf("sender balance = %d, want 700", b)
+	}
+	if b := balanceOf(t, to.ID); b != 300 {
+		t.Errorf("recipient balance = %d, want 300", b)
+	}
+}
+
+// 需确认收款：发起时只扣款，收款后才入账，已收款的转账不能再拒收
+func TestCreateTransferPendingAccept(t *testing.T) {
+	requireTestDB(t)
+	from := newTestUser(t, 1000)
+	to := newTestUser(t, 0)
+
+	tr, err := CreateTransfer(TransferParams{FromUserID: from.ID, ToUsername: to.Username, Amount: 300, RequireAccept: true})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if tr.Status != model.TransferStatusPending {
+		t.Fatalf("status = %d, want pending", tr.Status)
+	}
+	if b := balanceOf(t, from.ID); b != 700 {
+		t.Errorf("sender balance = %d, want 700", b)
+	}
+	if b := balanceOf(t, to.ID); b != 0 {
+		t.Errorf("recipient balance before accept = %d, want 0", b)
+	}
+
+	if _, err := AcceptTransfer(tr.ID, from.ID); err == nil || err.Error() != "transfer not found" {
+		t.Fatalf("accept by sender err = %v, want transfer not found", err)
+	}
+	if _, err := AcceptTransfer(tr.ID, to.ID); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if got := getTransfer(t, tr.ID); got.Status != model.TransferStatusAccepted {
+		t.Errorf("status = %d, want accepted", got.Status)
+	}
+	if b := balanceOf(t, to.ID); b != 300 {
+		t.Errorf("recipient balance = %d, want 300", b)
+	}
+	if _, err := RejectTransfer(tr.ID, to.ID); !errors.Is(err, errTransferFinished) {
+		t.Errorf("reject after accept err = %v, want %v", err, errTransferFinished)
+	}
+	if b := balanceOf(t, from.ID); b != 700 {
+		t.Errorf("sender balance after reject attempt = %d, want 700", b)
+	}
+}
+
+// 超时未收款：调度退回后转出方余额恢复，收款方不入账，过期后也不能再收款
+func TestReturnExpiredTransfers(t *testing.T) {
+	requireTestDB(t)
+	from := newTestUser(t, 1000)
+	to := newTestUser(t, 0)
+
+	tr, err := CreateTransfer(TransferParams{FromUserID: from.ID, ToUsername: to.Username, Amount: 300, RequireAccept: true})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if err := database.DB.Model(&model.Transfer{}).Where("id = ?", tr.ID).
+		Update("expired_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AcceptTransfer(tr.ID, to.ID); err == nil || err.Error() != "transfer is expired" {
+		t.Fatalf("accept expired err = %v, want transfer is expired", err)
+	}
+
+	for {
+		n, err := ReturnExpiredTransfers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if got := getTransfer(t, tr.ID); got.Status != model.TransferStatusReturned || got.FinishedAt == nil {
+		t.Errorf("status = %d finished_at = %v, want returned", got.Status, got.FinishedAt)
+	}
+	if b := balanceOf(t, from.ID); b != 1000 {
+		t.Errorf("sender balance = %d, want 1000", b)
+	}
+	if b := balanceOf(t, to.ID); b != 0 {
+		t.Errorf("recipient balance = %d, want 0", b)
+	}
+}
+
+// 余额不足：两种模式都不留下转账和流水，双方余额不变
+func TestCreateTransferInsufficientBalance(t *testing.T) {
+	requireTestDB(t)
+	for _, requireAccept := range []bool{false, true} {
+		from := newTestUser(t, 100)
+		to := newTestUser(t, 50)
+
+		_, err := CreateTransfer(TransferParams{FromUserID: from.ID, ToUsername: to.Username, Amount: 300, RequireAccept: requireAccept})
+		if err == nil || err.Error() != "insufficient balance" {
+			t.Fatalf("require_accept=%v: err = %v, want insufficient balance", requireAccept, err)
+		}
+		if n := countRows(t, &model.Transfer{}, "from_user_id = ?", from.ID); n != 0 {
+			t.Errorf("require_accept=%v: %d transfers left behind", requireAccept, n)
+		}
+		if n := countRows(t, &model.Transaction{}, "user_id IN ?", []uint64{from.ID, to.ID}); n != 0 {
+			t.Errorf("require_accept=%v: %d transactions left behind", requireAccept, n)
+		}
+		if b := balanceOf(t, from.ID); b != 100 {
+			t.Errorf("require_accept=%v: sender balance = %d, want 100", requireAccept, b)
+		}
+		if b := balanceOf(t, to.ID); b != 50 {
+			t.Errorf("require_accept=%v: recipient balance = %d, want 50", requireAccept, b)
+		}
+	}
+}
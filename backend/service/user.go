@@ -4,7 +4,9 @@ import (
 	"errors"
 	"time"
 
+	"red-packet/database"
 	"red-packet/model"
+	"red-packet/pkg/currency"
 	"red-packet/repository"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -22,13 +24,18 @@ func InitUserService(secret string, expireHours int) {
 	jwtExpireHours = expireHours
 }
 
+// TokenTTL 登录 token 的有效期
+func TokenTTL() time.Duration {
+	return time.Duration(jwtExpireHours) * time.Hour
+}
+
 type Claims struct {
 	UserID uint64 `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
 func Register(username, password string) (*model.User, error) {
-	_, err := repository.GetUserByUsername(username)
+	_, err := repository.GetUserByUsername(database.DB, username)
 	if err == nil {
 		return nil, errors.New("username already exists")
 	}
@@ -45,14 +52,14 @@ func Register(username, password string) (*model.User, error) {
 		Username:     username,
 		PasswordHash: string(hash),
 	}
-	if err := repository.CreateUser(user); err != nil {
+	if err := repository.CreateUser(database.DB, user); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
 func Login(username, password string) (string, error) {
-	user, err := repository.GetUserByUsername(username)
+	user, err := repository.GetUserByUsername(database.DB, username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", errors.New("username or password incorrect")
@@ -89,6 +96,84 @@ func ParseToken(tokenStr string) (*Claims, error) {
 	return claims, nil
 }
 
-func GetProfile(userID uint64) (*model.User, error) {
-	return repository.GetUserByID(userID)
+// checkUserActive 冻结账户禁止收发红包
+func checkUserActive(userID uint64) error {
+	user, err := repository.GetUserByID(database.DB, userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.Status == model.UserStatusFrozen {
+		return errors.New("account is frozen")
+	}
+	return nil
+}
+
+// GetUserRole 实时查询角色，角色变更无需重新登录即可生效；冻结账户视为无角色
+func GetUserRole(userID uint64) (string, error) {
+	user, err := repository.GetUserByID(database.DB, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.Status == model.UserStatusFrozen {
+		return "", errors.New("account is frozen")
+	}
+	return user.Role, nil
+}
+
+type Profile struct {
+	*model.User
+	Currency string // 默认币种
+	Balance  uint64 // 默认币种余额
+}
+
+type BalanceItem struct {
+	Currency  string
+	Balance   uint64
+	Precision int
+	Display   string
+}
+
+func GetProfile(userID uint64) (*Profile, error) {
+	user, err := repository.GetUserByID(database.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	cur := currency.Default()
+	balance, err := repository.GetUserBalance(database.DB, userID, cur)
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{User: user, Currency: cur, Balance: balance}, nil
+}
+
+// ListBalances 列出用户所有币种余额，默认币种即使没有钱包也返回 0
+func ListBalances(userID uint64) ([]BalanceItem, error) {
+	wallets, err := repository.ListUserWallets(database.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	def := currency.Default()
+	items := make([]BalanceItem, 0, len(wallets)+1)
+	hasDefault := false
+	for _, w := range wallets {
+		if w.Currency == def {
+			hasDefault = true
+		}
+		items = append(items, newBalanceItem(w.Currency, w.Balance))
+	}
+	if !hasDefault {
+		items = append([]BalanceItem{newBalanceItem(def, 0)}, items...)
+	}
+	return items, nil
+}
+
+func newBalanceItem(code string, balance uint64) BalanceItem {
+	c, _ := currency.Get(code)
+	return BalanceItem{
+		Currency:  code,
+		Balance:   balance,
+		Precision: c.Precision,
+		Display:   currency.Format(code, balance),
+	}
 }
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"time"
+
+	"red-packet/database"
+	"red-packet/pkg/cache"
+	"red-packet/repository"
+)
+
+// usernameCache 用户名缓存，列表接口展示发送者/领取者名称用，用户名不可修改，TTL 仅用于控制内存
+var usernameCache = cache.NewTTL[uint64, string](10*time.Minute, 100000)
+
+// getUsernames 批量获取用户名：先查缓存，未命中的用一次 IN 查询补齐
+func getUsernames(ids []uint64) (map[uint64]string, error) {
+	names := make(map[uint64]string, len(ids))
+	var missing []uint64
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if name, ok := usernameCache.Get(id); ok {
+			names[id] = name
+			continue
+		}
+		missing = append(missing, id)
+	}
+
+	if len(missing) > 0 {
+		loaded, err := repository.GetUsernamesByIDs(database.DB, missing)
+		if err != nil {
+			return nil, err
+		}
+		for id, name := range loaded {
+			usernameCache.Set(id, name)
+			names[id] = name
+		}
+	}
+	return names, nil
+}
+
+func getUsername(id uint64) (string, error) {
+	names, err := getUsernames([]uint64{id})
+	if err != nil {
+		return "", err
+	}
+	return names[id], nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"red-packet/model"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+// credit 入账并写收入流水
+func credit(tx *gorm.DB, userID uint64, cur string, amount uint64, txType string, relatedID *uint64, remark string) (*model.Transaction, error) {
+	if err := repository.AddUserBalance(tx, userID, cur, amount); err != nil {
+		return nil, err
+	}
+	return writeTransaction(tx, userID, cur, amount, txType, model.TransactionDirectionIn, relatedID, remark)
+}
+
+// debit 扣款并写支出流水，余额不足返回 insufficient balance
+func debit(tx *gorm.DB, userID uint64, cur string, amount uint64, txType string, relatedID *uint64, remark string) (*model.Transaction, error) {
+	if err := repository.DeductUserBalance(tx, userID, cur, amount); err != nil {
+		return nil, err
+	}
+	return writeTransaction(tx, userID, cur, amount, txType, model.TransactionDirectionOut, relatedID, remark)
+}
+
+func writeTransaction(tx *gorm.DB, userID uint64, cur string, amount uint64, txType string, direction int8, relatedID *uint64, remark string) (*model.Transaction, error) {
+	balanceAfter, err := repository.GetUserBalance(tx, userID, cur)
+	if err != nil {
+		return nil, err
+	}
+	t := &model.Transaction{
+		UserID:       userID,
+		Type:         txType,
+		Direction:    direction,
+		Currency:     cur,
+		Amount:       amount,
+		BalanceAfter: balanceAfter,
+		RelatedID:    relatedID,
+		Remark:       remark,
+	}
+	if err := repository.CreateTransaction(tx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
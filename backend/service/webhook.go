@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/event"
+	"red-packet/pkg/pagination"
+	"red-packet/pkg/webhook"
+	"red-packet/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchLimit     = 100
+	deliveryBatchLimit   = 50
+	deliveryWorkers      = 8
+	deliveryTimeout      = 10 * time.Second
+	deliveryLease        = time.Minute // 抢占后在此时间内其他实例不会重复投递，需大于请求超时
+	deliveryMaxAttempts  = 10          // 超过后进入死信
+	deliveryRetryBase    = 30 * time.Second
+	deliveryRetryMaxWait = 6 * time.Hour
+)
+
+var (
+	webhookClient       = webhook.NewClient(deliveryTimeout, false)
+	webhookAllowPrivate bool
+)
+
+// InitWebhookService allowPrivate 为 true 时允许推送到回环和内网地址，仅用于本地联调
+func InitWebhookService(allowPrivate bool) {
+	webhookAllowPrivate = allowPrivate
+	webhookClient = webhook.NewClient(deliveryTimeout, allowPrivate)
+}
+
+type CreateWebhookParams struct {
+	URL    string
+	Secret string // 为空时自动生成
+	Events []string
+}
+
+// CreateWebhookSubscription 新建订阅，返回的 Secret 只在创建时展示一次
+func CreateWebhookSubscription(admin AdminContext, params CreateWebhookParams) (*model.WebhookSubscription, error) {
+	u, err := url.Parse(params.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("invalid webhook url")
+	}
+	// 投递结果（状态码、错误）会展示给管理员，不限制目标地址就能借此探测内网
+	if !webhookAllowPrivate {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := webhook.CheckHost(ctx, u.Hostname())
+		cancel()
+		if errors.Is(err, webhook.ErrBlockedTarget) {
+			return nil, errors.New("webhook url must be a public address")
+		}
+		if err != nil {
+			return nil, errors.New("webhook host cannot be resolved")
+		}
+	}
+	if len(params.Events) == 0 {
+		return nil, errors.New("events must not be empty")
+	}
+	for _, e := range params.Events {
+		if !slices.Contains(event.RedPacketTypes, e) {
+			return nil, fmt.Errorf("unsupported event: %s", e)
+		}
+	}
+
+	secret := params.Secret
+	if secret == "" {
+		b := make([]byte, 24)
+		if _, err := crand.Read(b); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	sub := &model.WebhookSubscription{
+		URL:       params.URL,
+		Secret:    secret,
+		Events:    strings.Join(params.Events, ","),
+		Status:    model.WebhookStatusEnabled,
+		CreatedBy: admin.AdminID,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.CreateWebhookSubscription(tx, sub); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionCreateWebhook, "webhook", sub.ID, map[string]interface{}{
+			"url":    sub.URL,
+			"events": params.Events,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func ListWebhookSubscriptions() ([]model.WebhookSubscription, error) {
+	return repository.ListWebhookSubscriptions(database.DB, model.WebhookStatusEnabled)
+}
+
+// DeleteWebhookSubscription 停用订阅，尚未投递的记录在下次投递时进入死信
+func DeleteWebhookSubscription(admin AdminContext, id uint64) error {
+	sub, err := repository.GetWebhookSubscription(database.DB, id)
+	if err != nil || sub.Status != model.WebhookStatusEnabled {
+		return errors.New("webhook not found")
+	}
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.UpdateWebhookSubscriptionStatus(tx, id, model.WebhookStatusDisabled); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionDeleteWebhook, "webhook", id, map[string]interface{}{
+			"url": sub.URL,
+		})
+	})
+}
+
+func ListWebhookDeliveries(filter repository.WebhookDeliveryFilter, p pagination.Params) ([]model.WebhookDelivery, pagination.Result, error) {
+	list, total, err := repository.ListWebhookDeliveries(database.DB, filter, p)
+	if err != nil {
+		return nil, pagination.Result{}, err
+	}
+	list, result := pagination.Trim(list, p, total, func(d model.WebhookDelivery) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+	})
+	return list, result, nil
+}
+
+// RedeliverWebhook 把死信重新放回投递队列，重新计算重试次数
+func RedeliverWebhook(admin AdminContext, id uint64) error {
+	d, err := repository.GetWebhookDelivery(database.DB, id)
+	if err != nil {
+		return errors.New("delivery not found")
+	}
+	if d.Status != model.WebhookDeliveryDead {
+		return errors.New("only dead deliveries can be redelivered")
+	}
+	attempts := d.Attempts
+	d.Status = model.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := repository.UpdateWebhookDelivery(tx, d); err != nil {
+			return err
+		}
+		return writeAuditLog(tx, admin, model.AuditActionRedeliverWebhook, "webhook_delivery", id, map[string]interface{}{
+			"subscription_id": d.SubscriptionID,
+			"event_type":      d.EventType,
+			"attempts":        attempts,
+			"last_error":      d.LastError,
+		})
+	})
+}
+
+// DispatchOutbox 把发件箱中的新事件展开为各订阅的投递记录，返回处理的事件数
+func DispatchOutbox() (int, error) {
+	ids, err := repository.GetUndispatchedOutboxIDs(database.DB, outboxBatchLimit)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	subs, err := repository.ListWebhookSubscriptions(database.DB, model.WebhookStatusEnabled)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	for _, id := range ids {
+		done := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			e, err := repository.GetOutboxEventForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			// 加锁后再确认一次，可能已被其他实例处理
+			if e.DispatchedAt != nil {
+				return nil
+			}
+
+			body, err := json.Marshal(webhook.Payload{
+				ID:         e.ID,
+				Type:       e.EventType,
+				OccurredAt: e.CreatedAt,
+				Data:       json.RawMessage(e.Payload),
+			})
+			if err != nil {
+				return err
+			}
+			var deliveries []model.WebhookDelivery
+			for _, s := range subs {
+				if !slices.Contains(strings.Split(s.Events, ","), e.EventType) {
+					continue
+				}
+				deliveries = append(deliveries, model.WebhookDelivery{
+					SubscriptionID: s.ID,
+					OutboxEventID:  e.ID,
+					EventType:      e.EventType,
+					Body:           string(body),
+					Status:         model.WebhookDeliveryPending,
+					NextAttemptAt:  e.CreatedAt,
+				})
+			}
+			if err := repository.CreateWebhookDeliveries(tx, deliveries); err != nil {
+				return err
+			}
+			done = true
+			return repository.MarkOutboxDispatched(tx, e.ID, time.Now())
+		})
+		if err != nil {
+			return dispatched, err
+		}
+		if done {
+			dispatched++
+		}
+	}
+	return dispatched, nil
+}
+
+// DeliverWebhooks 投递到期的 webhook，失败按指数退避重试，返回本轮成功数和进入死信数
+func DeliverWebhooks() (int, int, error) {
+	list, err := repository.GetDueWebhookDeliveries(database.DB, time.Now(), deliveryBatchLimit)
+	if err != nil || len(list) == 0 {
+		return 0, 0, err
+	}
+
+	var (
+		mu              sync.Mutex
+		wg              sync.WaitGroup
+		succeeded, dead int
+		firstErr        error
+	)
+	sem := make(chan struct{}, deliveryWorkers)
+	for i := range list {
+		d := &list[i]
+		leased, err := repository.LeaseWebhookDelivery(database.DB, d, time.Now().Add(deliveryLease))
+		if err != nil {
+			return succeeded, dead, err
+		}
+		if !leased {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			status, err := deliverWebhook(d)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// 结果未能写入，投递仍按租约到期后重试，不计入本轮统计
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			switch status {
+			case model.WebhookDeliverySucceeded:
+				succeeded++
+			case model.WebhookDeliveryDead:
+				dead++
+			}
+		}()
+	}
+	wg.Wait()
+	return succeeded, dead, firstErr
+}
+
+// deliverWebhook 发送一次并记录结果，返回投递后的状态
+func deliverWebhook(d *model.WebhookDelivery) (int8, error) {
+	sub, err := repository.GetWebhookSubscription(database.DB, d.SubscriptionID)
+	if err != nil {
+		return 0, err
+	}
+
+	d.Attempts++
+	if sub.Status != model.WebhookStatusEnabled {
+		d.Status = model.WebhookDeliveryDead
+		d.LastError = "subscription disabled"
+		return d.Status, repository.UpdateWebhookDelivery(database.DB, d)
+	}
+
+	code, sendErr := postWebhook(sub, d)
+	d.LastStatusCode = code
+	switch {
+	case sendErr == nil:
+		d.Status = model.WebhookDeliverySucceeded
+		d.LastError = ""
+	case d.Attempts >= deliveryMaxAttempts:
+		d.Status = model.WebhookDeliveryDead
+		d.LastError = truncate(sendErr.Error(), 500)
+	default:
+		d.NextAttemptAt = time.Now().Add(retryBackoff(d.Attempts))
+		d.LastError = truncate(sendErr.Error(), 500)
+	}
+	return d.Status, repository.UpdateWebhookDelivery(database.DB, d)
+}
+
+func postWebhook(sub *model.WebhookSubscription, d *model.WebhookDelivery) (int, error) {
+	body := []byte(d.Body)
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderEvent, d.EventType)
+	req.Header.Set(webhook.HeaderDelivery, fmt.Sprintf("%d", d.ID))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(sub.Secret, time.Now(), body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryBackoff 第 n 次失败后的等待时间：30s、1m、2m、4m……，最长 6 小时
+func retryBackoff(attempts uint32) time.Duration {
+	wait := deliveryRetryBase << (attempts - 1)
+	if wait <= 0 || wait > deliveryRetryMaxWait {
+		return deliveryRetryMaxWait
+	}
+	return wait
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"red-packet/database"
+	"red-packet/model"
+	"red-packet/pkg/event"
+	"red-packet/pkg/webhook"
+	"red-packet/repository"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts uint32
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, deliveryRetryMaxWait},
+		{40, deliveryRetryMaxWait},
+		{100, deliveryRetryMaxWait}, // 位移溢出
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestCreateWebhookRejectsPrivateTarget(t *testing.T) {
+	for _, u := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.8/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/",
+	} {
+		_, err := CreateWebhookSubscription(AdminContext{AdminID: 1}, CreateWebhookParams{
+			URL:    u,
+			Events: []string{event.RedPacketSent},
+		})
+		if err == nil || err.Error() != "webhook url must be a public address" {
+			t.Errorf("%s: err = %v, want public address error", u, err)
+		}
+	}
+}
+
+// allowPrivateWebhooks 测试接收端监听在回环地址，临时放开目标地址限制
+func allowPrivateWebhooks(t *testing.T) {
+	InitWebhookService(true)
+	t.Cleanup(func() { InitWebhookService(false) })
+}
+
+func TestPostWebhook(t *testing.T) {
+	allowPrivateWebhooks(t)
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify("s3cret", r.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		if r.Header.Get(webhook.HeaderEvent) != event.RedPacketSent || r.Header.Get(webhook.HeaderDelivery) != "42" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		if string(body) != `{"id":7}` {
+			t.Errorf("body = %s", body)
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	sub := &model.WebhookSubscription{URL: srv.URL, Secret: "s3cret"}
+	d := &model.WebhookDelivery{ID: 42, EventType: event.RedPacketSent, Body: `{"id":7}`}
+	if code, err := postWebhook(sub, d); err != nil || code != http.StatusOK {
+		t.Fatalf("postWebhook = %d, %v; want 200, nil", code, err)
+	}
+	status.Store(http.StatusServiceUnavailable)
+	if code, err := postWebhook(sub, d); err == nil || code != http.StatusServiceUnavailable {
+		t.Fatalf("postWebhook = %d, %v; want 503 and error", code, err)
+	}
+}
+
+// 未放开限制时，投递阶段同样拒绝连接内网地址，不依赖创建时的校验
+func TestPostWebhookBlocksPrivateTarget(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+
+	sub := &model.WebhookSubscription{URL: srv.URL, Secret: "s"}
+	if _, err := postWebhook(sub, &model.WebhookDelivery{Body: "{}"}); err == nil {
+		t.Fatal("postWebhook to loopback succeeded")
+	}
+	if hits.Load() != 0 {
+		t.Fatal("request reached loopback server")
+	}
+}
+
+// 发件箱展开 → 投递失败重试 → 死信 → 重新投递 → 成功，需要 MySQL
+func TestWebhookDispatchAndDeliver(t *testing.T) {
+	requireTestDB(t)
+	allowPrivateWebhooks(t)
+
+	var fail atomic.Bool
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify("dispatch-secret", r.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		received.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	sub := &model.WebhookSubscription{
+		URL:       srv.URL,
+		Secret:    "dispatch-secret",
+		Events:    event.RedPacketSent,
+		Status:    model.WebhookStatusEnabled,
+		CreatedBy: 1,
+	}
+	if err := repository.CreateWebhookSubscription(database.DB, sub); err != nil {
+		t.Fatal(err)
+	}
+	// 停用，避免后续运行把事件继续展开到已关闭的接收端
+	defer repository.UpdateWebhookSubscriptionStatus(database.DB, sub.ID, model.WebhookStatusDisabled)
+
+	e := &model.OutboxEvent{EventType: event.RedPacketSent, Payload: `{"red_packet_id":0}`}
+	if err := repository.CreateOutboxEvent(database.DB, e); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		n, err := DispatchOutbox()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	var d model.WebhookDelivery
+	if err := database.DB.Where("subscription_id = ? AND outbox_event_id = ?", sub.ID, e.ID).First(&d).Error; err != nil {
+		t.Fatalf("delivery not dispatched: %v", err)
+	}
+	if d.Status != model.WebhookDeliveryPending || d.EventType != event.RedPacketSent {
+		t.Fatalf("unexpected delivery: %+v", d)
+	}
+
+	// 失败后按退避推迟下次投递
+	fail.Store(true)
+	before := time.Now()
+	if status, err := deliverWebhook(&d); err != nil || status != model.WebhookDeliveryPending {
+		t.Fatalf("deliverWebhook = %d, %v; want pending", status, err)
+	}
+	if d.Attempts != 1 || d.LastStatusCode != http.StatusInternalServerError || d.NextAttemptAt.Before(before.Add(deliveryRetryBase)) {
+		t.Fatalf("retry not scheduled: %+v", d)
+	}
+
+	// 最后一次失败进入死信
+	d.Attempts = deliveryMaxAttempts - 1
+	if status, err := deliverWebhook(&d); err != nil || status != model.WebhookDeliveryDead {
+		t.Fatalf("deliverWebhook = %d, %v; want dead", status, err)
+	}
+
+	// 重新投递写审计日志，之后投递成功
+	admin := AdminContext{AdminID: 1, IP: "127.0.0.1"}
+	if err := RedeliverWebhook(admin, d.ID); err != nil {
+		t.Fatal(err)
+	}
+	var logs int64
+	database.DB.Model(&model.AdminAuditLog{}).
+		Where("action = ? AND target_type = ? AND target_id = ?", model.AuditActionRedeliverWebhook, "webhook_delivery", d.ID).
+		Count(&logs)
+	if logs != 1 {
+		t.Fatalf("audit logs = %d, want 1", logs)
+	}
+	redelivered, err := repository.GetWebhookDelivery(database.DB, d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail.Store(false)
+	if status, err := deliverWebhook(redelivered); err != nil || status != model.WebhookDeliverySucceeded {
+		t.Fatalf("deliverWebhook = %d, %v; want succeeded", status, err)
+	}
+	if received.Load() != 3 {
+		t.Fatalf("receiver got %d requests, want 3", received.Load())
+	}
+}
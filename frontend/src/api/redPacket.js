@@ -4,8 +4,9 @@ export function sendRedPacket(type, totalAmount, totalCount) {
   return client.post('/red-packets', { type, total_amount: totalAmount, total_count: totalCount })
 }
 
-export function claimRedPacket(id) {
-  return client.post(`/red-packets/${id}/claim`)
+// shareToken 来自分享链接的 ?t= 参数，要求分享链接的红包必须携带
+export function claimRedPacket(id, shareToken) {
+  return client.post(`/red-packets/${id}/claim`, shareToken ? { share_token: shareToken } : undefined)
 }
 
 export function getRedPacketDetail(id) {
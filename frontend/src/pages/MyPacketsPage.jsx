@@ -6,6 +6,8 @@ import { getSentRedPackets, getReceivedRedPackets } from '../api/user'
 
 const { Text, Title } = Typography
 
+const TYPE_LABEL = { 1: '普通红包', 2: '拼手气红包', 3: '抽奖红包', 4: '指定金额红包' }
+
 const STATUS_TAG = {
   1: <Tag color="green">可领取</Tag>,
   2: <Tag color="default">已抢完</Tag>,
@@ -51,13 +53,13 @@ function SentList({ navigate }) {
       renderItem={(item) => (
         <List.Item
           style={styles.listItem}
-          onClick={() => navigate(`/red-packets/${item.id}`)}
+          onClick={() => navigate(`/red-packets/${item.public_id}`)}
         >
           <List.Item.Meta
             avatar={<Avatar icon={<GiftOutlined />} style={{ background: '#f5222d' }} />}
             title={
               <span>
-                {item.type === 1 ? '普通红包' : '拼手气红包'}
+                {TYPE_LABEL[item.type]}
                 <span style={styles.tagWrap}>{STATUS_TAG[item.status]}</span>
               </span>
             }
@@ -106,7 +108,7 @@ function ReceivedList({ navigate }) {
       renderItem={(item) => (
         <List.Item
           style={styles.listItem}
-          onClick={() => navigate(`/red-packets/${item.red_packet_id}`)}
+          onClick={() => navigate(`/red-packets/${item.public_id}`)}
         >
           <List.Item.Meta
             avatar={<Avatar icon={<GiftOutlined />} style={{ background: '#fa8c16' }} />}
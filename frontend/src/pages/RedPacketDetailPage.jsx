@@ -1,12 +1,12 @@
 import { useEffect, useState } from 'react'
-import { useParams, useNavigate } from 'react-router-dom'
+import { useParams, useNavigate, useSearchParams } from 'react-router-dom'
 import { Button, Card, List, Spin, Tag, Typography, message, Avatar, Divider } from 'antd'
 import { ArrowLeftOutlined, GiftOutlined, UserOutlined } from '@ant-design/icons'
 import { getRedPacketDetail, getRedPacketRecords, claimRedPacket } from '../api/redPacket'
 
 const { Title, Text } = Typography
 
-const TYPE_LABEL = { 1: '普通红包', 2: '拼手气红包' }
+const TYPE_LABEL = { 1: '普通红包', 2: '拼手气红包', 3: '抽奖红包', 4: '指定金额红包' }
 const STATUS_TAG = {
   1: <Tag color="green">可领取</Tag>,
   2: <Tag color="default">已抢完</Tag>,
@@ -23,6 +23,7 @@ function formatTime(iso) {
 
 export default function RedPacketDetailPage() {
   const { id } = useParams()
+  const [searchParams] = useSearchParams()
   const navigate = useNavigate()
   const [detail, setDetail] = useState(null)
   const [records, setRecords] = useState([])
@@ -64,7 +65,7 @@ export default function RedPacketDetailPage() {
   async function handleClaim() {
     setClaiming(true)
     try {
-      const data = await claimRedPacket(id)
+      const data = await claimRedPacket(id, searchParams.get('t'))
       message.success(`领到红包 ${formatAmount(data.amount)}！`)
       // 重新拉取详情和记录
       await loadDetail()
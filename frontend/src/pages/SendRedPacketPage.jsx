@@ -16,7 +16,7 @@ export default function SendRedPacketPage() {
       const data = await sendRedPacket(values.type, totalAmountFen, values.totalCount)
       message.success('红包发送成功！')
       // 发送成功后跳转到该红包的详情页
-      navigate(`/red-packets/${data.id}`)
+      navigate(`/red-packets/${data.public_id}`)
     } catch (err) {
       message.error(err.message)
     }